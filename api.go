@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	IsAdmin   bool      `json:"is_admin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiFeed struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	SiteURL       string     `json:"site_url,omitempty"`
+	UserID        uuid.UUID  `json:"user_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+}
+
+type apiFollow struct {
+	FeedID    uuid.UUID `json:"feed_id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	SiteURL   string    `json:"site_url,omitempty"`
+	Folder    string    `json:"folder,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Feed        string    `json:"feed"`
+	FeedURL     string    `json:"feed_url"`
+	Author      string    `json:"author,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	Categories  []string  `json:"categories"`
+	Read        bool      `json:"read"`
+	Starred     bool      `json:"starred"`
+	Description string    `json:"description"`
+	Content     string    `json:"content,omitempty"`
+}
+
+func newAPIFeed(feed database.Feed) apiFeed {
+	return apiFeed{
+		ID:            feed.ID,
+		Name:          feed.Name,
+		URL:           feed.Url,
+		SiteURL:       feed.SiteUrl,
+		UserID:        feed.UserID,
+		CreatedAt:     feed.CreatedAt,
+		LastFetchedAt: timePtr(feed.LastFetchedAt),
+	}
+}
+
+func (srv *server) apiUsers(w http.ResponseWriter, r *http.Request, user database.User) {
+	users, err := srv.s.db.GetUsers(r.Context())
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	resp := make([]apiUser, 0, len(users))
+	for _, u := range users {
+		resp = append(resp, apiUser{ID: u.ID, Name: u.Name, IsAdmin: u.IsAdmin, CreatedAt: u.CreatedAt})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (srv *server) apiFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := srv.s.db.GetFeeds(r.Context())
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	resp := make([]apiFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		resp = append(resp, newAPIFeed(feed))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (srv *server) apiCreateFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	// adds a feed and follows it, like addfeed
+	var req struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if err := validateFeedURL(req.URL); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := srv.s.db.GetFeedByUrl(r.Context(), req.URL); err == nil {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("a feed with url %v already exists", req.URL))
+		return
+	}
+
+	feed, err := srv.s.db.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      req.Name,
+		Url:       req.URL,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	_, err = srv.s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		FeedID:    feed.ID,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	audit(srv.s, user, "addfeed", feed.Url, feed.Name+" (api)")
+	respondWithJSON(w, http.StatusCreated, newAPIFeed(feed))
+}
+
+func (srv *server) apiFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	params := database.GetFeedFollowsForUserParams{UserID: user.ID}
+	if folder := r.URL.Query().Get("folder"); folder != "" {
+		params.Folder = sql.NullString{String: folder, Valid: true}
+	}
+	rows, err := srv.s.db.GetFeedFollowsForUser(r.Context(), params)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	resp := make([]apiFollow, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, apiFollow{
+			FeedID:    row.FeedID,
+			Name:      row.FeedName,
+			URL:       row.FeedUrl,
+			SiteURL:   row.FeedSiteUrl,
+			Folder:    row.FolderName.String,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (srv *server) apiFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var req struct {
+		Feed   string `json:"feed"`
+		Folder string `json:"folder"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.Feed == "" {
+		respondWithError(w, http.StatusBadRequest, "feed is required")
+		return
+	}
+
+	feed, err := srv.lookupFeed(r.Context(), user, req.Feed)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	_, err = srv.s.db.GetFeedFollow(r.Context(), database.GetFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if err == nil {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("already following %v", feed.Name))
+		return
+	}
+
+	params := database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		FeedID:    feed.ID,
+		UserID:    user.ID,
+	}
+	if req.Folder != "" {
+		folder, err := lookupFolder(srv.s, user, req.Folder)
+		if err != nil {
+			respondWithErr(w, err)
+			return
+		}
+		params.FolderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+	row, err := srv.s.db.CreateFeedFollow(r.Context(), params)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	audit(srv.s, user, "follow", feed.Url, "api")
+	respondWithJSON(w, http.StatusCreated, apiFollow{
+		FeedID:    feed.ID,
+		Name:      row.FeedName,
+		URL:       feed.Url,
+		SiteURL:   feed.SiteUrl,
+		Folder:    req.Folder,
+		CreatedAt: row.CreatedAt,
+	})
+}
+
+func (srv *server) apiUnfollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, err := srv.lookupFeed(r.Context(), user, r.PathValue("feed"))
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	_, err = srv.s.db.GetFeedFollow(r.Context(), database.GetFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("not following %v", feed.Name))
+		return
+	}
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	err = srv.s.db.Unfollow(r.Context(), database.UnfollowParams{FeedID: feed.ID, UserID: user.ID})
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	audit(srv.s, user, "unfollow", feed.Url, "api")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (srv *server) apiPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	// lists posts like browse, taking the browse flags as query parameters
+	fs := flag.NewFlagSet("posts", flag.ContinueOnError)
+	var filters postFilters
+	filters.register(fs, 20)
+	if err := setFlagsFromQuery(fs, r.URL.Query()); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params, err := filters.params(user)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := srv.s.db.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	resp := make([]apiPost, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, apiPost{
+			ID:          row.ID,
+			Title:       row.Title,
+			URL:         row.Url,
+			Feed:        row.Name,
+			FeedURL:     row.FeedUrl,
+			Author:      row.Author,
+			PublishedAt: publishedOrCreated(row.PublishedAt, row.CreatedAt),
+			Categories:  orEmpty(row.Categories),
+			Read:        row.ReadAt.Valid,
+			Starred:     row.StarredAt.Valid,
+			Description: row.Description,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (srv *server) apiPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, err := lookupFollowedPost(srv.s, user, r.PathValue("post"))
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, apiPost{
+		ID:          post.ID,
+		Title:       post.Title,
+		URL:         post.Url,
+		Feed:        post.FeedName,
+		FeedURL:     post.FeedUrl,
+		Author:      post.Author,
+		PublishedAt: publishedOrCreated(post.PublishedAt, post.CreatedAt),
+		Categories:  orEmpty(post.Categories),
+		Read:        post.ReadAt.Valid,
+		Starred:     post.StarredAt.Valid,
+		Description: post.Description,
+		Content:     post.Content,
+	})
+}
+
+func (srv *server) apiMarkPost(state string) func(http.ResponseWriter, *http.Request, database.User) {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		post, err := lookupFollowedPost(srv.s, user, r.PathValue("post"))
+		if err != nil {
+			respondWithErr(w, err)
+			return
+		}
+		if err := setPostState(srv.s, user, post.ID, state); err != nil {
+			respondWithErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (srv *server) apiPublish(w http.ResponseWriter, r *http.Request, user database.User) {
+	// serves the user's timeline as Atom or RSS, like publish
+	fs := flag.NewFlagSet("publish", flag.ContinueOnError)
+	var filters postFilters
+	filters.register(fs, 50)
+	format := fs.String("format", "atom", "document format: atom or rss")
+	title := fs.String("title", "", "title of the published feed")
+	if err := setFlagsFromQuery(fs, r.URL.Query()); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	write, err := feedWriter(*format)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params, err := filters.params(user)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the document links to itself
+	link := "http://" + r.Host + r.URL.RequestURI()
+	if r.TLS != nil {
+		link = "https://" + r.Host + r.URL.RequestURI()
+	}
+	feed, err := buildPublishedFeed(srv.s, user, params, *title, link)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/"+*format+"+xml; charset=utf-8")
+	if err := write(w, feed); err != nil {
+		log.Println(err)
+	}
+}
+
+func (srv *server) lookupFeed(ctx context.Context, user database.User, ref string) (database.Feed, error) {
+	// finds a feed by id, url, title or name
+	if id, err := uuid.Parse(ref); err == nil {
+		feed, err := srv.s.db.GetFeedByID(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Feed{}, notFound("no feed with id %v", ref)
+		}
+		return feed, err
+	}
+	return lookupFeed(srv.s, user, ref)
+}
+
+func setFlagsFromQuery(fs *flag.FlagSet, query url.Values) error {
+	// applies query parameters to the flags of the same name, so endpoints
+	// accept exactly what the matching command does
+	for key, values := range query {
+		f := fs.Lookup(key)
+		if f == nil {
+			return fmt.Errorf("unknown parameter %q", key)
+		}
+		for _, value := range values {
+			// a bare ?unread means true
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() && value == "" {
+				value = "true"
+			}
+			if err := fs.Set(key, value); err != nil {
+				return fmt.Errorf("invalid value %q for %v: %v", value, key, err)
+			}
+		}
+	}
+	return nil
+}
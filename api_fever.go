@@ -0,0 +1,334 @@
+package main
+
+import (
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+)
+
+// The Fever API (https://feedafever.com/api) is spoken by mobile readers
+// such as Reeder and Unread. Everything goes through one endpoint whose
+// query parameters pick what to return, and ids are integers, so items,
+// feeds and groups (folders) are identified by their serial_id.
+
+const (
+	feverAPIVersion = 3
+	// feverItemLimit is the most items Fever returns per request
+	feverItemLimit = 50
+)
+
+func feverAPIKey(username, password string) string {
+	// clients authenticate with md5("username:password")
+	sum := md5.Sum([]byte(username + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+func (srv *server) fever(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]any{
+		"api_version": feverAPIVersion,
+		"auth":        0,
+	}
+	if err := r.ParseForm(); err != nil {
+		respondWithJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	if _, ok := r.Form["api"]; !ok {
+		respondWithJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+
+	// Fever reports bad credentials in the body, not with a status code
+	user, err := srv.authenticate(r.Context(), strings.ToLower(r.PostFormValue("api_key")), scopeFever)
+	var authErr authError
+	if errors.As(err, &authErr) {
+		respondWithJSON(w, http.StatusOK, resp)
+		return
+	}
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	resp["auth"] = 1
+
+	if err := srv.feverRespond(r, user, resp); err != nil {
+		var badRequest feverError
+		if errors.As(err, &badRequest) {
+			resp["error"] = badRequest.Error()
+			respondWithJSON(w, http.StatusBadRequest, resp)
+			return
+		}
+		respondWithErr(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// feverError is a malformed Fever request
+type feverError string
+
+func (e feverError) Error() string {
+	return string(e)
+}
+
+func (srv *server) feverRespond(r *http.Request, user database.User, resp map[string]any) error {
+	// runs the mark action, if any, then fills resp with every requested section
+	ctx := r.Context()
+	has := func(key string) bool {
+		_, ok := r.Form[key]
+		return ok
+	}
+
+	// marking happens first so the returned ids reflect it
+	if has("mark") {
+		if err := srv.feverMark(r, user); err != nil {
+			return err
+		}
+	}
+
+	feeds, err := srv.s.db.GetFeverFeeds(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	lastRefreshed := int64(0)
+	for _, feed := range feeds {
+		if feed.LastFetchedAt.Valid && feed.LastFetchedAt.Time.Unix() > lastRefreshed {
+			lastRefreshed = feed.LastFetchedAt.Time.Unix()
+		}
+	}
+	resp["last_refreshed_on_time"] = lastRefreshed
+
+	if has("groups") || has("feeds") {
+		resp["feeds_groups"] = feverFeedsGroups(feeds)
+	}
+	if has("groups") {
+		groups, err := srv.s.db.GetFeverGroups(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		list := make([]map[string]any, 0, len(groups))
+		for _, group := range groups {
+			list = append(list, map[string]any{"id": group.SerialID, "title": group.Name})
+		}
+		resp["groups"] = list
+	}
+	if has("feeds") {
+		list := make([]map[string]any, 0, len(feeds))
+		for _, feed := range feeds {
+			list = append(list, map[string]any{
+				"id":                   feed.SerialID,
+				"favicon_id":           0,
+				"title":                feed.Title,
+				"url":                  feed.Url,
+				"site_url":             feed.SiteUrl,
+				"is_spark":             0,
+				"last_updated_on_time": unixOrZero(feed.LastFetchedAt),
+			})
+		}
+		resp["feeds"] = list
+	}
+	if has("favicons") {
+		// gator doesn't fetch favicons
+		resp["favicons"] = []any{}
+	}
+	if has("links") {
+		// nor does it compute Fever's hot links
+		resp["links"] = []any{}
+	}
+	if has("items") {
+		if err := srv.feverItems(r, user, resp); err != nil {
+			return err
+		}
+	}
+	if has("unread_item_ids") {
+		ids, err := srv.s.db.GetFeverItemIds(ctx, database.GetFeverItemIdsParams{UserID: user.ID, UnreadOnly: true})
+		if err != nil {
+			return err
+		}
+		resp["unread_item_ids"] = joinIDs(ids)
+	}
+	if has("saved_item_ids") {
+		ids, err := srv.s.db.GetFeverItemIds(ctx, database.GetFeverItemIdsParams{UserID: user.ID, StarredOnly: true})
+		if err != nil {
+			return err
+		}
+		resp["saved_item_ids"] = joinIDs(ids)
+	}
+	return nil
+}
+
+func (srv *server) feverItems(r *http.Request, user database.User, resp map[string]any) error {
+	// returns up to 50 items after since_id, before max_id, or listed in with_ids
+	params := database.GetFeverItemsParams{
+		UserID: user.ID,
+		Limit:  feverItemLimit,
+	}
+	switch {
+	case r.Form.Get("with_ids") != "":
+		for _, field := range strings.Split(r.Form.Get("with_ids"), ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return feverError("invalid with_ids")
+			}
+			params.WithIds = append(params.WithIds, id)
+		}
+	case r.Form.Get("max_id") != "":
+		id, err := strconv.ParseInt(r.Form.Get("max_id"), 10, 64)
+		if err != nil {
+			return feverError("invalid max_id")
+		}
+		params.MaxID = sql.NullInt64{Int64: id, Valid: true}
+		params.Descending = true
+	case r.Form.Get("since_id") != "":
+		id, err := strconv.ParseInt(r.Form.Get("since_id"), 10, 64)
+		if err != nil {
+			return feverError("invalid since_id")
+		}
+		params.SinceID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	rows, err := srv.s.db.GetFeverItems(r.Context(), params)
+	if err != nil {
+		return err
+	}
+	total, err := srv.s.db.CountFeverItems(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	items := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		html := row.Content
+		if html == "" {
+			html = row.Description
+		}
+		items = append(items, map[string]any{
+			"id":              row.SerialID,
+			"feed_id":         row.FeedSerialID,
+			"title":           row.Title,
+			"author":          row.Author,
+			"html":            html,
+			"url":             row.Url,
+			"is_saved":        boolInt(row.StarredAt.Valid),
+			"is_read":         boolInt(row.ReadAt.Valid),
+			"created_on_time": publishedOrCreated(row.PublishedAt, row.CreatedAt).Unix(),
+		})
+	}
+	resp["items"] = items
+	resp["total_items"] = total
+	return nil
+}
+
+func (srv *server) feverMark(r *http.Request, user database.User) error {
+	// handles mark=item|feed|group with as=read|unread|saved|unsaved and id
+	id, err := strconv.ParseInt(r.Form.Get("id"), 10, 64)
+	if err != nil {
+		return feverError("invalid id")
+	}
+	as := r.Form.Get("as")
+
+	switch r.Form.Get("mark") {
+	case "item":
+		states := map[string]string{"read": "read", "unread": "unread", "saved": "starred", "unsaved": "unstarred"}
+		state, ok := states[as]
+		if !ok {
+			return feverError("invalid as")
+		}
+		postID, err := srv.s.db.GetPostIdBySerialId(r.Context(), database.GetPostIdBySerialIdParams{
+			UserID:   user.ID,
+			SerialID: id,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// the item may have been deleted since the client synced
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return setPostState(srv.s, user, postID, state)
+
+	case "feed", "group":
+		if as != "read" {
+			return feverError("invalid as")
+		}
+		// before keeps items that arrived after the client last synced unread
+		before := time.Now().UTC()
+		if value := r.Form.Get("before"); value != "" {
+			unix, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return feverError("invalid before")
+			}
+			before = time.Unix(unix, 0).UTC()
+		}
+		params := database.MarkPostsReadBeforeParams{
+			UserID: user.ID,
+			Before: before,
+		}
+		if r.Form.Get("mark") == "feed" {
+			params.FeedSerialID = sql.NullInt64{Int64: id, Valid: true}
+		} else if id > 0 {
+			// group 0 is every feed, and -1 the sparks gator doesn't have
+			params.FolderSerialID = sql.NullInt64{Int64: id, Valid: true}
+		}
+		n, err := srv.s.db.MarkPostsReadBefore(r.Context(), params)
+		if err != nil {
+			return err
+		}
+		log.Printf("fever: marked %d posts read for %v", n, user.Name)
+		return nil
+	}
+	return feverError("invalid mark")
+}
+
+func feverFeedsGroups(feeds []database.GetFeverFeedsRow) []map[string]any {
+	// lists the feed ids in each group, as a comma separated string
+	byGroup := make(map[int64][]string)
+	order := make([]int64, 0)
+	for _, feed := range feeds {
+		if !feed.FolderSerialID.Valid {
+			continue
+		}
+		group := feed.FolderSerialID.Int64
+		if _, ok := byGroup[group]; !ok {
+			order = append(order, group)
+		}
+		byGroup[group] = append(byGroup[group], strconv.FormatInt(feed.SerialID, 10))
+	}
+	list := make([]map[string]any, 0, len(order))
+	for _, group := range order {
+		list = append(list, map[string]any{
+			"group_id": group,
+			"feed_ids": strings.Join(byGroup[group], ","),
+		})
+	}
+	return list
+}
+
+func joinIDs(ids []int64) string {
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(fields, ",")
+}
+
+func unixOrZero(t sql.NullTime) int64 {
+	if !t.Valid {
+		return 0
+	}
+	return t.Time.Unix()
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
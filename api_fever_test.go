@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeverTimesAreUTC(t *testing.T) {
+	// created_on_time, last_refreshed_on_time and before are unix times, so
+	// they have to match posts fetched while the local clock is ahead of UTC
+	localAheadOfUTC(t)
+	s := testState(t)
+	srv := httptest.NewServer((&server{s: s}).routes())
+	t.Cleanup(srv.Close)
+	user := testUser(t, s, "alice")
+	feed := testFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+	apiKey := feverAPIKey(user.Name, testToken(t, s, user, sql.NullTime{}, scopeFever))
+
+	fetched := time.Now()
+	savePosts(s, feed, []RSSItem{{Title: "Go 1.23 is released", Link: "https://go.dev/blog/go1.23"}})
+	if err := s.db.MarkFeedFetched(context.Background(), feed.ID); err != nil {
+		t.Fatal(err)
+	}
+	near := func(name string, unix int64) {
+		t.Helper()
+		if d := time.Unix(unix, 0).Sub(fetched); d < -time.Minute || d > time.Minute {
+			t.Errorf("%v is %v from when the post was fetched", name, d)
+		}
+	}
+
+	var resp struct {
+		LastRefreshed int64 `json:"last_refreshed_on_time"`
+		Items         []struct {
+			CreatedOn int64 `json:"created_on_time"`
+		} `json:"items"`
+		Feeds []struct {
+			ID int64 `json:"id"`
+		} `json:"feeds"`
+		UnreadItemIDs string `json:"unread_item_ids"`
+	}
+	feverDo(t, srv, "items&feeds&unread_item_ids", apiKey, nil, &resp)
+	if len(resp.Items) != 1 || len(resp.Feeds) != 1 || resp.UnreadItemIDs == "" {
+		t.Fatalf("items %v, feeds %v, unread %q, want the new post unread", resp.Items, resp.Feeds, resp.UnreadItemIDs)
+	}
+	near("last_refreshed_on_time", resp.LastRefreshed)
+	near("created_on_time", resp.Items[0].CreatedOn)
+
+	// marking the feed read before a minute ago leaves the post unread,
+	// before a minute from now doesn't
+	markFeedRead := func(d time.Duration) string {
+		t.Helper()
+		form := url.Values{
+			"mark":   {"feed"},
+			"as":     {"read"},
+			"id":     {strconv.FormatInt(resp.Feeds[0].ID, 10)},
+			"before": {strconv.FormatInt(fetched.Add(d).Unix(), 10)},
+		}
+		var unread struct {
+			UnreadItemIDs string `json:"unread_item_ids"`
+		}
+		feverDo(t, srv, "unread_item_ids", apiKey, form, &unread)
+		return unread.UnreadItemIDs
+	}
+	if got := markFeedRead(-time.Minute); got == "" {
+		t.Fatalf("unread after before a minute ago = %q, want the new post", got)
+	}
+	if got := markFeedRead(time.Minute); got != "" {
+		t.Fatalf("unread after before a minute from now = %q, want nothing", got)
+	}
+}
+
+func feverDo(t *testing.T, srv *httptest.Server, query, apiKey string, form url.Values, v any) {
+	// posts a Fever request with the api key and decodes the response into v
+	t.Helper()
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set("api_key", apiKey)
+	res, err := srv.Client().Post(srv.URL+"/fever/?api&"+query, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("fever %v = %v: %s", query, res.StatusCode, data)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("fever %v: %v", query, err)
+	}
+}
@@ -0,0 +1,743 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// The Google Reader API, as implemented by FreshRSS and Miniflux, is how
+// clients like NetNewsWire, FeedMe and Read You sync. Feeds are streams
+// named feed/<serial_id>, folders are labels and read and starred are
+// states, all addressed through stream ids like user/-/label/<folder>.
+
+const (
+	readerReadingList = "user/-/state/com.google/reading-list"
+	readerRead        = "user/-/state/com.google/read"
+	readerStarred     = "user/-/state/com.google/starred"
+	readerLabelPrefix = "user/-/label/"
+	readerItemPrefix  = "tag:google.com,2005:reader/item/"
+
+	// readerItemLimit is the most items a stream request returns
+	readerItemLimit = 1000
+	// readerDefaultItems is how many items are returned when n isn't given
+	readerDefaultItems = 20
+)
+
+func (srv *server) readerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/accounts/ClientLogin", srv.readerClientLogin)
+	mux.HandleFunc("/reader/api/0/token", srv.readerAuthenticated(srv.readerToken))
+	mux.HandleFunc("/reader/api/0/user-info", srv.readerAuthenticated(srv.readerUserInfo))
+	mux.HandleFunc("/reader/api/0/subscription/list", srv.readerAuthenticated(srv.readerSubscriptions))
+	mux.HandleFunc("POST /reader/api/0/subscription/edit", srv.readerAuthenticated(srv.readerEditSubscription))
+	mux.HandleFunc("POST /reader/api/0/subscription/quickadd", srv.readerAuthenticated(srv.readerQuickAdd))
+	mux.HandleFunc("/reader/api/0/tag/list", srv.readerAuthenticated(srv.readerTags))
+	mux.HandleFunc("/reader/api/0/unread-count", srv.readerAuthenticated(srv.readerUnreadCount))
+	mux.HandleFunc("/reader/api/0/stream/contents", srv.readerAuthenticated(srv.readerStreamContents))
+	mux.HandleFunc("/reader/api/0/stream/contents/{stream...}", srv.readerAuthenticated(srv.readerStreamContents))
+	mux.HandleFunc("/reader/api/0/stream/items/ids", srv.readerAuthenticated(srv.readerItemIDs))
+	mux.HandleFunc("/reader/api/0/stream/items/contents", srv.readerAuthenticated(srv.readerItemContents))
+	mux.HandleFunc("POST /reader/api/0/edit-tag", srv.readerAuthenticated(srv.readerEditTag))
+	mux.HandleFunc("POST /reader/api/0/mark-all-as-read", srv.readerAuthenticated(srv.readerMarkAllRead))
+}
+
+func (srv *server) readerClientLogin(w http.ResponseWriter, r *http.Request) {
+	// exchanges a username and greader token for the auth token clients send back
+	if err := r.ParseForm(); err != nil {
+		respondWithText(w, http.StatusBadRequest, "Error=BadRequest\n")
+		return
+	}
+	token := r.Form.Get("Passwd")
+	user, err := srv.authenticate(r.Context(), token, scopeGReader)
+	var authErr authError
+	if errors.As(err, &authErr) || (err == nil && user.Name != r.Form.Get("Email")) {
+		respondWithText(w, http.StatusUnauthorized, "Error=BadAuthentication\n")
+		return
+	}
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	// the token already identifies the user, so it doubles as the auth token
+	respondWithText(w, http.StatusOK, fmt.Sprintf("SID=%v\nLSID=%v\nAuth=%v\n", token, token, token))
+}
+
+// readerAuthenticated is authenticated for Google Reader clients, which send
+// their token as Authorization: GoogleLogin auth=<token>
+func (srv *server) readerAuthenticated(handler func(w http.ResponseWriter, r *http.Request, user database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !ok || token == "" {
+			respondWithText(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		user, err := srv.authenticate(r.Context(), strings.TrimSpace(token), scopeGReader)
+		var authErr authError
+		if errors.As(err, &authErr) {
+			respondWithText(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if err != nil {
+			respondWithErr(w, err)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			respondWithText(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+func (srv *server) readerToken(w http.ResponseWriter, r *http.Request, user database.User) {
+	// clients send this back as T on every edit. Requests are authenticated by
+	// a header rather than a cookie, so it isn't needed against CSRF and any
+	// value is accepted.
+	respondWithText(w, http.StatusOK, hashToken(user.ID.String())[:57]+"\n")
+}
+
+func (srv *server) readerUserInfo(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     "",
+	})
+}
+
+type readerCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type readerSubscription struct {
+	ID         string           `json:"id"`
+	Title      string           `json:"title"`
+	Categories []readerCategory `json:"categories"`
+	URL        string           `json:"url"`
+	HTMLURL    string           `json:"htmlUrl"`
+	IconURL    string           `json:"iconUrl"`
+	FirstItem  string           `json:"firstitemmsec"`
+}
+
+func (srv *server) readerSubscriptions(w http.ResponseWriter, r *http.Request, user database.User) {
+	rows, err := srv.s.db.GetReaderSubscriptions(r.Context(), user.ID)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	subscriptions := make([]readerSubscription, 0, len(rows))
+	for _, row := range rows {
+		categories := make([]readerCategory, 0, 1)
+		if row.FolderName.Valid {
+			categories = append(categories, readerCategory{
+				ID:    readerLabelPrefix + row.FolderName.String,
+				Label: row.FolderName.String,
+			})
+		}
+		subscriptions = append(subscriptions, readerSubscription{
+			ID:         readerFeedID(row.SerialID),
+			Title:      row.Title,
+			Categories: categories,
+			URL:        row.Url,
+			HTMLURL:    row.SiteUrl,
+			FirstItem:  strconv.FormatInt(row.CreatedAt.UnixMilli(), 10),
+		})
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{"subscriptions": subscriptions})
+}
+
+func (srv *server) readerEditSubscription(w http.ResponseWriter, r *http.Request, user database.User) {
+	// ac=subscribe|unsubscribe|edit on the stream s, with an optional title t,
+	// a label a to move it into and a label r to take it out of
+	for _, streamID := range r.Form["s"] {
+		var err error
+		switch r.Form.Get("ac") {
+		case "subscribe":
+			_, err = srv.readerSubscribe(r, user, streamID)
+		case "unsubscribe":
+			err = srv.readerUnsubscribe(r, user, streamID)
+		case "edit":
+			err = srv.readerEdit(r, user, streamID)
+		default:
+			err = readerError("unknown action " + r.Form.Get("ac"))
+		}
+		if err != nil {
+			respondWithReaderErr(w, err)
+			return
+		}
+	}
+	respondWithText(w, http.StatusOK, "OK")
+}
+
+func (srv *server) readerQuickAdd(w http.ResponseWriter, r *http.Request, user database.User) {
+	// subscribes to a feed url
+	streamID := r.Form.Get("quickadd")
+	if !strings.HasPrefix(streamID, "feed/") {
+		streamID = "feed/" + streamID
+	}
+	feed, err := srv.readerSubscribe(r, user, streamID)
+	if err != nil {
+		respondWithReaderErr(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"numResults": 1,
+		"query":      feed.Url,
+		"streamId":   readerFeedID(feed.SerialID),
+		"streamName": feed.Name,
+	})
+}
+
+func (srv *server) readerSubscribe(r *http.Request, user database.User, streamID string) (database.Feed, error) {
+	// follows the feed in streamID, adding it first if nobody has yet
+	ref, ok := strings.CutPrefix(streamID, "feed/")
+	if !ok {
+		return database.Feed{}, readerError("not a feed: " + streamID)
+	}
+	feed, err := srv.readerFeed(r, streamID)
+	var nf notFoundError
+	if errors.As(err, &nf) {
+		// not a feed gator has yet, so it has to be a url it can add
+		if err := validateFeedURL(ref); err != nil {
+			return database.Feed{}, readerError(err.Error())
+		}
+		feed.Url = ref
+	} else if err != nil {
+		return database.Feed{}, err
+	}
+	feed, err = subscribe(srv.s, user, feed.Url, r.Form.Get("t"), "greader")
+	if err != nil {
+		return database.Feed{}, err
+	}
+	return feed, srv.readerEdit(r, user, readerFeedID(feed.SerialID))
+}
+
+func (srv *server) readerUnsubscribe(r *http.Request, user database.User, streamID string) error {
+	feed, err := srv.readerFeed(r, streamID)
+	if err != nil {
+		return err
+	}
+	err = srv.s.db.Unfollow(r.Context(), database.UnfollowParams{FeedID: feed.ID, UserID: user.ID})
+	if err != nil {
+		return err
+	}
+	audit(srv.s, user, "unfollow", feed.Url, "greader")
+	return nil
+}
+
+func (srv *server) readerEdit(r *http.Request, user database.User, streamID string) error {
+	// applies the title and label changes of a subscription edit
+	feed, err := srv.readerFeed(r, streamID)
+	if err != nil {
+		return err
+	}
+	follow, err := srv.s.db.GetFeedFollow(r.Context(), database.GetFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("not following %v", feed.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if title := r.Form.Get("t"); title != "" && title != feed.Name {
+		_, err := srv.s.db.SetFeedFollowTitle(r.Context(), database.SetFeedFollowTitleParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+			Title:  sql.NullString{String: title, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// a feed is in at most one folder, so adding a label moves it there
+	folderID := follow.FolderID
+	if label, ok := strings.CutPrefix(readerStreamID(r.Form.Get("r")), readerLabelPrefix); ok {
+		folder, err := lookupFolder(srv.s, user, label)
+		if err != nil {
+			return err
+		}
+		if folderID.Valid && folderID.UUID == folder.ID {
+			folderID = uuid.NullUUID{}
+		}
+	}
+	if label, ok := strings.CutPrefix(readerStreamID(r.Form.Get("a")), readerLabelPrefix); ok {
+		folderID, err = ensureFolder(r.Context(), srv.s.db, user, label)
+		if err != nil {
+			return err
+		}
+	}
+	if folderID == follow.FolderID {
+		return nil
+	}
+	_, err = srv.s.db.SetFeedFollowFolder(r.Context(), database.SetFeedFollowFolderParams{
+		UserID:   user.ID,
+		FeedID:   feed.ID,
+		FolderID: folderID,
+	})
+	return err
+}
+
+func (srv *server) readerTags(w http.ResponseWriter, r *http.Request, user database.User) {
+	// lists the starred state and a label per folder
+	folders, err := srv.s.db.GetFoldersForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	tags := []map[string]string{{"id": readerStarred}}
+	for _, folder := range folders {
+		tags = append(tags, map[string]string{"id": readerLabelPrefix + folder.Name, "type": "folder"})
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+func (srv *server) readerUnreadCount(w http.ResponseWriter, r *http.Request, user database.User) {
+	// counts unread items per feed, per label and in the reading list
+	rows, err := srv.s.db.GetReaderUnreadCounts(r.Context(), user.ID)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+
+	type count struct {
+		ID      string `json:"id"`
+		Count   int64  `json:"count"`
+		Newest  string `json:"newestItemTimestampUsec"`
+		newestT time.Time
+	}
+	counts := make([]count, 0, len(rows)+1)
+	labels := make(map[string]int)
+	total := count{ID: readerReadingList}
+	add := func(c *count, n int64, newest time.Time) {
+		c.Count += n
+		if newest.After(c.newestT) {
+			c.newestT = newest
+		}
+		c.Newest = strconv.FormatInt(c.newestT.UnixMicro(), 10)
+	}
+	for _, row := range rows {
+		feed := count{ID: readerFeedID(row.SerialID)}
+		add(&feed, row.Unread, row.Newest)
+		counts = append(counts, feed)
+		add(&total, row.Unread, row.Newest)
+		if !row.FolderName.Valid {
+			continue
+		}
+		i, ok := labels[row.FolderName.String]
+		if !ok {
+			i = len(counts)
+			labels[row.FolderName.String] = i
+			counts = append(counts, count{ID: readerLabelPrefix + row.FolderName.String})
+		}
+		add(&counts[i], row.Unread, row.Newest)
+	}
+	counts = append(counts, total)
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"max":          readerItemLimit,
+		"unreadcounts": counts,
+	})
+}
+
+// readerQuery is a stream request turned into query parameters
+type readerQuery struct {
+	streamID string
+	params   database.GetReaderItemsParams
+}
+
+func (srv *server) parseReaderQuery(r *http.Request, user database.User, streamID string) (readerQuery, error) {
+	// reads the stream id and the n, r, c, xt, it, ot and nt parameters
+	q := readerQuery{
+		streamID: readerStreamID(streamID),
+		params: database.GetReaderItemsParams{
+			UserID: user.ID,
+			Limit:  readerDefaultItems,
+		},
+	}
+	if err := q.applyStream(srv, r, q.streamID, true); err != nil {
+		return readerQuery{}, err
+	}
+	for _, exclude := range r.Form["xt"] {
+		if err := q.applyStream(srv, r, readerStreamID(exclude), false); err != nil {
+			return readerQuery{}, err
+		}
+	}
+	for _, include := range r.Form["it"] {
+		if err := q.applyStream(srv, r, readerStreamID(include), true); err != nil {
+			return readerQuery{}, err
+		}
+	}
+
+	if value := r.Form.Get("n"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return readerQuery{}, readerError("invalid n")
+		}
+		q.params.Limit = int32(min(n, readerItemLimit))
+	}
+	q.params.OldestFirst = r.Form.Get("r") == "o"
+	if value := r.Form.Get("c"); value != "" {
+		c, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return readerQuery{}, readerError("invalid continuation")
+		}
+		q.params.Continuation = sql.NullInt64{Int64: c, Valid: true}
+	}
+	for key, field := range map[string]*sql.NullTime{"ot": &q.params.NewerThan, "nt": &q.params.OlderThan} {
+		value := r.Form.Get(key)
+		if value == "" {
+			continue
+		}
+		unix, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return readerQuery{}, readerError("invalid " + key)
+		}
+		*field = sql.NullTime{Time: time.Unix(unix, 0).UTC(), Valid: true}
+	}
+	return q, nil
+}
+
+func (q *readerQuery) applyStream(srv *server, r *http.Request, streamID string, include bool) error {
+	// narrows the query to a stream, or to everything outside it
+	switch {
+	case streamID == readerReadingList || streamID == "":
+		if !include {
+			return readerError("can't exclude the reading list")
+		}
+	case streamID == readerRead:
+		q.params.ReadOnly = include
+		q.params.ExcludeRead = !include
+	case streamID == readerStarred:
+		q.params.StarredOnly = include
+		q.params.ExcludeStarred = !include
+	case strings.HasPrefix(streamID, readerLabelPrefix) && include:
+		q.params.Folder = sql.NullString{String: strings.TrimPrefix(streamID, readerLabelPrefix), Valid: true}
+	case strings.HasPrefix(streamID, "feed/") && include:
+		feed, err := srv.readerFeed(r, streamID)
+		if err != nil {
+			return err
+		}
+		q.params.FeedSerialID = sql.NullInt64{Int64: feed.SerialID, Valid: true}
+	case strings.HasPrefix(streamID, "user/-/state/com.google/"):
+		// other states, like kept-unread, aren't tracked by gator
+	default:
+		return readerError("unsupported stream " + streamID)
+	}
+	return nil
+}
+
+func (srv *server) readerStreamContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	streamID := r.PathValue("stream")
+	if streamID == "" {
+		streamID = r.Form.Get("s")
+	}
+	q, err := srv.parseReaderQuery(r, user, streamID)
+	if err != nil {
+		respondWithReaderErr(w, err)
+		return
+	}
+	// fetch one extra item to find out whether there is another page
+	q.params.Limit++
+	rows, err := srv.s.db.GetReaderItems(r.Context(), q.params)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	resp := map[string]any{
+		"direction": "ltr",
+		"id":        q.streamID,
+		"updated":   time.Now().Unix(),
+	}
+	if len(rows) == int(q.params.Limit) {
+		rows = rows[:len(rows)-1]
+		resp["continuation"] = strconv.FormatInt(rows[len(rows)-1].SerialID, 10)
+	}
+	resp["items"] = readerItems(rows)
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (srv *server) readerItemIDs(w http.ResponseWriter, r *http.Request, user database.User) {
+	q, err := srv.parseReaderQuery(r, user, r.Form.Get("s"))
+	if err != nil {
+		respondWithReaderErr(w, err)
+		return
+	}
+	p := q.params
+	rows, err := srv.s.db.GetReaderItemIds(r.Context(), database.GetReaderItemIdsParams{
+		UserID:         p.UserID,
+		FeedSerialID:   p.FeedSerialID,
+		Folder:         p.Folder,
+		ReadOnly:       p.ReadOnly,
+		StarredOnly:    p.StarredOnly,
+		ExcludeRead:    p.ExcludeRead,
+		ExcludeStarred: p.ExcludeStarred,
+		NewerThan:      p.NewerThan,
+		OlderThan:      p.OlderThan,
+		Continuation:   p.Continuation,
+		OldestFirst:    p.OldestFirst,
+		Limit:          p.Limit + 1,
+	})
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	resp := map[string]any{}
+	if len(rows) == int(p.Limit)+1 {
+		rows = rows[:len(rows)-1]
+		resp["continuation"] = strconv.FormatInt(rows[len(rows)-1].SerialID, 10)
+	}
+	refs := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		refs = append(refs, map[string]any{
+			"id":              strconv.FormatInt(row.SerialID, 10),
+			"directStreamIds": []string{},
+			"timestampUsec":   strconv.FormatInt(row.CreatedAt.UnixMicro(), 10),
+		})
+	}
+	resp["itemRefs"] = refs
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (srv *server) readerItemContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	// returns the items listed in i, in any of the id formats
+	ids, err := parseReaderItemIDs(r.Form["i"])
+	if err != nil {
+		respondWithReaderErr(w, err)
+		return
+	}
+	rows, err := srv.s.db.GetReaderItems(r.Context(), database.GetReaderItemsParams{
+		UserID:  user.ID,
+		WithIds: ids,
+		Limit:   int32(len(ids)),
+	})
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{
+		"direction": "ltr",
+		"id":        readerReadingList,
+		"updated":   time.Now().Unix(),
+		"items":     readerItems(rows),
+	})
+}
+
+func (srv *server) readerEditTag(w http.ResponseWriter, r *http.Request, user database.User) {
+	// adds (a) or removes (r) the read and starred states of the items in i
+	ids, err := parseReaderItemIDs(r.Form["i"])
+	if err != nil {
+		respondWithReaderErr(w, err)
+		return
+	}
+	states := make([]string, 0, 2)
+	for _, tag := range r.Form["a"] {
+		switch readerStreamID(tag) {
+		case readerRead:
+			states = append(states, "read")
+		case readerStarred:
+			states = append(states, "starred")
+		}
+	}
+	for _, tag := range r.Form["r"] {
+		switch readerStreamID(tag) {
+		case readerRead:
+			states = append(states, "unread")
+		case readerStarred:
+			states = append(states, "unstarred")
+		}
+	}
+
+	for _, id := range ids {
+		postID, err := srv.s.db.GetPostIdBySerialId(r.Context(), database.GetPostIdBySerialIdParams{
+			UserID:   user.ID,
+			SerialID: id,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			respondWithErr(w, err)
+			return
+		}
+		for _, state := range states {
+			if err := setPostState(srv.s, user, postID, state); err != nil {
+				respondWithErr(w, err)
+				return
+			}
+		}
+	}
+	respondWithText(w, http.StatusOK, "OK")
+}
+
+func (srv *server) readerMarkAllRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	// marks every item in stream s read, up to the timestamp ts in microseconds
+	params := database.MarkReaderStreamReadParams{
+		UserID: user.ID,
+		Before: time.Now().UTC(),
+	}
+	if value := r.Form.Get("ts"); value != "" {
+		usec, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			respondWithReaderErr(w, readerError("invalid ts"))
+			return
+		}
+		params.Before = time.UnixMicro(usec).UTC()
+	}
+	streamID := readerStreamID(r.Form.Get("s"))
+	switch {
+	case streamID == readerReadingList:
+	case strings.HasPrefix(streamID, readerLabelPrefix):
+		params.Folder = sql.NullString{String: strings.TrimPrefix(streamID, readerLabelPrefix), Valid: true}
+	case strings.HasPrefix(streamID, "feed/"):
+		feed, err := srv.readerFeed(r, streamID)
+		if err != nil {
+			respondWithReaderErr(w, err)
+			return
+		}
+		params.FeedSerialID = sql.NullInt64{Int64: feed.SerialID, Valid: true}
+	default:
+		respondWithReaderErr(w, readerError("unsupported stream "+streamID))
+		return
+	}
+	if _, err := srv.s.db.MarkReaderStreamRead(r.Context(), params); err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	respondWithText(w, http.StatusOK, "OK")
+}
+
+func readerItems(rows []database.GetReaderItemsRow) []map[string]any {
+	items := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		categories := []string{readerReadingList}
+		if row.FolderName.Valid {
+			categories = append(categories, readerLabelPrefix+row.FolderName.String)
+		}
+		if row.ReadAt.Valid {
+			categories = append(categories, readerRead)
+		}
+		if row.StarredAt.Valid {
+			categories = append(categories, readerStarred)
+		}
+		content := row.Content
+		if content == "" {
+			content = row.Description
+		}
+		published := publishedOrCreated(row.PublishedAt, row.CreatedAt)
+		links := []map[string]string{{"href": row.Url, "type": "text/html"}}
+		items = append(items, map[string]any{
+			"id":            fmt.Sprintf("%v%016x", readerItemPrefix, row.SerialID),
+			"crawlTimeMsec": strconv.FormatInt(row.CreatedAt.UnixMilli(), 10),
+			"timestampUsec": strconv.FormatInt(row.CreatedAt.UnixMicro(), 10),
+			"published":     published.Unix(),
+			"updated":       published.Unix(),
+			"title":         row.Title,
+			"author":        row.Author,
+			"canonical":     links,
+			"alternate":     links,
+			"categories":    categories,
+			"summary":       map[string]string{"direction": "ltr", "content": content},
+			"origin": map[string]string{
+				"streamId": readerFeedID(row.FeedSerialID),
+				"title":    row.FeedTitle,
+				"htmlUrl":  row.SiteUrl,
+			},
+		})
+	}
+	return items
+}
+
+func (srv *server) readerFeed(r *http.Request, streamID string) (database.Feed, error) {
+	// finds the feed in feed/<serial_id> or feed/<url>
+	ref, ok := strings.CutPrefix(streamID, "feed/")
+	if !ok {
+		return database.Feed{}, readerError("not a feed: " + streamID)
+	}
+	if serialID, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		feed, err := srv.s.db.GetFeedBySerialId(r.Context(), serialID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Feed{}, notFound("no feed %v", streamID)
+		}
+		return feed, err
+	}
+	feed, err := srv.s.db.GetFeedByUrl(r.Context(), ref)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, notFound("no feed %v", streamID)
+	}
+	return feed, err
+}
+
+func readerFeedID(serialID int64) string {
+	return "feed/" + strconv.FormatInt(serialID, 10)
+}
+
+func readerStreamID(streamID string) string {
+	// clients may name the user instead of using -, so normalize to user/-/
+	rest, ok := strings.CutPrefix(streamID, "user/")
+	if !ok {
+		return streamID
+	}
+	if _, tail, ok := strings.Cut(rest, "/"); ok {
+		return "user/-/" + tail
+	}
+	return streamID
+}
+
+func parseReaderItemIDs(values []string) ([]int64, error) {
+	// item ids come as tag:google.com,2005:reader/item/<hex> or as decimals
+	if len(values) == 0 {
+		return nil, readerError("no items given")
+	}
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		var id int64
+		var err error
+		if hex, ok := strings.CutPrefix(value, readerItemPrefix); ok {
+			var u uint64
+			u, err = strconv.ParseUint(hex, 16, 64)
+			id = int64(u)
+		} else {
+			id, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return nil, readerError("invalid item id " + value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// readerError is a malformed Google Reader request
+type readerError string
+
+func (e readerError) Error() string {
+	return string(e)
+}
+
+func respondWithReaderErr(w http.ResponseWriter, err error) {
+	var badRequest readerError
+	if errors.As(err, &badRequest) {
+		respondWithText(w, http.StatusBadRequest, badRequest.Error())
+		return
+	}
+	var nf notFoundError
+	if errors.As(err, &nf) {
+		respondWithText(w, http.StatusNotFound, nf.msg)
+		return
+	}
+	respondWithErr(w, err)
+}
+
+func respondWithText(w http.ResponseWriter, code int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write([]byte(text))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReaderTimesAreUTC(t *testing.T) {
+	// ot, nt and ts are unix times, so they have to match posts fetched
+	// while the local clock is ahead of UTC
+	localAheadOfUTC(t)
+	s := testState(t)
+	srv := httptest.NewServer((&server{s: s}).routes())
+	t.Cleanup(srv.Close)
+	user := testUser(t, s, "alice")
+	token := testToken(t, s, user, sql.NullTime{}, scopeGReader)
+	feed := testFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+
+	fetched := time.Now()
+	savePosts(s, feed, []RSSItem{{Title: "Go 1.23 is released", Link: "https://go.dev/blog/go1.23"}})
+
+	ids := func(query string) []string {
+		t.Helper()
+		var resp struct {
+			ItemRefs []struct {
+				ID            string `json:"id"`
+				TimestampUsec string `json:"timestampUsec"`
+			} `json:"itemRefs"`
+		}
+		body := readerDo(t, srv, "GET", "/reader/api/0/stream/items/ids?s="+readerReadingList+query, token, nil)
+		if err := json.Unmarshal([]byte(body), &resp); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, ref := range resp.ItemRefs {
+			usec, err := strconv.ParseInt(ref.TimestampUsec, 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := time.UnixMicro(usec).Sub(fetched); d < -time.Minute || d > time.Minute {
+				t.Errorf("timestampUsec is %v from when the post was fetched", d)
+			}
+			got = append(got, ref.ID)
+		}
+		return got
+	}
+	unix := func(d time.Duration) string { return strconv.FormatInt(fetched.Add(d).Unix(), 10) }
+
+	if got := ids(""); len(got) != 1 {
+		t.Fatalf("reading list = %v, want the new post", got)
+	}
+	if got := ids("&ot=" + unix(-time.Minute)); len(got) != 1 {
+		t.Errorf("ot a minute before = %v, want the new post", got)
+	}
+	if got := ids("&ot=" + unix(time.Hour)); len(got) != 0 {
+		t.Errorf("ot an hour later = %v, want nothing", got)
+	}
+	if got := ids("&nt=" + unix(time.Hour)); len(got) != 1 {
+		t.Errorf("nt an hour later = %v, want the new post", got)
+	}
+
+	// marking read up to a minute before leaves the post unread, up to a
+	// minute after doesn't
+	markAllRead := func(d time.Duration) {
+		t.Helper()
+		form := url.Values{"s": {readerReadingList}, "ts": {strconv.FormatInt(fetched.Add(d).UnixMicro(), 10)}}
+		readerDo(t, srv, "POST", "/reader/api/0/mark-all-as-read", token, form)
+	}
+	unread := "&xt=" + readerRead
+	markAllRead(-time.Minute)
+	if got := ids(unread); len(got) != 1 {
+		t.Fatalf("unread after ts a minute before = %v, want the new post", got)
+	}
+	markAllRead(time.Minute)
+	if got := ids(unread); len(got) != 0 {
+		t.Fatalf("unread after ts a minute later = %v, want nothing", got)
+	}
+}
+
+func readerDo(t *testing.T, srv *httptest.Server, method, path, token string, form url.Values) string {
+	// sends a Google Reader request, failing unless it's a 200
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "GoogleLogin auth="+token)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("%v %v = %v: %s", method, path, res.StatusCode, data)
+	}
+	return string(data)
+}
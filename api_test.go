@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestAPIRequiresBearerToken(t *testing.T) {
+	// requests without a token are turned away before the database is used
+	srv := &server{s: &state{}}
+	for _, header := range []string{"", "Bearer ", "Basic YWxpY2U6c2VjcmV0"} {
+		req := httptest.NewRequest("GET", "/api/feeds", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		srv.routes().ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %v, want 401", header, rec.Code)
+		}
+		if got := rec.Header().Get("WWW-Authenticate"); got != `Bearer realm="gator"` {
+			t.Errorf("Authorization %q: WWW-Authenticate = %q", header, got)
+		}
+		if got := apiError(t, rec.Body); got != "missing bearer token" {
+			t.Errorf("Authorization %q: error = %q", header, got)
+		}
+	}
+}
+
+func TestAPIRejectsBadTokens(t *testing.T) {
+	s := testState(t)
+	srv := httptest.NewServer((&server{s: s}).routes())
+	t.Cleanup(srv.Close)
+	user := testUser(t, s, "alice")
+	readOnly := testToken(t, s, user, sql.NullTime{}, scopeRead)
+	expired := testToken(t, s, user, sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}, scopeRead, scopeWrite)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+		err    string
+	}{
+		{"unknown token", "GET", "/api/feeds", tokenPrefix + "not-a-real-token", http.StatusUnauthorized, "invalid token"},
+		{"expired token", "GET", "/api/feeds", expired, http.StatusUnauthorized, "token expired"},
+		{"missing scope", "POST", "/api/feeds", readOnly, http.StatusForbidden, "token lacks the write scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := apiDo(t, srv, tt.method, tt.path, tt.token, `{"name":"Go Blog","url":"https://go.dev/blog/feed.atom"}`)
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %v, want %v: %s", res.StatusCode, tt.status, body)
+			}
+			if got := apiError(t, strings.NewReader(body)); got != tt.err {
+				t.Errorf("error = %q, want %q", got, tt.err)
+			}
+			if !strings.HasPrefix(res.Header.Get("WWW-Authenticate"), `Bearer realm="gator", error=`) {
+				t.Errorf("WWW-Authenticate = %q", res.Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+	// a rejected token doesn't change anything
+	if feeds, err := s.db.GetFeeds(context.Background()); err != nil || len(feeds) != 0 {
+		t.Fatalf("GetFeeds = %v, %v, want no feeds", len(feeds), err)
+	}
+}
+
+func TestAPIFeeds(t *testing.T) {
+	s := testState(t)
+	srv := httptest.NewServer((&server{s: s}).routes())
+	t.Cleanup(srv.Close)
+	user := testUser(t, s, "alice")
+	token := testToken(t, s, user, sql.NullTime{}, scopeRead, scopeWrite)
+
+	res, body := apiDo(t, srv, "POST", "/api/feeds", token, `{"name":"Go Blog","url":"https://go.dev/blog/feed.atom"}`)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /api/feeds = %v: %s", res.StatusCode, body)
+	}
+	var created apiFeed
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Name != "Go Blog" || created.URL != "https://go.dev/blog/feed.atom" || created.UserID != user.ID {
+		t.Fatalf("created %+v", created)
+	}
+
+	var feeds []apiFeed
+	apiGet(t, srv, "/api/feeds", token, &feeds)
+	if len(feeds) != 1 || feeds[0].ID != created.ID {
+		t.Fatalf("GET /api/feeds = %+v, want the new feed", feeds)
+	}
+	var follows []apiFollow
+	apiGet(t, srv, "/api/follows", token, &follows)
+	if len(follows) != 1 || follows[0].FeedID != created.ID {
+		t.Fatalf("GET /api/follows = %+v, want the new feed followed", follows)
+	}
+
+	errorTests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"duplicate feed", "POST", "/api/feeds", `{"name":"Go","url":"https://go.dev/blog/feed.atom"}`, http.StatusConflict},
+		{"missing name", "POST", "/api/feeds", `{"url":"https://example.com/feed"}`, http.StatusBadRequest},
+		{"not an http url", "POST", "/api/feeds", `{"name":"Local","url":"file:///etc/passwd"}`, http.StatusBadRequest},
+		{"unknown field", "POST", "/api/feeds", `{"name":"Example","url":"https://example.com/feed","folder":"news"}`, http.StatusBadRequest},
+		{"malformed body", "POST", "/api/feeds", `{"name":`, http.StatusBadRequest},
+		{"already following", "POST", "/api/follows", `{"feed":"Go Blog"}`, http.StatusConflict},
+		{"follow unknown feed", "POST", "/api/follows", `{"feed":"https://example.com/feed"}`, http.StatusNotFound},
+		{"unfollow unknown feed", "DELETE", "/api/follows/" + uuid.NewString(), "", http.StatusNotFound},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := apiDo(t, srv, tt.method, tt.path, token, tt.body)
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %v, want %v: %s", res.StatusCode, tt.status, body)
+			}
+			if apiError(t, strings.NewReader(body)) == "" {
+				t.Errorf("no error message in %s", body)
+			}
+		})
+	}
+
+	res, body = apiDo(t, srv, "DELETE", "/api/follows/"+created.ID.String(), token, "")
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE /api/follows = %v: %s", res.StatusCode, body)
+	}
+	res, body = apiDo(t, srv, "DELETE", "/api/follows/"+created.ID.String(), token, "")
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("second DELETE /api/follows = %v, want 404: %s", res.StatusCode, body)
+	}
+}
+
+func TestAPIPosts(t *testing.T) {
+	s := testState(t)
+	srv := httptest.NewServer((&server{s: s}).routes())
+	t.Cleanup(srv.Close)
+	user := testUser(t, s, "alice")
+	token := testToken(t, s, user, sql.NullTime{}, scopeRead, scopeWrite)
+	feed := testFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+	post := testPost(t, s, feed, "Go 1.23 is released", "https://go.dev/blog/go1.23")
+	testPost(t, s, feed, "Range over functions", "https://go.dev/blog/range-functions")
+
+	var posts []apiPost
+	apiGet(t, srv, "/api/posts", token, &posts)
+	if len(posts) != 2 {
+		t.Fatalf("GET /api/posts = %d posts, want 2", len(posts))
+	}
+
+	for _, path := range []string{"/api/posts/" + post.ID.String() + "/read", "/api/posts/" + post.ID.String() + "/star"} {
+		res, body := apiDo(t, srv, "PUT", path, token, "")
+		if res.StatusCode != http.StatusNoContent {
+			t.Fatalf("PUT %v = %v: %s", path, res.StatusCode, body)
+		}
+	}
+	var got apiPost
+	apiGet(t, srv, "/api/posts/"+post.ID.String(), token, &got)
+	if got.ID != post.ID || got.Feed != "Go Blog" || !got.Read || !got.Starred {
+		t.Fatalf("GET /api/posts/{post} = %+v, want it read and starred", got)
+	}
+	apiGet(t, srv, "/api/posts?unread", token, &posts)
+	if len(posts) != 1 || posts[0].Title != "Range over functions" {
+		t.Fatalf("GET /api/posts?unread = %+v, want only the unread post", posts)
+	}
+	apiGet(t, srv, "/api/posts?starred=true&limit=5", token, &posts)
+	if len(posts) != 1 || posts[0].ID != post.ID {
+		t.Fatalf("GET /api/posts?starred = %+v, want only the starred post", posts)
+	}
+
+	res, body := apiDo(t, srv, "DELETE", "/api/posts/"+post.ID.String()+"/read", token, "")
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE read = %v: %s", res.StatusCode, body)
+	}
+	apiGet(t, srv, "/api/posts?unread", token, &posts)
+	if len(posts) != 2 {
+		t.Fatalf("GET /api/posts?unread = %d posts after marking unread, want 2", len(posts))
+	}
+
+	// posts from feeds alice doesn't follow aren't hers to see or mark
+	bob := testUser(t, s, "bob")
+	hidden := testPost(t, s, testFeed(t, s, bob, "Hacker News", "https://news.ycombinator.com/rss"), "Show HN: gator", "https://news.ycombinator.com/item?id=1")
+
+	errorTests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"unknown post", "GET", "/api/posts/" + uuid.NewString(), http.StatusNotFound},
+		{"unfollowed post", "GET", "/api/posts/" + hidden.ID.String(), http.StatusNotFound},
+		{"mark unfollowed post", "PUT", "/api/posts/" + hidden.ID.String() + "/star", http.StatusNotFound},
+		{"mark unknown post", "PUT", "/api/posts/" + uuid.NewString() + "/read", http.StatusNotFound},
+		{"unknown parameter", "GET", "/api/posts?color=red", http.StatusBadRequest},
+		{"invalid limit", "GET", "/api/posts?limit=many", http.StatusBadRequest},
+		{"zero limit", "GET", "/api/posts?limit=0", http.StatusBadRequest},
+		{"wrong method", "PATCH", "/api/posts/" + post.ID.String(), http.StatusMethodNotAllowed},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := apiDo(t, srv, tt.method, tt.path, token, "")
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %v, want %v: %s", res.StatusCode, tt.status, body)
+			}
+		})
+	}
+}
+
+func testToken(t *testing.T, s *state, user database.User, expiresAt sql.NullTime, scopes ...string) string {
+	// creates an API token for user and returns it in the clear
+	t.Helper()
+	token, err := newToken(tokenPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.db.CreateAPIToken(context.Background(), database.CreateAPITokenParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      "test-" + strings.Join(scopes, "-"),
+		TokenHash: hashToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func apiDo(t *testing.T, srv *httptest.Server, method, path, token, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, string(data)
+}
+
+func apiGet(t *testing.T, srv *httptest.Server, path, token string, v any) {
+	// fetches path and decodes its JSON into v, failing unless it's a 200
+	t.Helper()
+	res, body := apiDo(t, srv, "GET", path, token, "")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET %v = %v: %s", path, res.StatusCode, body)
+	}
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		t.Fatalf("GET %v: %v", path, err)
+	}
+}
+
+func apiError(t *testing.T, body io.Reader) string {
+	// the message in an {"error": ...} response
+	t.Helper()
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		t.Fatalf("decoding error response: %v", err)
+	}
+	return resp.Error
+}
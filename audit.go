@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func recordAudit(ctx context.Context, q *database.Queries, actor database.User, action, target, details string) error {
+	// writes an audit_log entry. The actor's name is kept alongside the id
+	// so entries stay readable after the actor's account is deleted. A zero
+	// actor, setting up a database without users, leaves both empty.
+	return q.CreateAuditLogEntry(ctx, database.CreateAuditLogEntryParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		ActorID:   uuid.NullUUID{UUID: actor.ID, Valid: actor.ID != uuid.Nil},
+		ActorName: actor.Name,
+		Action:    action,
+		Target:    target,
+		Details:   details,
+	})
+}
+
+func audit(s *state, actor database.User, action, target, details string) {
+	// records an action that has already happened; failing to record it
+	// shouldn't make the action itself look failed, so errors are only logged
+	err := recordAudit(context.Background(), s.db, actor, action, target, details)
+	if err != nil {
+		log.Printf("couldn't write audit log entry: %v", err)
+	}
+}
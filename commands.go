@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/cryptidcodes/gator/internal/database"
 )
@@ -33,10 +34,62 @@ func (c *commands) register(name string, f func(*state, command) error) {
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
 		// check if a user is logged in
-		user, err := s.db.GetUserByName(context.Background(), s.cfg.CurrentUserName)
+		user, err := currentUser(s)
 		if err != nil {
 			return err
 		}
 		return handler(s, cmd, user)
 	}
 }
+
+func middlewareAdmin(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+	return middlewareLoggedIn(func(s *state, cmd command, user database.User) error {
+		// check the logged in user is an admin
+		if err := checkAdmin(cmd, user); err != nil {
+			return err
+		}
+		return handler(s, cmd, user)
+	})
+}
+
+func checkAdmin(cmd command, user database.User) error {
+	// admin rights only count on accounts with a password, since anyone can
+	// log in by name to one without
+	if !user.IsAdmin {
+		return fmt.Errorf("%v can only be run by an admin", cmd.Name)
+	}
+	if !user.PasswordHash.Valid {
+		return fmt.Errorf("%v can only be run by an admin with a password, set one with: gator passwd", cmd.Name)
+	}
+	return nil
+}
+
+func middlewareAdminOrEmpty(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+	return func(s *state, cmd command) error {
+		// a database without users has no admin to log in as, so anyone may
+		// set it up, the same way the first user to register becomes an admin.
+		// The handler gets a zero user then.
+		count, err := s.db.CountUsers(context.Background())
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return handler(s, cmd, database.User{})
+		}
+		return middlewareAdmin(handler)(s, cmd)
+	}
+}
+
+// notFoundError is returned by the lookup helpers when a user, feed, folder
+// or post doesn't exist, so the server can answer with a 404
+type notFoundError struct {
+	msg string
+}
+
+func (e notFoundError) Error() string {
+	return e.msg
+}
+
+func notFound(format string, args ...any) error {
+	return notFoundError{msg: fmt.Sprintf(format, args...)}
+}
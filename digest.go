@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/config"
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/cryptidcodes/gator/internal/digest"
+	"github.com/google/uuid"
+)
+
+// Digests email each user a summary of their unread posts on a daily or
+// weekly schedule. agg and serve send the ones that are due through the
+// SMTP server in the config file, and remember which posts went out so the
+// next digest only has new ones.
+
+const (
+	// digestInterval is how often digests are checked for being due
+	digestInterval = time.Minute
+	// digestRetry is how long to wait before sending a digest that failed again
+	digestRetry = 15 * time.Minute
+	// digestTimeout bounds a single conversation with the SMTP server
+	digestTimeout = 30 * time.Second
+	// digestMaxPosts is the most posts one digest includes; the rest wait for the next
+	digestMaxPosts = 200
+	// digestBatch is how many due digests are claimed at once
+	digestBatch = 10
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+func digestPeriod(schedule string) time.Duration {
+	if schedule == "weekly" {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+func nextDigestTime(schedule string, hour int, weekday time.Weekday, loc *time.Location, after time.Time) time.Time {
+	// finds the first send time on the schedule after a moment, in UTC
+	t := after.In(loc)
+	day, days := t.Day(), 1
+	if schedule == "weekly" {
+		day += int(weekday-t.Weekday()+7) % 7
+		days = 7
+	}
+	// the time is built from the date each step rather than adding days to
+	// it, so an hour skipped by a DST change doesn't shift later sends
+	next := time.Date(t.Year(), t.Month(), day, hour, 0, 0, 0, loc)
+	for !next.After(t) {
+		day += days
+		next = time.Date(t.Year(), t.Month(), day, hour, 0, 0, 0, loc)
+	}
+	return next.UTC()
+}
+
+func digestLoop(s *state) {
+	// sends due digests for as long as the process runs
+	ticker := time.NewTicker(digestInterval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		if err := sendDueDigests(s); err != nil {
+			log.Printf("digests: %v", err)
+		}
+	}
+}
+
+func sendDueDigests(s *state) error {
+	// sends every due digest, claiming them first so agg and serve running
+	// side by side don't both send the same one
+	for {
+		now := time.Now().UTC()
+		digests, err := s.db.ClaimDueDigests(context.Background(), database.ClaimDueDigestsParams{
+			Now:        now,
+			LeaseUntil: now.Add(digestBatch * 2 * digestTimeout),
+			Limit:      digestBatch,
+		})
+		if err != nil {
+			return err
+		}
+		if len(digests) == 0 {
+			return nil
+		}
+		for _, d := range digests {
+			if err := sendScheduledDigest(s, d); err != nil {
+				log.Printf("digest to %v: %v", d.Email, err)
+			}
+		}
+	}
+}
+
+func sendScheduledDigest(s *state, d database.Digest) error {
+	// sends a digest and schedules the next one, or a retry if it failed
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	params := database.ScheduleDigestParams{
+		UserID:     d.UserID,
+		NextSendAt: nextDigestTime(d.Schedule, int(d.Hour), time.Weekday(d.Weekday), loc, now),
+	}
+	n, sendErr := sendDigest(s, d, loc)
+	switch {
+	case sendErr != nil:
+		params.Error = sendErr.Error()
+		params.NextSendAt = now.Add(digestRetry)
+	case n > 0:
+		params.SentAt = sql.NullTime{Time: now, Valid: true}
+	}
+	if err := s.db.ScheduleDigest(context.Background(), params); err != nil {
+		return err
+	}
+	if sendErr == nil && n > 0 {
+		log.Printf("digests: sent %d posts to %v", n, d.Email)
+	}
+	return sendErr
+}
+
+func sendDigest(s *state, d database.Digest, loc *time.Location) (int, error) {
+	// emails a user's unread posts that no digest has included yet and marks
+	// them delivered. Nothing is sent when there are none.
+	if s.cfg.SMTP == nil {
+		return 0, fmt.Errorf("no smtp server in the config file")
+	}
+	content, postIDs, err := buildDigest(s, d, loc)
+	if err != nil {
+		return 0, err
+	}
+	if len(postIDs) == 0 {
+		return 0, nil
+	}
+	subject := fmt.Sprintf("%v: %d unread posts", content.Title, len(postIDs))
+	msg, err := digest.Message(s.cfg.SMTP.From, d.Email, subject, content)
+	if err != nil {
+		return 0, err
+	}
+	if err := sendMail(*s.cfg.SMTP, d.Email, msg); err != nil {
+		return 0, err
+	}
+	err = s.db.MarkPostsDelivered(context.Background(), database.MarkPostsDeliveredParams{
+		UserID:  d.UserID,
+		PostIds: postIDs,
+	})
+	return len(postIDs), err
+}
+
+func buildDigest(s *state, d database.Digest, loc *time.Location) (digest.Digest, []uuid.UUID, error) {
+	// collects the posts for a digest, grouping them by feed in the order
+	// each feed's newest post appears
+	posts, err := s.db.GetDigestPosts(context.Background(), database.GetDigestPostsParams{
+		UserID: d.UserID,
+		// the first digest reaches back one period rather than to every
+		// unread post ever fetched
+		Since: d.CreatedAt.Add(-digestPeriod(d.Schedule)),
+		Limit: digestMaxPosts,
+	})
+	if err != nil {
+		return digest.Digest{}, nil, err
+	}
+
+	content := digest.Digest{
+		Title: fmt.Sprintf("Your %v gator digest", d.Schedule),
+		Date:  time.Now().In(loc),
+	}
+	feeds := map[string]int{}
+	postIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		i, ok := feeds[post.FeedUrl]
+		if !ok {
+			i = len(content.Feeds)
+			feeds[post.FeedUrl] = i
+			content.Feeds = append(content.Feeds, digest.Feed{
+				Name:    post.FeedName,
+				URL:     post.FeedUrl,
+				SiteURL: post.FeedSiteUrl,
+			})
+		}
+		content.Feeds[i].Posts = append(content.Feeds[i].Posts, digest.Post{
+			Title:     post.Title,
+			URL:       post.Url,
+			Author:    post.Author,
+			Published: publishedOrCreated(post.PublishedAt, post.CreatedAt).In(loc),
+			Summary:   summarize(post.Description),
+		})
+		postIDs = append(postIDs, post.ID)
+	}
+	return content, postIDs, nil
+}
+
+func sendMail(cfg config.SMTPConfig, to string, msg []byte) error {
+	// delivers a message, upgrading to TLS when the server offers it and
+	// logging in when the config has credentials
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid smtp from address %q: %v", cfg.From, err)
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return err
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+		if cfg.TLS {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	dialer := &net.Dialer{Timeout: digestTimeout}
+	var conn net.Conn
+	if cfg.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(digestTimeout))
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && !cfg.TLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		// PlainAuth refuses to send the password unencrypted, except to localhost
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(rcpt.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/config"
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/cryptidcodes/gator/internal/digest"
+)
+
+func TestNextDigestTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(s string) time.Time {
+		t.Helper()
+		ts, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	// Berlin moves from CET (+1) to CEST (+2) on 2026-03-29 at 02:00 and
+	// back on 2026-10-25 at 03:00. 2026-10-19 is a Monday.
+	tests := []struct {
+		name     string
+		schedule string
+		hour     int
+		weekday  time.Weekday
+		loc      *time.Location
+		after    time.Time
+		want     time.Time
+	}{
+		{"daily later today", "daily", 8, 0, berlin, utc("2026-10-19 04:00"), utc("2026-10-19 06:00")},
+		{"daily already sent today", "daily", 8, 0, berlin, utc("2026-10-19 07:00"), utc("2026-10-20 06:00")},
+		{"daily exactly on time", "daily", 8, 0, berlin, utc("2026-10-19 06:00"), utc("2026-10-20 06:00")},
+		{"daily into winter time", "daily", 8, 0, berlin, utc("2026-10-24 07:00"), utc("2026-10-25 07:00")},
+		{"daily into summer time", "daily", 8, 0, berlin, utc("2026-03-28 08:00"), utc("2026-03-29 06:00")},
+		{"daily at the skipped hour", "daily", 2, 0, berlin, utc("2026-03-28 02:00"), utc("2026-03-29 01:00")},
+		{"daily after the skipped hour", "daily", 2, 0, berlin, utc("2026-03-29 01:00"), utc("2026-03-30 00:00")},
+		// Go settles on the second of the two 02:00s
+		{"daily at the repeated hour", "daily", 2, 0, berlin, utc("2026-10-24 01:00"), utc("2026-10-25 01:00")},
+		{"daily tomorrow in the reader's timezone", "daily", 8, 0, newYork, utc("2026-10-20 02:00"), utc("2026-10-20 12:00")},
+		{"weekly later today", "weekly", 8, time.Monday, berlin, utc("2026-10-19 04:00"), utc("2026-10-19 06:00")},
+		{"weekly already sent today", "weekly", 8, time.Monday, berlin, utc("2026-10-19 07:00"), utc("2026-10-26 07:00")},
+		{"weekly later this week", "weekly", 8, time.Friday, berlin, utc("2026-10-19 07:00"), utc("2026-10-23 06:00")},
+		{"weekly wraps to next week", "weekly", 8, time.Monday, berlin, utc("2026-10-24 12:00"), utc("2026-10-26 07:00")},
+		{"weekly on sunday from monday", "weekly", 8, time.Sunday, berlin, utc("2026-10-19 12:00"), utc("2026-10-25 07:00")},
+		{"weekly into summer time", "weekly", 8, time.Sunday, berlin, utc("2026-03-22 08:00"), utc("2026-03-29 06:00")},
+		{"weekly across the new year", "weekly", 8, time.Friday, berlin, utc("2026-12-28 12:00"), utc("2027-01-01 07:00")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextDigestTime(tt.schedule, tt.hour, tt.weekday, tt.loc, tt.after)
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("nextDigestTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// smtpStandIn accepts mail on a local port, just enough of SMTP for sendMail,
+// and keeps every message it's given
+type smtpStandIn struct {
+	addr *net.TCPAddr
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	srv := &smtpStandIn{addr: ln.Addr().(*net.TCPAddr)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+func (srv *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 localhost ready")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, _, _ := strings.Cut(strings.ToUpper(line), " ")
+		switch verb {
+		case "EHLO", "HELO", "MAIL", "RCPT", "RSET", "NOOP":
+			tc.PrintfLine("250 ok")
+		case "DATA":
+			tc.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(tc.DotReader())
+			if err != nil {
+				return
+			}
+			srv.mu.Lock()
+			srv.messages = append(srv.messages, string(data))
+			srv.mu.Unlock()
+			tc.PrintfLine("250 queued")
+		case "QUIT":
+			tc.PrintfLine("221 bye")
+			return
+		default:
+			tc.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (srv *smtpStandIn) config() config.SMTPConfig {
+	return config.SMTPConfig{
+		Host: srv.addr.IP.String(),
+		Port: srv.addr.Port,
+		From: "gator <gator@example.com>",
+	}
+}
+
+func (srv *smtpStandIn) received() []string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append([]string(nil), srv.messages...)
+}
+
+// digestParts splits a digest email into its decoded plain text and HTML
+func digestParts(t *testing.T, raw string) (msg *mail.Message, text, html string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var types []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the reader undoes the quoted-printable encoding
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		types = append(types, partType)
+		switch partType {
+		case "text/plain":
+			text = string(body)
+		case "text/html":
+			html = string(body)
+		}
+	}
+	if strings.Join(types, ",") != "text/plain,text/html" {
+		t.Fatalf("parts = %v, want text/plain then text/html", types)
+	}
+	return msg, text, html
+}
+
+func assertInOrder(t *testing.T, doc string, want ...string) {
+	// checks each string appears in doc after the one before it
+	t.Helper()
+	rest := doc
+	for _, s := range want {
+		i := strings.Index(rest, s)
+		if i < 0 {
+			t.Fatalf("%q missing or out of order in\n%v", s, doc)
+		}
+		rest = rest[i+len(s):]
+	}
+}
+
+func TestSendMailDigest(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	published := time.Date(2026, 10, 19, 7, 30, 0, 0, time.UTC)
+	d := digest.Digest{
+		Title: "Your daily gator digest",
+		Date:  published,
+		Feeds: []digest.Feed{
+			{
+				Name:    "Go Blog",
+				URL:     "https://go.dev/blog/feed.atom",
+				SiteURL: "https://go.dev/blog",
+				Posts: []digest.Post{
+					{Title: "Go 1.23 is released", URL: "https://go.dev/blog/go1.23", Published: published, Summary: "Today the Go team is happy to release Go 1.23."},
+					{Title: "Range over functions", URL: "https://go.dev/blog/range-functions", Author: "Ian", Published: published},
+				},
+			},
+			{
+				Name:  "Hacker News",
+				URL:   "https://news.ycombinator.com/rss",
+				Posts: []digest.Post{{Title: "Show HN: gator & friends", URL: "https://news.ycombinator.com/item?id=1", Published: published}},
+			},
+		},
+	}
+	msg, err := digest.Message("gator <gator@example.com>", "me@example.com", "Your daily gator digest: 3 unread posts", d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sendMail(srv.config(), "me@example.com", msg); err != nil {
+		t.Fatal(err)
+	}
+
+	received := srv.received()
+	if len(received) != 1 {
+		t.Fatalf("got %d messages, want 1", len(received))
+	}
+	m, text, html := digestParts(t, received[0])
+	if got := m.Header.Get("To"); got != "<me@example.com>" {
+		t.Errorf("To = %q", got)
+	}
+	assertInOrder(t, text,
+		"== Go Blog ==", "https://go.dev/blog\n",
+		"* Go 1.23 is released", "Today the Go team",
+		"* Range over functions", "by Ian, ",
+		"== Hacker News ==", "https://news.ycombinator.com/rss\n",
+		"* Show HN: gator & friends",
+	)
+	assertInOrder(t, html,
+		`<a href="https://go.dev/blog"`, "Go Blog</a></h2>",
+		`<a href="https://go.dev/blog/go1.23"`,
+		`<a href="https://go.dev/blog/range-functions"`,
+		`<a href="https://news.ycombinator.com/rss"`, "Hacker News</a></h2>",
+		"Show HN: gator &amp; friends",
+	)
+}
+
+func TestSendDigestSkipsDeliveredPosts(t *testing.T) {
+	s := testState(t)
+	ctx := context.Background()
+	srv := newSMTPStandIn(t)
+	smtpConfig := srv.config()
+	s.cfg.SMTP = &smtpConfig
+
+	user := testUser(t, s, "alice")
+	goBlog := testFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+	hn := testFeed(t, s, user, "Hacker News", "https://news.ycombinator.com/rss")
+	// feeds are ordered by their newest post, posts newest first
+	publishPost(t, s, testPost(t, s, goBlog, "Go 1.23 is released", "https://go.dev/blog/go1.23"), 3*time.Hour)
+	publishPost(t, s, testPost(t, s, hn, "Show HN: gator", "https://news.ycombinator.com/item?id=1"), 2*time.Hour)
+	publishPost(t, s, testPost(t, s, goBlog, "Range over functions", "https://go.dev/blog/range-functions"), time.Hour)
+	read := testPost(t, s, hn, "Already read", "https://news.ycombinator.com/item?id=2")
+	if err := s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: read.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := s.db.UpsertDigest(ctx, database.UpsertDigestParams{
+		UserID:     user.ID,
+		Email:      "alice@example.com",
+		Schedule:   "daily",
+		Hour:       8,
+		Timezone:   "UTC",
+		NextSendAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := sendDigest(s, d, time.UTC)
+	if err != nil || n != 3 {
+		t.Fatalf("sendDigest = %v, %v, want 3 posts sent", n, err)
+	}
+	received := srv.received()
+	if len(received) != 1 {
+		t.Fatalf("got %d messages, want 1", len(received))
+	}
+	_, text, _ := digestParts(t, received[0])
+	assertInOrder(t, text,
+		"== Go Blog ==", "* Range over functions", "* Go 1.23 is released",
+		"== Hacker News ==", "* Show HN: gator",
+	)
+	if strings.Contains(text, "Already read") {
+		t.Errorf("digest includes a read post:\n%v", text)
+	}
+
+	// posts that went out aren't sent again, only ones fetched since
+	n, err = sendDigest(s, d, time.UTC)
+	if err != nil || n != 0 {
+		t.Fatalf("second sendDigest = %v, %v, want nothing sent", n, err)
+	}
+	testPost(t, s, hn, "Ask HN: digests?", "https://news.ycombinator.com/item?id=3")
+	content, postIDs, err := buildDigest(s, d, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(postIDs) != 1 || len(content.Feeds) != 1 || content.Feeds[0].Posts[0].Title != "Ask HN: digests?" {
+		t.Fatalf("next digest = %+v, want only the new post", content.Feeds)
+	}
+	if n := len(srv.received()); n != 1 {
+		t.Fatalf("got %d messages, want 1", n)
+	}
+}
+
+func publishPost(t *testing.T, s *state, post database.Post, ago time.Duration) {
+	t.Helper()
+	_, err := s.conn.Exec("UPDATE posts SET published_at = $1 WHERE id = $2", time.Now().UTC().Add(-ago), post.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
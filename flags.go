@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseFlags parses fs from args and returns the positional args.
+// Unlike fs.Parse, flags may appear before, between or after positional args.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseTimeArg parses an absolute date (2006-01-02, RFC 3339) or a duration
+// relative to now such as 24h, 7d or 2w, which is interpreted as "ago".
+func parseTimeArg(value string, now time.Time) (time.Time, error) {
+	return parseTime(value, now, -1)
+}
+
+// parseFutureTimeArg is parseTimeArg for deadlines: durations count
+// forward from now, so 30d means "in 30 days".
+func parseFutureTimeArg(value string, now time.Time) (time.Time, error) {
+	return parseTime(value, now, 1)
+}
+
+func parseTime(value string, now time.Time, sign int) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty time value")
+	}
+
+	// relative durations, with day and week units added on top of time.ParseDuration
+	if unit := value[len(value)-1]; unit == 'd' || unit == 'w' {
+		if n, err := strconv.Atoi(value[:len(value)-1]); err == nil {
+			if unit == 'w' {
+				n *= 7
+			}
+			return now.AddDate(0, 0, sign*n), nil
+		}
+	}
+	if dur, err := time.ParseDuration(value); err == nil {
+		return now.Add(time.Duration(sign) * dur), nil
+	}
+
+	// absolute dates
+	layouts := []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a date like 2006-01-02 or a duration like 24h or 7d", value)
+}
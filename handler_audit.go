@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+)
+
+func handlerAudit(s *state, cmd command, user database.User) error {
+	// prints audit log entries, newest first
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	actor := fs.String("user", "", "only show actions by this user")
+	since := fs.String("since", "", "only show actions after this date or duration ago (e.g. 2024-01-31, 24h, 7d)")
+	action := fs.String("action", "", "only show this action, e.g. addfeed or unfollow")
+	limit := fs.Int("limit", 50, "maximum number of entries to show")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--user name] [--since t] [--action a] [--limit n]", cmd.Name)
+	}
+	if *limit < 1 {
+		return fmt.Errorf("limit must be at least 1")
+	}
+
+	params := database.GetAuditLogParams{Limit: int32(*limit)}
+	if *actor != "" {
+		params.Actor = sql.NullString{String: *actor, Valid: true}
+	}
+	if *since != "" {
+		t, err := parseTimeArg(*since, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		params.Since = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+	if *action != "" {
+		params.Action = sql.NullString{String: *action, Valid: true}
+	}
+
+	entries, err := s.db.GetAuditLog(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		println("No audit log entries")
+		return nil
+	}
+	for _, entry := range entries {
+		line := fmt.Sprintf("%v  %v  %v %v", entry.CreatedAt.Local().Format("2006-01-02 15:04:05"), entry.ActorName, entry.Action, entry.Target)
+		if entry.Details != "" {
+			line += " (" + entry.Details + ")"
+		}
+		println(line)
+	}
+	return nil
+}
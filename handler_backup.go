@@ -0,0 +1,858 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/backup"
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func handlerBackup(s *state, cmd command, user database.User) error {
+	// writes the whole database to a JSON archive
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	out := fs.String("out", "", "file to write to instead of stdout")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--out file]", cmd.Name)
+	}
+
+	// one read-only snapshot, so posts fetched by agg mid-backup can't
+	// reference a feed the archive doesn't have
+	ctx := context.Background()
+	tx, err := s.conn.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	archive, err := dumpArchive(ctx, s.db.WithTx(tx))
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if err := writeOutput(*out, archive.Write); err != nil {
+		return err
+	}
+	target := *out
+	if target == "" {
+		target = "stdout"
+	}
+	audit(s, user, "backup", target, fmt.Sprintf("%d users, %d feeds and %d posts", len(archive.Users), len(archive.Feeds), len(archive.Posts)))
+	if *out != "" {
+		fmt.Printf("Backed up %d users, %d feeds and %d posts to %v\n", len(archive.Users), len(archive.Feeds), len(archive.Posts), *out)
+	}
+	return nil
+}
+
+func dumpArchive(ctx context.Context, q *database.Queries) (*backup.Archive, error) {
+	archive := backup.New()
+
+	users, err := q.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userNames := make(map[uuid.UUID]string, len(users))
+	for _, user := range users {
+		userNames[user.ID] = user.Name
+		archive.Users = append(archive.Users, backup.User{
+			Name:         user.Name,
+			PasswordHash: user.PasswordHash.String,
+			IsAdmin:      user.IsAdmin,
+			CreatedAt:    user.CreatedAt,
+		})
+	}
+
+	feeds, err := q.GetFeeds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, feed := range feeds {
+		archive.Feeds = append(archive.Feeds, backup.Feed{
+			URL:       feed.Url,
+			Name:      feed.Name,
+			SiteURL:   feed.SiteUrl,
+			Owner:     userNames[feed.UserID],
+			CreatedAt: feed.CreatedAt,
+		})
+	}
+
+	folders, err := q.GetAllFolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, folder := range folders {
+		archive.Folders = append(archive.Folders, backup.Folder{
+			User:      folder.UserName,
+			Name:      folder.Name,
+			CreatedAt: folder.CreatedAt,
+		})
+	}
+
+	follows, err := q.GetAllFeedFollows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, follow := range follows {
+		archive.Follows = append(archive.Follows, backup.Follow{
+			User:      follow.UserName,
+			FeedURL:   follow.FeedUrl,
+			Folder:    follow.FolderName.String,
+			Title:     follow.Title.String,
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+
+	posts, err := q.GetAllPosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		archive.Posts = append(archive.Posts, backup.Post{
+			URL:         post.Url,
+			FeedURL:     post.FeedUrl,
+			Title:       post.Title,
+			Description: post.Description,
+			Content:     post.Content,
+			Author:      post.Author,
+			Categories:  post.Categories,
+			PublishedAt: timePtr(post.PublishedAt),
+			CreatedAt:   post.CreatedAt,
+		})
+	}
+
+	states, err := q.GetAllPostStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range states {
+		archive.PostStates = append(archive.PostStates, backup.PostState{
+			User:      state.UserName,
+			PostURL:   state.PostUrl,
+			ReadAt:    timePtr(state.ReadAt),
+			StarredAt: timePtr(state.StarredAt),
+		})
+	}
+
+	tags, err := q.GetAllPostTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		archive.PostTags = append(archive.PostTags, backup.PostTag{
+			User:    tag.UserName,
+			PostURL: tag.PostUrl,
+			Tag:     tag.Tag,
+		})
+	}
+
+	searches, err := q.GetAllSavedSearches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, search := range searches {
+		archive.SavedSearches = append(archive.SavedSearches, backup.SavedSearch{
+			User:  search.UserName,
+			Name:  search.Name,
+			Query: search.Query,
+		})
+	}
+
+	filters, err := q.GetAllFilterRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, filter := range filters {
+		archive.Filters = append(archive.Filters, backup.Filter{
+			User:      filter.UserName,
+			FeedURL:   filter.FeedUrl.String,
+			Field:     filter.Field,
+			MatchType: filter.MatchType,
+			Pattern:   filter.Pattern,
+		})
+	}
+
+	rules, err := q.GetAllRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		archive.Rules = append(archive.Rules, backup.Rule{
+			User:      rule.UserName,
+			Name:      rule.Name,
+			FeedURL:   rule.FeedUrl.String,
+			Field:     rule.Field,
+			MatchType: rule.MatchType,
+			Pattern:   rule.Pattern,
+			Action:    rule.Action,
+			ActionArg: rule.ActionArg,
+		})
+	}
+
+	tokens, err := q.GetAllAPITokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		archive.APITokens = append(archive.APITokens, backup.APIToken{
+			User:       token.UserName,
+			Name:       token.Name,
+			TokenHash:  token.TokenHash,
+			Scopes:     token.Scopes,
+			ExpiresAt:  timePtr(token.ExpiresAt),
+			LastUsedAt: timePtr(token.LastUsedAt),
+			CreatedAt:  token.CreatedAt,
+		})
+	}
+
+	hooks, err := q.GetAllWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range hooks {
+		archive.Webhooks = append(archive.Webhooks, backup.Webhook{
+			User:        hook.UserName,
+			Name:        hook.Name,
+			URL:         hook.Url,
+			Secret:      hook.Secret,
+			FeedURL:     hook.FeedUrl.String,
+			Folder:      hook.FolderName.String,
+			Field:       hook.Field,
+			MatchType:   hook.MatchType,
+			Pattern:     hook.Pattern,
+			Template:    hook.Template,
+			ContentType: hook.ContentType,
+			RulesOnly:   hook.RulesOnly,
+			CreatedAt:   hook.CreatedAt,
+		})
+	}
+
+	digests, err := q.GetAllDigests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range digests {
+		archive.Digests = append(archive.Digests, backup.Digest{
+			User:       d.UserName,
+			Email:      d.Email,
+			Schedule:   d.Schedule,
+			Hour:       int(d.Hour),
+			Weekday:    int(d.Weekday),
+			Timezone:   d.Timezone,
+			LastSentAt: timePtr(d.LastSentAt),
+			CreatedAt:  d.CreatedAt,
+		})
+	}
+
+	delivered, err := q.GetAllDigestPosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, dp := range delivered {
+		archive.DigestPosts = append(archive.DigestPosts, backup.DigestPost{
+			User:        dp.UserName,
+			PostURL:     dp.PostUrl,
+			DeliveredAt: dp.DeliveredAt,
+		})
+	}
+
+	entries, err := q.GetAllAuditLog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		archive.AuditLog = append(archive.AuditLog, backup.AuditEntry{
+			Actor:     entry.ActorName,
+			Action:    entry.Action,
+			Target:    entry.Target,
+			Details:   entry.Details,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+	return archive, nil
+}
+
+func handlerRestore(s *state, cmd command, user database.User) error {
+	// merges a JSON archive into the database in a single transaction
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {file}", cmd.Name)
+	}
+
+	file, err := os.Open(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	archive, err := backup.Read(file)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	r := &restorer{
+		ctx:     ctx,
+		q:       s.db.WithTx(tx),
+		users:   make(map[string]uuid.UUID),
+		feeds:   make(map[string]uuid.UUID),
+		posts:   make(map[string]uuid.UUID),
+		folders: make(map[string]uuid.UUID),
+		added:   make(map[string]int),
+	}
+	if err := r.restore(archive); err != nil {
+		return fmt.Errorf("restore failed, nothing was changed: %v", err)
+	}
+	details := fmt.Sprintf("%d users, %d feeds and %d posts added", r.added["users"], r.added["feeds"], r.added["posts"])
+	if err := recordAudit(ctx, r.q, user, "restore", cmd.Args[0], details); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %v (backup version %d from %v)\n", cmd.Args[0], archive.Version, archive.CreatedAt.Format(time.RFC1123))
+	fmt.Printf("Users:          %d of %d added\n", r.added["users"], len(archive.Users))
+	fmt.Printf("Feeds:          %d of %d added\n", r.added["feeds"], len(archive.Feeds))
+	fmt.Printf("Folders:        %d of %d added\n", r.added["folders"], len(archive.Folders))
+	fmt.Printf("Follows:        %d of %d added\n", r.added["follows"], len(archive.Follows))
+	fmt.Printf("Posts:          %d of %d added\n", r.added["posts"], len(archive.Posts))
+	fmt.Printf("Saved searches: %d of %d added\n", r.added["saved searches"], len(archive.SavedSearches))
+	fmt.Printf("Filters:        %d of %d added\n", r.added["filters"], len(archive.Filters))
+	fmt.Printf("Rules:          %d of %d added\n", r.added["rules"], len(archive.Rules))
+	fmt.Printf("API tokens:     %d of %d added\n", r.added["api tokens"], len(archive.APITokens))
+	fmt.Printf("Webhooks:       %d of %d added\n", r.added["webhooks"], len(archive.Webhooks))
+	fmt.Printf("Digests:        %d of %d added\n", r.added["digests"], len(archive.Digests))
+	fmt.Printf("Audit entries:  %d of %d added\n", r.added["audit entries"], len(archive.AuditLog))
+	return nil
+}
+
+// restorer maps the natural keys of an archive to the ids of existing or
+// newly created rows while it is merged into the database
+type restorer struct {
+	ctx     context.Context
+	q       *database.Queries
+	users   map[string]uuid.UUID
+	feeds   map[string]uuid.UUID
+	posts   map[string]uuid.UUID
+	folders map[string]uuid.UUID
+	added   map[string]int
+}
+
+func (r *restorer) restore(a *backup.Archive) error {
+	// rows are restored in dependency order
+	steps := []func(*backup.Archive) error{
+		r.restoreUsers,
+		r.restoreFeeds,
+		r.restoreFolders,
+		r.restoreFollows,
+		r.restorePosts,
+		r.restorePostStates,
+		r.restoreSavedSearches,
+		r.restoreFilters,
+		r.restoreRules,
+		r.restoreAPITokens,
+		r.restoreWebhooks,
+		r.restoreDigests,
+		r.restoreAuditLog,
+	}
+	for _, step := range steps {
+		if err := step(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreUsers(a *backup.Archive) error {
+	for _, u := range a.Users {
+		user, err := r.q.GetUserByName(r.ctx, u.Name)
+		if errors.Is(err, sql.ErrNoRows) {
+			user, err = r.q.CreateUser(r.ctx, database.CreateUserParams{
+				ID:           uuid.New(),
+				CreatedAt:    orNow(u.CreatedAt),
+				UpdatedAt:    time.Now(),
+				Name:         u.Name,
+				PasswordHash: sql.NullString{String: u.PasswordHash, Valid: u.PasswordHash != ""},
+				IsAdmin:      u.IsAdmin,
+			})
+			r.added["users"]++
+		}
+		if err != nil {
+			return fmt.Errorf("user %v: %v", u.Name, err)
+		}
+		r.users[u.Name] = user.ID
+	}
+	return nil
+}
+
+func (r *restorer) userID(name string) (uuid.UUID, error) {
+	if id, ok := r.users[name]; ok {
+		return id, nil
+	}
+	return uuid.UUID{}, fmt.Errorf("unknown user %v", name)
+}
+
+func (r *restorer) feedID(feedURL string) (uuid.NullUUID, error) {
+	// resolves an optional feed reference
+	if feedURL == "" {
+		return uuid.NullUUID{}, nil
+	}
+	if id, ok := r.feeds[feedURL]; ok {
+		return uuid.NullUUID{UUID: id, Valid: true}, nil
+	}
+	return uuid.NullUUID{}, fmt.Errorf("unknown feed %v", feedURL)
+}
+
+func (r *restorer) restoreFeeds(a *backup.Archive) error {
+	for _, f := range a.Feeds {
+		feed, err := r.q.GetFeedByUrl(r.ctx, f.URL)
+		if errors.Is(err, sql.ErrNoRows) {
+			ownerID, err := r.userID(f.Owner)
+			if err != nil {
+				return fmt.Errorf("feed %v: %v", f.URL, err)
+			}
+			feed, err = r.q.CreateFeed(r.ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: orNow(f.CreatedAt),
+				UpdatedAt: time.Now(),
+				Name:      f.Name,
+				Url:       f.URL,
+				UserID:    ownerID,
+			})
+			if err != nil {
+				return fmt.Errorf("feed %v: %v", f.URL, err)
+			}
+			err = r.q.SetFeedSiteUrl(r.ctx, database.SetFeedSiteUrlParams{
+				ID:      feed.ID,
+				SiteUrl: f.SiteURL,
+			})
+			if err != nil {
+				return fmt.Errorf("feed %v: %v", f.URL, err)
+			}
+			r.added["feeds"]++
+		} else if err != nil {
+			return fmt.Errorf("feed %v: %v", f.URL, err)
+		}
+		r.feeds[f.URL] = feed.ID
+	}
+	return nil
+}
+
+func (r *restorer) restoreFolders(a *backup.Archive) error {
+	for _, f := range a.Folders {
+		userID, err := r.userID(f.User)
+		if err != nil {
+			return fmt.Errorf("folder %v: %v", f.Name, err)
+		}
+		folder, err := r.q.GetFolderByName(r.ctx, database.GetFolderByNameParams{
+			UserID: userID,
+			Name:   f.Name,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			folder, err = r.q.CreateFolder(r.ctx, database.CreateFolderParams{
+				ID:        uuid.New(),
+				CreatedAt: orNow(f.CreatedAt),
+				UpdatedAt: time.Now(),
+				UserID:    userID,
+				Name:      f.Name,
+			})
+			r.added["folders"]++
+		}
+		if err != nil {
+			return fmt.Errorf("folder %v: %v", f.Name, err)
+		}
+		r.folders[f.User+"\x00"+f.Name] = folder.ID
+	}
+	return nil
+}
+
+func (r *restorer) restoreFollows(a *backup.Archive) error {
+	for _, f := range a.Follows {
+		userID, err := r.userID(f.User)
+		if err != nil {
+			return fmt.Errorf("follow of %v: %v", f.FeedURL, err)
+		}
+		feedID, err := r.feedID(f.FeedURL)
+		if err != nil || !feedID.Valid {
+			return fmt.Errorf("follow of %v: unknown feed", f.FeedURL)
+		}
+
+		_, err = r.q.GetFeedFollow(r.ctx, database.GetFeedFollowParams{
+			UserID: userID,
+			FeedID: feedID.UUID,
+		})
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		params := database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: orNow(f.CreatedAt),
+			UpdatedAt: time.Now(),
+			UserID:    userID,
+			FeedID:    feedID.UUID,
+		}
+		if f.Folder != "" {
+			folderID, ok := r.folders[f.User+"\x00"+f.Folder]
+			if !ok {
+				return fmt.Errorf("follow of %v: unknown folder %v", f.FeedURL, f.Folder)
+			}
+			params.FolderID = uuid.NullUUID{UUID: folderID, Valid: true}
+		}
+		if _, err := r.q.CreateFeedFollow(r.ctx, params); err != nil {
+			return fmt.Errorf("follow of %v: %v", f.FeedURL, err)
+		}
+		if f.Title != "" {
+			_, err = r.q.SetFeedFollowTitle(r.ctx, database.SetFeedFollowTitleParams{
+				UserID: userID,
+				FeedID: feedID.UUID,
+				Title:  sql.NullString{String: f.Title, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("follow of %v: %v", f.FeedURL, err)
+			}
+		}
+		r.added["follows"]++
+	}
+	return nil
+}
+
+func (r *restorer) restorePosts(a *backup.Archive) error {
+	for _, p := range a.Posts {
+		post, err := r.q.GetPostByUrl(r.ctx, p.URL)
+		if errors.Is(err, sql.ErrNoRows) {
+			feedID, err := r.feedID(p.FeedURL)
+			if err != nil || !feedID.Valid {
+				return fmt.Errorf("post %v: unknown feed %v", p.URL, p.FeedURL)
+			}
+			post, err = r.q.CreatePost(r.ctx, database.CreatePostParams{
+				ID:          uuid.New(),
+				CreatedAt:   orNow(p.CreatedAt),
+				UpdatedAt:   time.Now(),
+				Title:       p.Title,
+				Url:         p.URL,
+				Description: p.Description,
+				PublishedAt: nullTime(p.PublishedAt),
+				FeedID:      feedID.UUID,
+				Author:      p.Author,
+				Content:     p.Content,
+				Categories:  orEmpty(p.Categories),
+			})
+			if err != nil {
+				return fmt.Errorf("post %v: %v", p.URL, err)
+			}
+			r.added["posts"]++
+		} else if err != nil {
+			return fmt.Errorf("post %v: %v", p.URL, err)
+		}
+		r.posts[p.URL] = post.ID
+	}
+	return nil
+}
+
+func (r *restorer) restorePostStates(a *backup.Archive) error {
+	for _, ps := range a.PostStates {
+		userID, err := r.userID(ps.User)
+		if err != nil {
+			return fmt.Errorf("state of %v: %v", ps.PostURL, err)
+		}
+		postID, ok := r.posts[ps.PostURL]
+		if !ok {
+			return fmt.Errorf("state of %v: unknown post", ps.PostURL)
+		}
+		err = r.q.RestorePostState(r.ctx, database.RestorePostStateParams{
+			UserID:    userID,
+			PostID:    postID,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			ReadAt:    nullTime(ps.ReadAt),
+			StarredAt: nullTime(ps.StarredAt),
+		})
+		if err != nil {
+			return fmt.Errorf("state of %v: %v", ps.PostURL, err)
+		}
+	}
+
+	for _, pt := range a.PostTags {
+		userID, err := r.userID(pt.User)
+		if err != nil {
+			return fmt.Errorf("tag of %v: %v", pt.PostURL, err)
+		}
+		postID, ok := r.posts[pt.PostURL]
+		if !ok {
+			return fmt.Errorf("tag of %v: unknown post", pt.PostURL)
+		}
+		err = r.q.TagPost(r.ctx, database.TagPostParams{
+			UserID: userID,
+			PostID: postID,
+			Tag:    pt.Tag,
+		})
+		if err != nil {
+			return fmt.Errorf("tag of %v: %v", pt.PostURL, err)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreSavedSearches(a *backup.Archive) error {
+	for _, ss := range a.SavedSearches {
+		userID, err := r.userID(ss.User)
+		if err != nil {
+			return fmt.Errorf("saved search %v: %v", ss.Name, err)
+		}
+		n, err := r.q.RestoreSavedSearch(r.ctx, database.RestoreSavedSearchParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    userID,
+			Name:      ss.Name,
+			Query:     ss.Query,
+		})
+		if err != nil {
+			return fmt.Errorf("saved search %v: %v", ss.Name, err)
+		}
+		r.added["saved searches"] += int(n)
+	}
+	return nil
+}
+
+func (r *restorer) restoreFilters(a *backup.Archive) error {
+	for _, f := range a.Filters {
+		userID, err := r.userID(f.User)
+		if err != nil {
+			return fmt.Errorf("filter %q: %v", f.Pattern, err)
+		}
+		feedID, err := r.feedID(f.FeedURL)
+		if err != nil {
+			return fmt.Errorf("filter %q: %v", f.Pattern, err)
+		}
+		n, err := r.q.RestoreFilterRule(r.ctx, database.RestoreFilterRuleParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    userID,
+			FeedID:    feedID,
+			Field:     f.Field,
+			MatchType: f.MatchType,
+			Pattern:   f.Pattern,
+		})
+		if err != nil {
+			return fmt.Errorf("filter %q: %v", f.Pattern, err)
+		}
+		r.added["filters"] += int(n)
+	}
+	return nil
+}
+
+func (r *restorer) restoreRules(a *backup.Archive) error {
+	for _, rule := range a.Rules {
+		userID, err := r.userID(rule.User)
+		if err != nil {
+			return fmt.Errorf("rule %v: %v", rule.Name, err)
+		}
+		feedID, err := r.feedID(rule.FeedURL)
+		if err != nil {
+			return fmt.Errorf("rule %v: %v", rule.Name, err)
+		}
+		n, err := r.q.RestoreRule(r.ctx, database.RestoreRuleParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    userID,
+			Name:      rule.Name,
+			FeedID:    feedID,
+			Field:     rule.Field,
+			MatchType: rule.MatchType,
+			Pattern:   rule.Pattern,
+			Action:    rule.Action,
+			ActionArg: rule.ActionArg,
+		})
+		if err != nil {
+			return fmt.Errorf("rule %v: %v", rule.Name, err)
+		}
+		r.added["rules"] += int(n)
+	}
+	return nil
+}
+
+func (r *restorer) restoreAPITokens(a *backup.Archive) error {
+	for _, token := range a.APITokens {
+		userID, err := r.userID(token.User)
+		if err != nil {
+			return fmt.Errorf("api token %v: %v", token.Name, err)
+		}
+		n, err := r.q.RestoreAPIToken(r.ctx, database.RestoreAPITokenParams{
+			ID:         uuid.New(),
+			CreatedAt:  orNow(token.CreatedAt),
+			UserID:     userID,
+			Name:       token.Name,
+			TokenHash:  token.TokenHash,
+			Scopes:     orEmpty(token.Scopes),
+			ExpiresAt:  nullTime(token.ExpiresAt),
+			LastUsedAt: nullTime(token.LastUsedAt),
+		})
+		if err != nil {
+			return fmt.Errorf("api token %v: %v", token.Name, err)
+		}
+		r.added["api tokens"] += int(n)
+	}
+	return nil
+}
+
+func (r *restorer) restoreWebhooks(a *backup.Archive) error {
+	for _, hook := range a.Webhooks {
+		userID, err := r.userID(hook.User)
+		if err != nil {
+			return fmt.Errorf("webhook %v: %v", hook.Name, err)
+		}
+		feedID, err := r.feedID(hook.FeedURL)
+		if err != nil {
+			return fmt.Errorf("webhook %v: %v", hook.Name, err)
+		}
+		var folderID uuid.NullUUID
+		if hook.Folder != "" {
+			id, ok := r.folders[hook.User+"\x00"+hook.Folder]
+			if !ok {
+				return fmt.Errorf("webhook %v: unknown folder %v", hook.Name, hook.Folder)
+			}
+			folderID = uuid.NullUUID{UUID: id, Valid: true}
+		}
+		n, err := r.q.RestoreWebhook(r.ctx, database.RestoreWebhookParams{
+			ID:          uuid.New(),
+			CreatedAt:   orNow(hook.CreatedAt),
+			UpdatedAt:   time.Now(),
+			UserID:      userID,
+			Name:        hook.Name,
+			Url:         hook.URL,
+			Secret:      hook.Secret,
+			FeedID:      feedID,
+			FolderID:    folderID,
+			Field:       hook.Field,
+			MatchType:   hook.MatchType,
+			Pattern:     hook.Pattern,
+			Template:    hook.Template,
+			ContentType: hook.ContentType,
+			RulesOnly:   hook.RulesOnly,
+		})
+		if err != nil {
+			return fmt.Errorf("webhook %v: %v", hook.Name, err)
+		}
+		r.added["webhooks"] += int(n)
+	}
+	return nil
+}
+
+func (r *restorer) restoreDigests(a *backup.Archive) error {
+	// digests are rescheduled from now rather than restored with the next
+	// send time they had, which may long have passed
+	for _, d := range a.Digests {
+		userID, err := r.userID(d.User)
+		if err != nil {
+			return fmt.Errorf("digest of %v: %v", d.User, err)
+		}
+		loc, err := time.LoadLocation(d.Timezone)
+		if err != nil {
+			return fmt.Errorf("digest of %v: %v", d.User, err)
+		}
+		n, err := r.q.RestoreDigest(r.ctx, database.RestoreDigestParams{
+			UserID:     userID,
+			CreatedAt:  orNow(d.CreatedAt),
+			UpdatedAt:  time.Now(),
+			Email:      d.Email,
+			Schedule:   d.Schedule,
+			Hour:       int32(d.Hour),
+			Weekday:    int32(d.Weekday),
+			Timezone:   loc.String(),
+			NextSendAt: nextDigestTime(d.Schedule, d.Hour, time.Weekday(d.Weekday), loc, time.Now()),
+			LastSentAt: nullTime(d.LastSentAt),
+		})
+		if err != nil {
+			return fmt.Errorf("digest of %v: %v", d.User, err)
+		}
+		r.added["digests"] += int(n)
+	}
+
+	for _, dp := range a.DigestPosts {
+		userID, err := r.userID(dp.User)
+		if err != nil {
+			return fmt.Errorf("digest post %v: %v", dp.PostURL, err)
+		}
+		postID, ok := r.posts[dp.PostURL]
+		if !ok {
+			return fmt.Errorf("digest post %v: unknown post", dp.PostURL)
+		}
+		err = r.q.RestoreDigestPost(r.ctx, database.RestoreDigestPostParams{
+			UserID:      userID,
+			PostID:      postID,
+			DeliveredAt: orNow(dp.DeliveredAt),
+		})
+		if err != nil {
+			return fmt.Errorf("digest post %v: %v", dp.PostURL, err)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) restoreAuditLog(a *backup.Archive) error {
+	for _, entry := range a.AuditLog {
+		// entries by users who have since been deleted keep just their name
+		var actorID uuid.NullUUID
+		if id, ok := r.users[entry.Actor]; ok {
+			actorID = uuid.NullUUID{UUID: id, Valid: true}
+		}
+		n, err := r.q.RestoreAuditLogEntry(r.ctx, database.RestoreAuditLogEntryParams{
+			ID:        uuid.New(),
+			CreatedAt: orNow(entry.CreatedAt),
+			ActorID:   actorID,
+			ActorName: entry.Actor,
+			Action:    entry.Action,
+			Target:    entry.Target,
+			Details:   entry.Details,
+		})
+		if err != nil {
+			return fmt.Errorf("audit entry %v by %v: %v", entry.Action, entry.Actor, err)
+		}
+		r.added["audit entries"] += int(n)
+	}
+	return nil
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func orNow(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now().UTC()
+	}
+	return t.UTC()
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/backup"
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	src := testState(t)
+	ctx := context.Background()
+
+	alice := testUser(t, src, "alice")
+	bob := testUser(t, src, "bob")
+	err := src.db.SetUserPassword(ctx, database.SetUserPasswordParams{
+		ID:           alice.ID,
+		PasswordHash: sql.NullString{String: "$2a$10$hash", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	goBlog := testFeed(t, src, alice, "Go Blog", "https://go.dev/blog/feed.atom")
+	hn := testFeed(t, src, bob, "Hacker News", "https://news.ycombinator.com/rss")
+	first := testPost(t, src, goBlog, "Go 1.23 is released", "https://go.dev/blog/go1.23")
+	second := testPost(t, src, hn, "Show HN: gator", "https://news.ycombinator.com/item?id=1")
+
+	folder, err := src.db.CreateFolder(ctx, database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    alice.ID,
+		Name:      "tech",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.db.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{
+		UserID:   alice.ID,
+		FeedID:   goBlog.ID,
+		FolderID: uuid.NullUUID{UUID: folder.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.db.SetFeedFollowTitle(ctx, database.SetFeedFollowTitleParams{
+		UserID: alice.ID,
+		FeedID: goBlog.ID,
+		Title:  sql.NullString{String: "Go", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := src.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: alice.ID, PostID: first.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.db.StarPost(ctx, database.StarPostParams{UserID: bob.ID, PostID: second.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.db.TagPost(ctx, database.TagPostParams{UserID: alice.ID, PostID: first.ID, Tag: "go"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.db.CreateSavedSearch(ctx, database.CreateSavedSearchParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    alice.ID,
+		Name:      "releases",
+		Query:     "released",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.db.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    bob.ID,
+		FeedID:    uuid.NullUUID{UUID: hn.ID, Valid: true},
+		Field:     "title",
+		MatchType: "regex",
+		Pattern:   "^ask hn",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.db.CreateRule(ctx, database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    alice.ID,
+		Name:      "star-releases",
+		Field:     "title",
+		MatchType: "substring",
+		Pattern:   "released",
+		Action:    "star",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.db.CreateAPIToken(ctx, database.CreateAPITokenParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    bob.ID,
+		Name:      "reader",
+		TokenHash: hashToken("gator_secret"),
+		Scopes:    []string{scopeRead, scopeGReader},
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(24 * time.Hour), Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.db.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		UserID:      alice.ID,
+		Name:        "chat",
+		Url:         "https://chat.example.com/hooks/1",
+		Secret:      "s3cret",
+		FolderID:    uuid.NullUUID{UUID: folder.ID, Valid: true},
+		Field:       "title",
+		MatchType:   "substring",
+		Pattern:     "release",
+		Template:    `{"text": {{json .Post.Title}}}`,
+		ContentType: "application/json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = src.db.UpsertDigest(ctx, database.UpsertDigestParams{
+		UserID:     alice.ID,
+		Email:      "alice@example.com",
+		Schedule:   "weekly",
+		Hour:       7,
+		Weekday:    int32(time.Friday),
+		Timezone:   "Europe/Berlin",
+		NextSendAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = src.db.MarkPostsDelivered(ctx, database.MarkPostsDeliveredParams{
+		UserID:  alice.ID,
+		PostIds: []uuid.UUID{first.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit(src, alice, "addfeed", goBlog.Url, goBlog.Name)
+
+	want, err := dumpArchive(ctx, src.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "backup.json")
+	if err := writeOutput(file, want.Write); err != nil {
+		t.Fatal(err)
+	}
+
+	// restoring into an empty database recreates everything, and restoring
+	// the same archive again adds nothing
+	dst := testState(t)
+	for i := 0; i < 2; i++ {
+		if err := handlerRestore(dst, command{Name: "restore", Args: []string{file}}, database.User{}); err != nil {
+			t.Fatalf("restore %d: %v", i+1, err)
+		}
+		got, err := dumpArchive(ctx, dst.db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, b := archiveJSON(t, want), archiveJSON(t, got); a != b {
+			t.Fatalf("restore %d: archives differ\nwant %v\ngot  %v", i+1, a, b)
+		}
+	}
+	// each restore is in the audit log, by nobody since dst had no users
+	entries, err := dst.db.GetAuditLog(ctx, database.GetAuditLogParams{Action: sql.NullString{String: "restore", Valid: true}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Target != file || entries[0].ActorID.Valid {
+		t.Fatalf("restore audit entries = %+v, want 2 for %v without an actor", entries, file)
+	}
+}
+
+func TestRestoreRejectsUnknownFeed(t *testing.T) {
+	s := testState(t)
+	archive := backup.New()
+	archive.Users = []backup.User{{Name: "alice"}}
+	archive.Posts = []backup.Post{{URL: "https://example.com/post", FeedURL: "https://example.com/feed"}}
+	file := filepath.Join(t.TempDir(), "backup.json")
+	if err := writeOutput(file, archive.Write); err != nil {
+		t.Fatal(err)
+	}
+
+	err := handlerRestore(s, command{Name: "restore", Args: []string{file}}, database.User{})
+	if err == nil || !strings.Contains(err.Error(), "unknown feed") {
+		t.Fatalf("restore = %v, want an unknown feed error", err)
+	}
+	// the transaction is rolled back, so alice wasn't added either
+	if _, err := s.db.GetUserByName(context.Background(), "alice"); err != sql.ErrNoRows {
+		t.Fatalf("GetUserByName = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func archiveJSON(t *testing.T, a *backup.Archive) string {
+	// encodes an archive for comparison, ignoring when it was made, the
+	// order of tables that are dumped unsorted and the entries restores add
+	// to the audit log
+	t.Helper()
+	c := *a
+	c.CreatedAt = time.Time{}
+	c.AuditLog = slices.DeleteFunc(slices.Clone(a.AuditLog), func(e backup.AuditEntry) bool { return e.Action == "restore" })
+	c.Users = slices.Clone(a.Users)
+	slices.SortFunc(c.Users, func(x, y backup.User) int { return strings.Compare(x.Name, y.Name) })
+	c.Feeds = slices.Clone(a.Feeds)
+	slices.SortFunc(c.Feeds, func(x, y backup.Feed) int { return strings.Compare(x.URL, y.URL) })
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/cryptidcodes/gator/internal/digest"
+)
+
+func handlerDigest(s *state, cmd command, user database.User) error {
+	// dispatches the digest subcommands
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: %v set|show|off|send", cmd.Name)
+	}
+	sub := command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+	switch cmd.Args[0] {
+	case "set":
+		return digestSet(s, sub, user)
+	case "show":
+		return digestShow(s, sub, user)
+	case "off":
+		return digestOff(s, sub, user)
+	case "send":
+		return digestSend(s, sub, user)
+	}
+	return fmt.Errorf("unknown subcommand %q, usage: %v set|show|off|send", cmd.Args[0], cmd.Name)
+}
+
+func digestSet(s *state, cmd command, user database.User) error {
+	// schedules the user's digest, replacing any earlier schedule
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	schedule := fs.String("schedule", "daily", "how often to send the digest: daily or weekly")
+	hour := fs.Int("hour", 8, "hour of the day to send the digest at, 0 to 23")
+	day := fs.String("day", "monday", "day of the week to send a weekly digest on")
+	tz := fs.String("tz", "UTC", "timezone of --hour, e.g. Europe/Berlin")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v {email} [--schedule daily|weekly] [--hour h] [--day weekday] [--tz timezone]", cmd.Name)
+	}
+	addr, err := mail.ParseAddress(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid email address %q: %v", args[0], err)
+	}
+	if *schedule != "daily" && *schedule != "weekly" {
+		return fmt.Errorf("unknown schedule %q: use daily or weekly", *schedule)
+	}
+	if *hour < 0 || *hour > 23 {
+		return fmt.Errorf("hour must be between 0 and 23")
+	}
+	weekday, ok := weekdays[strings.ToLower(*day)]
+	if !ok {
+		return fmt.Errorf("unknown day %q: use monday through sunday", *day)
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fmt.Errorf("unknown timezone %q: %v", *tz, err)
+	}
+
+	d, err := s.db.UpsertDigest(context.Background(), database.UpsertDigestParams{
+		UserID:     user.ID,
+		Email:      addr.Address,
+		Schedule:   *schedule,
+		Hour:       int32(*hour),
+		Weekday:    int32(weekday),
+		Timezone:   loc.String(),
+		NextSendAt: nextDigestTime(*schedule, *hour, weekday, loc, time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+	audit(s, user, "digest set", d.Email, d.Schedule)
+	fmt.Printf("Digest scheduled: %v\n", describeDigest(d))
+	if s.cfg.SMTP == nil {
+		println("Digests are only sent once an smtp server is added to the config file")
+	}
+	return nil
+}
+
+func digestShow(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	d, err := getDigest(s, user)
+	if err != nil {
+		return err
+	}
+	println(describeDigest(d))
+	if d.LastSentAt.Valid {
+		fmt.Printf("Last sent %v\n", d.LastSentAt.Time.Local().Format("2006-01-02 15:04"))
+	}
+	if d.LastError != "" {
+		fmt.Printf("Last attempt failed: %v\n", d.LastError)
+	}
+	return nil
+}
+
+func digestOff(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	n, err := s.db.DeleteDigest(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no digest scheduled")
+	}
+	audit(s, user, "digest off", "", "")
+	println("Digest turned off")
+	return nil
+}
+
+func digestSend(s *state, cmd command, user database.User) error {
+	// sends the digest right away without changing its schedule, or with
+	// --dry-run prints it without sending or marking anything
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "print the digest instead of sending it")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--dry-run]", cmd.Name)
+	}
+
+	d, err := getDigest(s, user)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		content, _, err := buildDigest(s, d, loc)
+		if err != nil {
+			return err
+		}
+		if content.Count() == 0 {
+			println("No new unread posts")
+			return nil
+		}
+		print(digest.Text(content))
+		return nil
+	}
+
+	n, err := sendDigest(s, d, loc)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		println("No new unread posts, nothing sent")
+		return nil
+	}
+	fmt.Printf("Sent %d posts to %v\n", n, d.Email)
+	return nil
+}
+
+func getDigest(s *state, user database.User) (database.Digest, error) {
+	d, err := s.db.GetDigest(context.Background(), user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Digest{}, fmt.Errorf("no digest scheduled, add one with: digest set {email}")
+	}
+	return d, err
+}
+
+func describeDigest(d database.Digest) string {
+	// e.g. "weekly, Mondays at 08:00 Europe/Berlin to me@example.com, next on ..."
+	when := fmt.Sprintf("daily at %02d:00 %v", d.Hour, d.Timezone)
+	if d.Schedule == "weekly" {
+		when = fmt.Sprintf("weekly, %vs at %02d:00 %v", time.Weekday(d.Weekday), d.Hour, d.Timezone)
+	}
+	next := d.NextSendAt.Local().Format("Mon 2006-01-02 15:04")
+	if loc, err := time.LoadLocation(d.Timezone); err == nil {
+		next = d.NextSendAt.In(loc).Format("Mon 2006-01-02 15:04")
+	}
+	return fmt.Sprintf("%v to %v, next on %v", when, d.Email, next)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/cryptidcodes/gator/internal/opml"
+	"github.com/cryptidcodes/gator/internal/postexport"
+	"github.com/cryptidcodes/gator/internal/site"
+	"github.com/google/uuid"
+)
+
+func handlerExport(s *state, cmd command, user database.User) error {
+	// dispatches the export formats
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: %v opml|posts", cmd.Name)
+	}
+	sub := command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+	switch cmd.Args[0] {
+	case "opml":
+		return exportOPML(s, sub, user)
+	case "posts":
+		return exportPosts(s, sub, user)
+	}
+	return fmt.Errorf("unknown format %q, usage: %v opml|posts", cmd.Args[0], cmd.Name)
+}
+
+func exportOPML(s *state, cmd command, user database.User) error {
+	// writes the feeds a user follows as an OPML 2.0 document
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	userName := fs.String("user", "", "export another user's subscriptions")
+	folder := fs.String("folder", "", "only export the feeds in this folder and its subfolders")
+	out := fs.String("out", "", "file to write to instead of stdout")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--user name] [--folder name] [--out file]", cmd.Name)
+	}
+
+	if *userName != "" {
+		user, err = lookupUserAs(s, cmd, user, *userName)
+		if err != nil {
+			return err
+		}
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), database.GetFeedFollowsForUserParams{
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	entries := make([]opml.Entry, 0, len(follows))
+	for _, follow := range follows {
+		if *folder != "" && !inFolder(follow.FolderName.String, *folder) {
+			continue
+		}
+		entries = append(entries, opml.Entry{
+			Title:   follow.FeedName,
+			XMLURL:  follow.FeedUrl,
+			HTMLURL: follow.FeedSiteUrl,
+			Folder:  follow.FolderName.String,
+		})
+	}
+
+	doc := opml.Build(fmt.Sprintf("%v's gator subscriptions", user.Name), entries)
+	return writeOutput(*out, doc.Write)
+}
+
+func exportPosts(s *state, cmd command, user database.User) error {
+	// writes posts matching the browse filters as markdown, html, json or csv
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	format := fs.String("format", "md", "output format: "+strings.Join(postexport.Formats, ", "))
+	userName := fs.String("user", "", "export another user's posts")
+	out := fs.String("out", "", "file to write a combined document to instead of stdout")
+	dir := fs.String("dir", "", "directory to write one file per post to")
+	var filters postFilters
+	filters.register(fs, 100)
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--format md|html|json|csv] [--out file | --dir dir] [--user name] [browse flags]", cmd.Name)
+	}
+	if *out != "" && *dir != "" {
+		return fmt.Errorf("--out and --dir can't be combined")
+	}
+	if !slices.Contains(postexport.Formats, *format) {
+		return fmt.Errorf("unknown format %q, expected one of %v", *format, strings.Join(postexport.Formats, ", "))
+	}
+	if *dir != "" && *format == "csv" {
+		return fmt.Errorf("csv can only be exported as a single combined file")
+	}
+
+	if *userName != "" {
+		user, err = lookupUserAs(s, cmd, user, *userName)
+		if err != nil {
+			return err
+		}
+	}
+	params, err := filters.params(user)
+	if err != nil {
+		return err
+	}
+	posts, err := loadExportPosts(s, user, params)
+	if err != nil {
+		return err
+	}
+
+	if *dir == "" {
+		return writeOutput(*out, func(w io.Writer) error {
+			return postexport.Write(w, *format, postexport.Document{
+				Title:    fmt.Sprintf("%v's gator posts", user.Name),
+				Exported: time.Now(),
+				Posts:    posts,
+			})
+		})
+	}
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, post := range posts {
+		// name files by date and title so they sort chronologically
+		base := post.Published.UTC().Format("2006-01-02") + "-" + site.Slug(post.Title)
+		name := base + "." + *format
+		for i := 2; names[name]; i++ {
+			name = fmt.Sprintf("%v-%d.%v", base, i, *format)
+		}
+		names[name] = true
+
+		err := writeOutput(filepath.Join(*dir, name), func(w io.Writer) error {
+			return postexport.WritePost(w, *format, post)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Exported %d posts to %v\n", len(posts), *dir)
+	return nil
+}
+
+func loadExportPosts(s *state, user database.User, params database.GetPostsForUserParams) ([]postexport.Post, error) {
+	// loads posts with the user's tags attached
+	rows, err := s.db.GetPostsForUser(context.Background(), params)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	tagRows, err := s.db.GetTagsForPosts(context.Background(), database.GetTagsForPostsParams{
+		UserID:  user.ID,
+		PostIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[uuid.UUID][]string)
+	for _, row := range tagRows {
+		tags[row.PostID] = append(tags[row.PostID], row.Tag)
+	}
+
+	posts := make([]postexport.Post, 0, len(rows))
+	for _, row := range rows {
+		content := row.Content
+		if content == "" {
+			content = row.Description
+		}
+		posts = append(posts, postexport.Post{
+			ID:         row.ID.String(),
+			Title:      row.Title,
+			URL:        row.Url,
+			Feed:       row.Name,
+			FeedURL:    row.FeedUrl,
+			Author:     row.Author,
+			Published:  publishedOrCreated(row.PublishedAt, row.CreatedAt),
+			Categories: orEmpty(row.Categories),
+			Tags:       orEmpty(tags[row.ID]),
+			Read:       row.ReadAt.Valid,
+			Starred:    row.StarredAt.Valid,
+			Content:    content,
+		})
+	}
+	return posts, nil
+}
+
+func inFolder(name, folder string) bool {
+	// reports whether name is folder or one of its slash separated subfolders
+	return name == folder || strings.HasPrefix(name, folder+"/")
+}
+
+func lookupUser(s *state, name string) (database.User, error) {
+	user, err := s.db.GetUserByName(context.Background(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, notFound("no user named %v", name)
+	}
+	return user, err
+}
+
+func lookupUserAs(s *state, cmd command, user database.User, name string) (database.User, error) {
+	// looks up the user named by a --user flag, which only admins may point
+	// at someone other than themselves
+	if name == user.Name {
+		return user, nil
+	}
+	if err := checkAdmin(cmd, user); err != nil {
+		return database.User{}, err
+	}
+	return lookupUser(s, name)
+}
+
+func writeOutput(path string, write func(io.Writer) error) error {
+	// runs write against the file at path, or stdout if path is empty. The
+	// file is only readable by its owner, since backups hold password and
+	// token hashes.
+	if path == "" {
+		return write(os.Stdout)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestLookupUserAsNeedsAdmin(t *testing.T) {
+	// a user may always name themselves, but naming someone else is refused
+	// before the database is consulted unless they're an admin
+	alice := database.User{ID: uuid.New(), Name: "alice"}
+	cmd := command{Name: "export opml"}
+
+	got, err := lookupUserAs(nil, cmd, alice, "alice")
+	if err != nil || got.ID != alice.ID {
+		t.Fatalf("lookupUserAs(alice) = %v, %v, want alice", got.Name, err)
+	}
+	_, err = lookupUserAs(nil, cmd, alice, "bob")
+	if err == nil || err.Error() != "export opml can only be run by an admin" {
+		t.Fatalf("lookupUserAs(bob) = %v, want the admin error", err)
+	}
+	// an admin without a password doesn't count, since anyone can log in as them
+	root := database.User{ID: uuid.New(), Name: "root", IsAdmin: true}
+	_, err = lookupUserAs(nil, cmd, root, "bob")
+	if err == nil || !strings.Contains(err.Error(), "admin with a password") {
+		t.Fatalf("lookupUserAs(bob) as an admin without a password = %v, want the password error", err)
+	}
+}
+
+func TestWriteOutputIsPrivate(t *testing.T) {
+	// an existing file is truncated, and a new one is only readable by its
+	// owner since backups hold password and token hashes
+	path := filepath.Join(t.TempDir(), "backup.json")
+	if err := os.WriteFile(path, []byte("an older, longer backup"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := writeOutput(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "{}")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "{}" {
+		t.Fatalf("ReadFile = %q, %v, want the file truncated", data, err)
+	}
+
+	path = filepath.Join(t.TempDir(), "new.json")
+	if err := writeOutput(path, func(io.Writer) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		t.Fatalf("mode = %v, want no group or other access", perm)
+	}
+}
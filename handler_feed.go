@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
 	"html"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cryptidcodes/gator/internal/database"
@@ -17,18 +21,30 @@ import (
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title string `xml:"title"`
+		// atom:link has to come before link, which would match it too
+		AtomLinks   []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Item        []RSSItem  `xml:"item"`
 	} `xml:"channel"`
 }
 
+// AtomLink is an <atom:link>, used by feeds to name their hub and canonical url
+type AtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Categories  []string `xml:"category"`
 }
 
 func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
@@ -47,8 +63,13 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	if err != nil {
 		return &RSSFeed{}, fmt.Errorf("error: %v", err)
 	}
+	return parseFeed(data)
+}
+
+func parseFeed(data []byte) (*RSSFeed, error) {
+	// decodes an RSS document, whether fetched or pushed by a WebSub hub
 	var result RSSFeed
-	err = xml.Unmarshal(data, &result)
+	err := xml.Unmarshal(data, &result)
 	if err != nil {
 		return &RSSFeed{}, fmt.Errorf("error: %v", err)
 	}
@@ -57,11 +78,78 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	for i := 0; i < len(result.Channel.Item); i++ {
 		result.Channel.Item[i].Title = html.UnescapeString(result.Channel.Item[i].Title)
 		result.Channel.Item[i].Description = html.UnescapeString(result.Channel.Item[i].Description)
+		// prefer dc:creator since the RSS author element is meant to be an email address
+		if result.Channel.Item[i].Creator != "" {
+			result.Channel.Item[i].Author = result.Channel.Item[i].Creator
+		}
+		result.Channel.Item[i].Author = html.UnescapeString(result.Channel.Item[i].Author)
 	}
 
 	return &result, nil
 }
 
+func (f *RSSFeed) hub() (hubURL, selfURL string) {
+	// returns the WebSub hub the feed advertises and the url to subscribe with
+	for _, link := range f.Channel.AtomLinks {
+		switch strings.ToLower(link.Rel) {
+		case "hub":
+			if hubURL == "" {
+				hubURL = strings.TrimSpace(link.Href)
+			}
+		case "self":
+			selfURL = strings.TrimSpace(link.Href)
+		}
+	}
+	return hubURL, selfURL
+}
+
+// publishedLayouts lists the date formats seen in the wild for pubDate
+var publishedLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parsePublished(value string) sql.NullTime {
+	// parses an item's pubDate, returning an invalid NullTime if no layout matches
+	value = strings.TrimSpace(value)
+	for _, layout := range publishedLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return sql.NullTime{Time: t.UTC(), Valid: true}
+		}
+	}
+	return sql.NullTime{}
+}
+
+func orEmpty(s []string) []string {
+	// the categories column is NOT NULL, and pq encodes a nil slice as NULL
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func formatPublished(t sql.NullTime) string {
+	if !t.Valid {
+		return "unknown date"
+	}
+	return t.Time.Format("Mon, 02 Jan 2006 15:04 MST")
+}
+
+// publishedOrCreated falls back to when gator first saw a post that has no date
+func publishedOrCreated(published sql.NullTime, created time.Time) time.Time {
+	if published.Valid {
+		return published.Time
+	}
+	return created
+}
+
 func handlerAddFeed(s *state, cmd command, user database.User) error {
 	if len(cmd.Args) != 2 {
 		log.Fatal("syntax: addfeed requires 2 args")
@@ -102,9 +190,68 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	}
 	s.db.CreateFeedFollow(context.Background(), params)
 
+	audit(s, user, "addfeed", feed.Url, feed.Name)
 	return nil
 }
 
+func lookupFeed(s *state, user database.User, ref string) (database.Feed, error) {
+	// finds a feed by its url, or failing that by the title user gave it or its name
+	feed, err := s.db.GetFeedByNameOrUrl(context.Background(), database.GetFeedByNameOrUrlParams{
+		UserID: user.ID,
+		Ref:    ref,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, notFound("no feed found with name or url %v", ref)
+	}
+	return feed, err
+}
+
+func subscribe(s *state, user database.User, feedURL, name, source string) (database.Feed, error) {
+	// follows the feed at feedURL, adding it first if nobody has yet. Following
+	// a feed twice is not an error. Callers validate feedURL, and source is
+	// recorded in the audit log.
+	feed, err := s.db.GetFeedByUrl(context.Background(), feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		if name == "" {
+			name = feedURL
+		}
+		feed, err = s.db.CreateFeed(context.Background(), database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      name,
+			Url:       feedURL,
+			UserID:    user.ID,
+		})
+		if err != nil {
+			return database.Feed{}, err
+		}
+		audit(s, user, "addfeed", feed.Url, feed.Name+" ("+source+")")
+	} else if err != nil {
+		return database.Feed{}, err
+	}
+
+	_, err = s.db.GetFeedFollow(context.Background(), database.GetFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if err == nil {
+		return feed, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, err
+	}
+	_, err = s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		FeedID:    feed.ID,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+	audit(s, user, "follow", feed.Url, source)
+	return feed, nil
+}
+
 func handlerFeeds(s *state, cmd command) error {
 	if len(cmd.Args) != 0 {
 		log.Fatal("syntax: feeds does not accept args")
@@ -126,11 +273,17 @@ func handlerFeeds(s *state, cmd command) error {
 }
 
 func handlerFollow(s *state, cmd command, user database.User) error {
-	if len(cmd.Args) != 1 {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	folder := fs.String("folder", "", "folder to put the feed in")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
 		log.Fatal("syntax: follow requires 1 arg (url)")
 	}
 
-	feed, err := s.db.GetFeedByUrl(context.Background(), cmd.Args[0])
+	feed, err := s.db.GetFeedByUrl(context.Background(), args[0])
 	if err != nil {
 		return err
 	}
@@ -141,10 +294,18 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 		FeedID:    feed.ID,
 		UserID:    user.ID,
 	}
+	if *folder != "" {
+		dbFolder, err := lookupFolder(s, user, *folder)
+		if err != nil {
+			return err
+		}
+		params.FolderID = uuid.NullUUID{UUID: dbFolder.ID, Valid: true}
+	}
 	row, err := s.db.CreateFeedFollow(context.Background(), params)
 	if err != nil {
 		return err
 	}
+	audit(s, user, "follow", feed.Url, "")
 
 	fmt.Printf("%v followed: %v\n", row.UserName, row.FeedName)
 	return nil
@@ -173,15 +334,81 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 	if err != nil {
 		return err
 	}
+	audit(s, user, "unfollow", feed.Url, "")
+	return nil
+}
+
+func handlerRetitle(s *state, cmd command, user database.User) error {
+	// sets the title the current user sees for a followed feed
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	reset := fs.Bool("reset", false, "go back to the feed's own name")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if (len(args) != 2 || *reset) && (len(args) != 1 || !*reset) {
+		return fmt.Errorf("usage: %v {feed name|url} {title} | %v {feed name|url} --reset", cmd.Name, cmd.Name)
+	}
+
+	feed, err := lookupFeed(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	title := sql.NullString{}
+	if !*reset {
+		title = sql.NullString{String: args[1], Valid: true}
+	}
+	n, err := s.db.SetFeedFollowTitle(context.Background(), database.SetFeedFollowTitleParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+		Title:  title,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("you aren't following %v", feed.Name)
+	}
+	if *reset {
+		fmt.Printf("%v is shown with its own name again\n", feed.Name)
+		return nil
+	}
+	fmt.Printf("%v is now shown as %v\n", feed.Name, title.String)
 	return nil
 }
 
 func handlerFollowing(s *state, cmd command, user database.User) error {
-	rows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	folder := fs.String("folder", "", "only list the feeds in this folder")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--folder name]", cmd.Name)
+	}
+
+	params := database.GetFeedFollowsForUserParams{
+		UserID: user.ID,
+	}
+	if *folder != "" {
+		params.Folder = sql.NullString{String: *folder, Valid: true}
+	}
+	rows, err := s.db.GetFeedFollowsForUser(context.Background(), params)
 	if err != nil {
 		return err
 	}
+
+	// rows are sorted by folder, so print a heading whenever it changes
 	for i := 0; i < len(rows); i++ {
+		if rows[i].FolderName.Valid && (i == 0 || rows[i].FolderName != rows[i-1].FolderName) {
+			println(rows[i].FolderName.String + "/")
+		}
+		if rows[i].FolderName.Valid {
+			println("  " + rows[i].FeedName)
+			continue
+		}
 		println(rows[i].FeedName)
 	}
 	return nil
@@ -196,6 +423,10 @@ func handlerAgg(s *state, cmd command) error {
 	if err != nil {
 		return err
 	}
+	go webhookLoop(s)
+	if s.cfg.SMTP != nil {
+		go digestLoop(s)
+	}
 	ticker := time.NewTicker(dur)
 	for ; ; <-ticker.C {
 		println("Aggin...")
@@ -217,67 +448,109 @@ func scrapeFeeds(s *state) {
 
 		// fetch current state of feed
 		fmt.Printf("Fetching from %v\n", dbFeed.Name)
-		rssFeed, err := fetchFeed(context.Background(), dbFeed.Url)
-		if err != nil {
-			log.Println(err)
+		rssFeed, fetchErr := fetchFeed(context.Background(), dbFeed.Url)
+		if fetchErr != nil {
+			log.Println(fetchErr)
 		}
 
 		// mark the feed as fetched
 		s.db.MarkFeedFetched(context.Background(), dbFeed.ID)
 
-		// create posts table entries for any posts that dont have entries already
-		for i := 0; i < len(rssFeed.Channel.Item); i++ {
-			_, err := s.db.GetPostByUrl(context.Background(), rssFeed.Channel.Item[i].Link)
+		// remember the feed's website for exports
+		if rssFeed.Channel.Link != "" {
+			err = s.db.SetFeedSiteUrl(context.Background(), database.SetFeedSiteUrlParams{
+				ID:      dbFeed.ID,
+				SiteUrl: rssFeed.Channel.Link,
+			})
+			if err != nil {
+				log.Println(err)
+			}
+		}
+
+		// remember the feed's hub so serve can subscribe to it
+		hubURL, selfURL := rssFeed.hub()
+		if fetchErr == nil && (hubURL != dbFeed.HubUrl || selfURL != dbFeed.SelfUrl) {
+			err = s.db.SetFeedHub(context.Background(), database.SetFeedHubParams{
+				ID:      dbFeed.ID,
+				HubUrl:  hubURL,
+				SelfUrl: selfURL,
+			})
 			if err != nil {
-				params := database.CreatePostParams{
-					ID:          uuid.New(),
-					CreatedAt:   time.Now(),
-					UpdatedAt:   time.Now(),
-					Title:       rssFeed.Channel.Item[i].Title,
-					Url:         rssFeed.Channel.Item[i].Link,
-					Description: rssFeed.Channel.Item[i].Description,
-					PublishedAt: rssFeed.Channel.Item[i].PubDate,
-					FeedID:      dbFeed.ID,
-				}
-				_, err := s.db.CreatePost(context.Background(), params)
-				if err != nil {
-					println(err)
-				}
-				println(params.Title)
-				println(params.PublishedAt)
-				println(params.Url)
+				log.Println(err)
 			}
 		}
+
+		savePosts(s, dbFeed, rssFeed.Channel.Item)
 	}
 }
 
-func handlerBrowse(s *state, cmd command, user database.User) error {
-	// prints posts using GetPostsForUser
+func savePosts(s *state, dbFeed database.Feed, items []RSSItem) {
+	// load the rules of every follower so they can run on new posts
+	rules, err := s.db.GetRulesForFeed(context.Background(), dbFeed.ID)
+	if err != nil {
+		log.Println(err)
+	}
 
-	// ensure maximum of 1 arg was passed
-	if len(cmd.Args) > 1 {
-		return fmt.Errorf("usage: %v {num_posts}", cmd.Name)
+	// create posts table entries for any posts that dont have entries already
+	for i := 0; i < len(items); i++ {
+		_, err := s.db.GetPostByUrl(context.Background(), items[i].Link)
+		if err != nil {
+			// created_at stands in for a missing pubDate, so it's kept in UTC too
+			now := time.Now().UTC()
+			params := database.CreatePostParams{
+				ID:          uuid.New(),
+				CreatedAt:   now,
+				UpdatedAt:   now,
+				Title:       items[i].Title,
+				Url:         items[i].Link,
+				Description: items[i].Description,
+				PublishedAt: parsePublished(items[i].PubDate),
+				FeedID:      dbFeed.ID,
+				Author:      items[i].Author,
+				Content:     items[i].Content,
+				Categories:  orEmpty(items[i].Categories),
+			}
+			post, err := s.db.CreatePost(context.Background(), params)
+			if err != nil {
+				println(err)
+				continue
+			}
+			applyRules(s, rules, post)
+			queueWebhooks(s, dbFeed, post)
+			println(params.Title)
+			println(formatPublished(params.PublishedAt))
+			println(params.Url)
+		}
 	}
+}
 
-	// retrieve current user's ID
-	user, err := s.db.GetUserByName(context.Background(), s.cfg.CurrentUserName)
+func handlerBrowse(s *state, cmd command, user database.User) error {
+	// prints posts using GetPostsForUser
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	var filters postFilters
+	filters.register(fs, 2)
+	args, err := parseFlags(fs, cmd.Args)
 	if err != nil {
 		return err
 	}
 
-	// create GetPostsForUser params
-	params := database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  2,
+	// ensure maximum of 1 positional arg was passed
+	if len(args) > 1 {
+		return fmt.Errorf("usage: %v {num_posts} [--page n | --offset n] [--since t] [--until t] [--feed name|url] [--folder name] [--unread] [--starred] [--tag t] [--sort asc|desc]", cmd.Name)
 	}
 
 	// if a limit arg was passed, set the limit parameter to match
-	if len(cmd.Args) == 1 {
-		i, err := strconv.Atoi(cmd.Args[0])
+	if len(args) == 1 {
+		filters.limit, err = strconv.Atoi(args[0])
 		if err != nil {
 			return err
 		}
-		params.Limit = int32(i)
+	}
+
+	params, err := filters.params(user)
+	if err != nil {
+		return err
 	}
 
 	// get the posts
@@ -286,11 +559,103 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 		return err
 	}
 	for i := 0; i < len(row); i++ {
+		title := row[i].Title
+		if row[i].StarredAt.Valid {
+			title = "[starred] " + title
+		}
 		println(row[i].Name)
-		println(row[i].Title)
-		println(row[i].PublishedAt)
+		println(title)
+		println(formatPublished(row[i].PublishedAt))
 		println(row[i].Url)
 		// println(row[i].Description)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// postFilters holds the flags shared by every command that lists a user's posts
+type postFilters struct {
+	limit   int
+	page    int
+	offset  int
+	since   string
+	until   string
+	feed    string
+	folder  string
+	tag     string
+	unread  bool
+	starred bool
+	sort    string
+}
+
+func (f *postFilters) register(fs *flag.FlagSet, defaultLimit int) {
+	fs.IntVar(&f.limit, "limit", defaultLimit, "number of posts to show")
+	fs.IntVar(&f.page, "page", 1, "page of results to show, starting at 1")
+	fs.IntVar(&f.offset, "offset", 0, "number of posts to skip (overrides --page)")
+	fs.StringVar(&f.since, "since", "", "only show posts published after this date or duration ago (e.g. 2024-01-31, 24h, 7d)")
+	fs.StringVar(&f.until, "until", "", "only show posts published before this date or duration ago")
+	fs.StringVar(&f.feed, "feed", "", "only show posts from the feed with this name or url")
+	fs.StringVar(&f.folder, "folder", "", "only show posts from feeds in this folder")
+	fs.StringVar(&f.tag, "tag", "", "only show posts with this tag")
+	fs.BoolVar(&f.unread, "unread", false, "only show unread posts")
+	fs.BoolVar(&f.starred, "starred", false, "only show starred posts")
+	fs.StringVar(&f.sort, "sort", "desc", "sort order by publish date: asc or desc")
+}
+
+func (f postFilters) params(user database.User) (database.GetPostsForUserParams, error) {
+	// converts the filter flags into GetPostsForUser params
+	if f.limit < 1 {
+		return database.GetPostsForUserParams{}, fmt.Errorf("limit must be at least 1")
+	}
+	if f.page < 1 {
+		return database.GetPostsForUserParams{}, fmt.Errorf("page must be at least 1")
+	}
+	if f.offset < 0 {
+		return database.GetPostsForUserParams{}, fmt.Errorf("offset can't be negative")
+	}
+	offset := f.offset
+	if offset == 0 {
+		offset = (f.page - 1) * f.limit
+	}
+
+	params := database.GetPostsForUserParams{
+		UserID:      user.ID,
+		UnreadOnly:  f.unread,
+		StarredOnly: f.starred,
+		Limit:       int32(f.limit),
+		Offset:      int32(offset),
+	}
+
+	switch f.sort {
+	case "asc":
+		params.Ascending = true
+	case "desc":
+	default:
+		return database.GetPostsForUserParams{}, fmt.Errorf("sort must be asc or desc, got %q", f.sort)
+	}
+
+	now := time.Now().UTC()
+	if f.since != "" {
+		t, err := parseTimeArg(f.since, now)
+		if err != nil {
+			return database.GetPostsForUserParams{}, err
+		}
+		params.Since = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+	if f.until != "" {
+		t, err := parseTimeArg(f.until, now)
+		if err != nil {
+			return database.GetPostsForUserParams{}, err
+		}
+		params.Until = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+	if f.feed != "" {
+		params.Feed = sql.NullString{String: f.feed, Valid: true}
+	}
+	if f.folder != "" {
+		params.Folder = sql.NullString{String: f.folder, Valid: true}
+	}
+	if f.tag != "" {
+		params.Tag = sql.NullString{String: f.tag, Valid: true}
+	}
+	return params, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/cryptidcodes/gator/internal/database"
+)
+
+func TestLookupFeedByTitle(t *testing.T) {
+	// a feed can be named by the title its follower gave it, which only
+	// means something to that follower
+	s := testState(t)
+	alice := testUser(t, s, "alice")
+	bob := testUser(t, s, "bob")
+	goBlog := testFeed(t, s, alice, "The Go Blog", "https://go.dev/blog/feed.atom")
+	_, err := s.db.SetFeedFollowTitle(context.Background(), database.SetFeedFollowTitleParams{
+		UserID: alice.ID,
+		FeedID: goBlog.ID,
+		Title:  sql.NullString{String: "Go", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{"Go", "The Go Blog", "https://go.dev/blog/feed.atom"} {
+		feed, err := lookupFeed(s, alice, ref)
+		if err != nil || feed.ID != goBlog.ID {
+			t.Errorf("lookupFeed(alice, %q) = %v, %v, want the Go Blog", ref, feed.Name, err)
+		}
+	}
+	if _, err := lookupFeed(s, bob, "Go"); !errors.As(err, new(notFoundError)) {
+		t.Errorf("lookupFeed(bob, Go) = %v, want not found", err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+var filterFields = map[string]bool{
+	"title":       true,
+	"description": true,
+	"author":      true,
+	"tag":         true,
+}
+
+func handlerFilter(s *state, cmd command, user database.User) error {
+	// dispatches the filter subcommands
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: %v add|list|rm", cmd.Name)
+	}
+	sub := command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+	switch cmd.Args[0] {
+	case "add":
+		return filterAdd(s, sub, user)
+	case "list":
+		return filterList(s, sub, user)
+	case "rm":
+		return filterRemove(s, sub, user)
+	}
+	return fmt.Errorf("unknown subcommand %q, usage: %v add|list|rm", cmd.Args[0], cmd.Name)
+}
+
+func filterAdd(s *state, cmd command, user database.User) error {
+	// adds a rule hiding matching posts from browse, search and exports
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	field := fs.String("field", "title", "post field to match: title, description, author or tag")
+	regex := fs.Bool("regex", false, "treat the pattern as a case insensitive regular expression")
+	feed := fs.String("feed", "", "only apply the rule to the feed with this name or url")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v {pattern} [--field title|description|author|tag] [--regex] [--feed name|url]", cmd.Name)
+	}
+	if !filterFields[*field] {
+		return fmt.Errorf("unknown field %q: use title, description, author or tag", *field)
+	}
+
+	params := database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Field:     *field,
+		MatchType: "substring",
+		Pattern:   args[0],
+	}
+	if *regex {
+		// filters run inside postgres, whose regex dialect differs from Go's,
+		// and a pattern it rejects would fail every query that evaluates the rule
+		if err := s.db.CheckFilterRegex(context.Background(), args[0]); err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+		params.MatchType = "regex"
+	}
+	if *feed != "" {
+		dbFeed, err := lookupFeed(s, user, *feed)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: dbFeed.ID, Valid: true}
+	}
+
+	rule, err := s.db.CreateFilterRule(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Filter added: %v\n", rule.ID)
+	return nil
+}
+
+func filterList(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	rules, err := s.db.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		println("No filters")
+		return nil
+	}
+	for i := 0; i < len(rules); i++ {
+		scope := "all feeds"
+		if rules[i].FeedName.Valid {
+			scope = rules[i].FeedName.String
+		}
+		fmt.Printf("%v  %v %v %q (%v)\n", rules[i].ID, rules[i].Field, rules[i].MatchType, rules[i].Pattern, scope)
+	}
+	return nil
+}
+
+func filterRemove(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {filter_id}", cmd.Name)
+	}
+	id, err := uuid.Parse(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("invalid filter id %v", cmd.Args[0])
+	}
+
+	n, err := s.db.DeleteFilterRule(context.Background(), database.DeleteFilterRuleParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no filter with id %v", id)
+	}
+	fmt.Printf("Removed filter %v\n", id)
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func lookupFolder(s *state, user database.User, name string) (database.Folder, error) {
+	// finds one of the user's folders by name
+	folder, err := s.db.GetFolderByName(context.Background(), database.GetFolderByNameParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Folder{}, notFound("no folder named %v, create it with: gator folder create %v", name, name)
+	}
+	return folder, err
+}
+
+func handlerFolder(s *state, cmd command, user database.User) error {
+	// dispatches the folder subcommands
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: %v create|list|rm|mv", cmd.Name)
+	}
+	sub := command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+	switch cmd.Args[0] {
+	case "create":
+		return folderCreate(s, sub, user)
+	case "list":
+		return folderList(s, sub, user)
+	case "rm":
+		return folderRemove(s, sub, user)
+	case "mv":
+		return folderMove(s, sub, user)
+	}
+	return fmt.Errorf("unknown subcommand %q, usage: %v create|list|rm|mv", cmd.Args[0], cmd.Name)
+}
+
+func folderCreate(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {name}", cmd.Name)
+	}
+
+	folder, err := s.db.CreateFolder(context.Background(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      cmd.Args[0],
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create folder %v: %v", cmd.Args[0], err)
+	}
+	fmt.Printf("Folder created: %v\n", folder.Name)
+	return nil
+}
+
+func folderList(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	folders, err := s.db.GetFoldersForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if len(folders) == 0 {
+		println("No folders")
+		return nil
+	}
+	for i := 0; i < len(folders); i++ {
+		fmt.Printf("%v (%d feeds)\n", folders[i].Name, folders[i].FeedCount)
+	}
+	return nil
+}
+
+func folderRemove(s *state, cmd command, user database.User) error {
+	// deletes a folder, leaving its feeds followed but unfiled
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {name}", cmd.Name)
+	}
+
+	n, err := s.db.DeleteFolder(context.Background(), database.DeleteFolderParams{
+		UserID: user.ID,
+		Name:   cmd.Args[0],
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no folder named %v", cmd.Args[0])
+	}
+	fmt.Printf("Removed folder %v\n", cmd.Args[0])
+	return nil
+}
+
+func folderMove(s *state, cmd command, user database.User) error {
+	// moves a followed feed into a folder, or out of any folder with --none
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	none := fs.Bool("none", false, "take the feed out of its folder")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if (len(args) != 2 || *none) && (len(args) != 1 || !*none) {
+		return fmt.Errorf("usage: %v {feed name|url} {folder} | %v {feed name|url} --none", cmd.Name, cmd.Name)
+	}
+
+	feed, err := lookupFeed(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	folderID := uuid.NullUUID{}
+	destination := "no folder"
+	if !*none {
+		folder, err := lookupFolder(s, user, args[1])
+		if err != nil {
+			return err
+		}
+		folderID = uuid.NullUUID{UUID: folder.ID, Valid: true}
+		destination = folder.Name
+	}
+
+	n, err := s.db.SetFeedFollowFolder(context.Background(), database.SetFeedFollowFolderParams{
+		UserID:   user.ID,
+		FeedID:   feed.ID,
+		FolderID: folderID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("you aren't following %v", feed.Name)
+	}
+	fmt.Printf("Moved %v to %v\n", feed.Name, destination)
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/cryptidcodes/gator/internal/opml"
+	"github.com/google/uuid"
+)
+
+func handlerImport(s *state, cmd command, user database.User) error {
+	// dispatches the import formats
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: %v opml {file}", cmd.Name)
+	}
+	sub := command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+	switch cmd.Args[0] {
+	case "opml":
+		return importOPML(s, sub, user)
+	}
+	return fmt.Errorf("unknown format %q, usage: %v opml {file}", cmd.Args[0], cmd.Name)
+}
+
+func importOPML(s *state, cmd command, user database.User) error {
+	// follows every feed in an OPML file, creating feeds and folders as needed
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {file}", cmd.Name)
+	}
+
+	file, err := os.Open(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	doc, err := opml.Parse(file)
+	if err != nil {
+		return err
+	}
+
+	// the whole file is imported or, if anything fails, none of it
+	ctx := context.Background()
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.db.WithTx(tx)
+
+	var created, followed, skipped int
+	invalid := make([]string, 0)
+	folders := make(map[string]uuid.NullUUID)
+	for _, entry := range doc.Entries() {
+		if err := validateFeedURL(entry.XMLURL); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%v: %v", entry.Title, err))
+			continue
+		}
+
+		// reuse the feed if someone already added it
+		feed, err := q.GetFeedByUrl(ctx, entry.XMLURL)
+		if errors.Is(err, sql.ErrNoRows) {
+			name := entry.Title
+			if name == "" {
+				name = entry.XMLURL
+			}
+			feed, err = q.CreateFeed(ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      name,
+				Url:       entry.XMLURL,
+				UserID:    user.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("couldn't create feed %v: %v", entry.XMLURL, err)
+			}
+			if err := recordAudit(ctx, q, user, "addfeed", feed.Url, feed.Name+" (opml import)"); err != nil {
+				return err
+			}
+			if entry.HTMLURL != "" {
+				err = q.SetFeedSiteUrl(ctx, database.SetFeedSiteUrlParams{
+					ID:      feed.ID,
+					SiteUrl: entry.HTMLURL,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			created++
+		} else if err != nil {
+			return err
+		}
+
+		_, err = q.GetFeedFollow(ctx, database.GetFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+		})
+		if err == nil {
+			skipped++
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		folderID, ok := folders[entry.Folder]
+		if !ok && entry.Folder != "" {
+			folderID, err = ensureFolder(ctx, q, user, entry.Folder)
+			if err != nil {
+				return err
+			}
+			folders[entry.Folder] = folderID
+		}
+
+		_, err = q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+			FolderID:  folderID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't follow %v: %v", entry.XMLURL, err)
+		}
+		if err := recordAudit(ctx, q, user, "follow", feed.Url, "opml import"); err != nil {
+			return err
+		}
+		followed++
+
+		// keep the title from the file if the existing feed is named differently
+		if entry.Title != "" && entry.Title != feed.Name {
+			_, err = q.SetFeedFollowTitle(ctx, database.SetFeedFollowTitleParams{
+				UserID: user.ID,
+				FeedID: feed.ID,
+				Title:  sql.NullString{String: entry.Title, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %v\n", cmd.Args[0])
+	fmt.Printf("Followed: %d (%d new feeds added)\n", followed, created)
+	fmt.Printf("Skipped:  %d (already following)\n", skipped)
+	fmt.Printf("Invalid:  %d\n", len(invalid))
+	for _, entry := range invalid {
+		fmt.Printf("  %v\n", entry)
+	}
+	return nil
+}
+
+func ensureFolder(ctx context.Context, q *database.Queries, user database.User, name string) (uuid.NullUUID, error) {
+	// returns the id of the user's folder with this name, creating it if needed
+	folder, err := q.GetFolderByName(ctx, database.GetFolderByNameParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		folder, err = q.CreateFolder(ctx, database.CreateFolderParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			Name:      name,
+		})
+	}
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: folder.ID, Valid: true}, nil
+}
+
+func validateFeedURL(feedURL string) error {
+	if feedURL == "" {
+		return errors.New("no feed url")
+	}
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %v", feedURL)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("not an http(s) url: %v", feedURL)
+	}
+	return nil
+}
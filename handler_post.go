@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/cryptidcodes/gator/internal/htmltext"
+	"github.com/google/uuid"
+)
+
+const showWidth = 80
+
+func lookupPost(s *state, user database.User, ref string) (database.GetPostDetailRow, error) {
+	// finds a post by its url or id, with the feed named as the user sees it.
+	// Any post can be shown on the command line, as search --all-feeds finds them.
+	return getPostDetail(s, database.GetPostDetailParams{UserID: user.ID, Ref: ref})
+}
+
+func lookupFollowedPost(s *state, user database.User, ref string) (database.GetPostDetailRow, error) {
+	// lookupPost for the server, which only serves posts from feeds the user follows
+	return getPostDetail(s, database.GetPostDetailParams{UserID: user.ID, Ref: ref, FollowingOnly: true})
+}
+
+func getPostDetail(s *state, params database.GetPostDetailParams) (database.GetPostDetailRow, error) {
+	post, err := s.db.GetPostDetail(context.Background(), params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.GetPostDetailRow{}, notFound("no post found for %v", params.Ref)
+	}
+	return post, err
+}
+
+func handlerShow(s *state, cmd command, user database.User) error {
+	// prints a single post with its description rendered as terminal text
+
+	// ensure we were passed exactly 1 arg (post url or id)
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {post_url|post_id}", cmd.Name)
+	}
+
+	post, err := lookupPost(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	// print the header
+	fmt.Println(post.Title)
+	fmt.Println(strings.Repeat("=", min(len(post.Title), showWidth)))
+	fmt.Printf("Feed:      %v\n", post.FeedName)
+	if post.Author != "" {
+		fmt.Printf("Author:    %v\n", post.Author)
+	}
+	fmt.Printf("Published: %v\n", formatPublished(post.PublishedAt))
+	fmt.Printf("Link:      %v\n", post.Url)
+	fmt.Printf("ID:        %v\n", post.ID)
+	fmt.Println()
+
+	// prefer the full content over the summary when the feed provides it
+	body := post.Content
+	if strings.TrimSpace(body) == "" {
+		body = post.Description
+	}
+	fmt.Println(htmltext.Render(body, showWidth))
+
+	// viewing a post counts as reading it
+	return s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+}
+
+func handlerMark(s *state, cmd command, user database.User) error {
+	// sets the read or starred state of a post for the current user
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %v {post_url|post_id} read|unread|starred|unstarred", cmd.Name)
+	}
+
+	post, err := lookupPost(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := setPostState(s, user, post.ID, cmd.Args[1]); err != nil {
+		return err
+	}
+	fmt.Printf("Marked %v as %v\n", post.Title, cmd.Args[1])
+	return nil
+}
+
+func setPostState(s *state, user database.User, postID uuid.UUID, state string) error {
+	// applies one of read, unread, starred or unstarred to a post
+	switch state {
+	case "read":
+		return s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{UserID: user.ID, PostID: postID})
+	case "unread":
+		return s.db.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{UserID: user.ID, PostID: postID})
+	case "starred":
+		return s.db.StarPost(context.Background(), database.StarPostParams{UserID: user.ID, PostID: postID})
+	case "unstarred":
+		return s.db.UnstarPost(context.Background(), database.UnstarPostParams{UserID: user.ID, PostID: postID})
+	}
+	return fmt.Errorf("unknown state %q: use read, unread, starred or unstarred", state)
+}
+
+func handlerTag(s *state, cmd command, user database.User) error {
+	// adds a tag to a post for the current user, or removes it with --remove
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	remove := fs.Bool("remove", false, "remove the tag instead of adding it")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: %v {post_url|post_id} {tag} [--remove]", cmd.Name)
+	}
+
+	post, err := lookupPost(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	if *remove {
+		n, err := s.db.UntagPost(context.Background(), database.UntagPostParams{
+			UserID: user.ID,
+			PostID: post.ID,
+			Tag:    args[1],
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("%v isn't tagged %v", post.Title, args[1])
+		}
+		fmt.Printf("Removed tag %v from %v\n", args[1], post.Title)
+		return nil
+	}
+
+	err = s.db.TagPost(context.Background(), database.TagPostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		Tag:    args[1],
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Tagged %v with %v\n", post.Title, args[1])
+	return nil
+}
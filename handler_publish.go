@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/cryptidcodes/gator/internal/feedgen"
+)
+
+func handlerPublish(s *state, cmd command, user database.User) error {
+	// writes a user's aggregated timeline as an Atom or RSS document
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	var filters postFilters
+	filters.register(fs, 50)
+	format := fs.String("format", "atom", "document format: atom or rss")
+	userName := fs.String("user", "", "publish another user's timeline")
+	title := fs.String("title", "", "title of the published feed")
+	link := fs.String("link", "", "url the feed will be served from (required for rss)")
+	out := fs.String("out", "", "file to write to instead of stdout")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--format atom|rss] [--user name] [--folder name] [--limit n] [--link url] [--title t] [--out file]", cmd.Name)
+	}
+	if *format == "rss" && *link == "" {
+		return fmt.Errorf("rss feeds need a link, pass the url the feed will be served from with --link")
+	}
+
+	if *userName != "" {
+		user, err = lookupUserAs(s, cmd, user, *userName)
+		if err != nil {
+			return err
+		}
+	}
+
+	write, err := feedWriter(*format)
+	if err != nil {
+		return err
+	}
+	params, err := filters.params(user)
+	if err != nil {
+		return err
+	}
+	feed, err := buildPublishedFeed(s, user, params, *title, *link)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*out, func(w io.Writer) error {
+		return write(w, feed)
+	})
+}
+
+func feedWriter(format string) (func(io.Writer, feedgen.Feed) error, error) {
+	switch format {
+	case "atom":
+		return feedgen.WriteAtom, nil
+	case "rss":
+		return feedgen.WriteRSS, nil
+	}
+	return nil, fmt.Errorf("unknown format %q: use atom or rss", format)
+}
+
+func buildPublishedFeed(s *state, user database.User, params database.GetPostsForUserParams, title, link string) (feedgen.Feed, error) {
+	// loads the posts matching params and converts them into a feedgen.Feed
+	rows, err := s.db.GetPostsForUser(context.Background(), params)
+	if err != nil {
+		return feedgen.Feed{}, err
+	}
+
+	id := "urn:gator:" + user.Name
+	if params.Folder.Valid {
+		id += ":" + params.Folder.String
+	}
+	if title == "" {
+		title = user.Name + "'s gator timeline"
+		if params.Folder.Valid {
+			title = fmt.Sprintf("%v's gator timeline: %v", user.Name, params.Folder.String)
+		}
+	}
+
+	feed := feedgen.Feed{
+		ID:      id,
+		Title:   title,
+		Link:    link,
+		Author:  user.Name,
+		Updated: time.Now(),
+	}
+	for i, row := range rows {
+		published := publishedOrCreated(row.PublishedAt, row.CreatedAt)
+		// the timeline is as fresh as its newest post
+		if i == 0 || published.After(feed.Updated) {
+			feed.Updated = published
+		}
+		feed.Items = append(feed.Items, feedgen.Item{
+			ID:          "urn:uuid:" + row.ID.String(),
+			Title:       row.Title,
+			Link:        row.Url,
+			Summary:     row.Description,
+			Content:     row.Content,
+			Author:      row.Author,
+			Categories:  row.Categories,
+			Published:   published,
+			SourceTitle: row.Name,
+			SourceLink:  row.FeedUrl,
+		})
+	}
+	return feed, nil
+}
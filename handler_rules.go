@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+var ruleActions = map[string]bool{
+	"read":   true,
+	"star":   true,
+	"tag":    true,
+	"notify": true,
+}
+
+func handlerRules(s *state, cmd command, user database.User) error {
+	// dispatches the rules subcommands
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: %v add|list|rm|test", cmd.Name)
+	}
+	sub := command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+	switch cmd.Args[0] {
+	case "add":
+		return rulesAdd(s, sub, user)
+	case "list":
+		return rulesList(s, sub, user)
+	case "rm":
+		return rulesRemove(s, sub, user)
+	case "test":
+		return rulesTest(s, sub, user)
+	}
+	return fmt.Errorf("unknown subcommand %q, usage: %v add|list|rm|test", cmd.Args[0], cmd.Name)
+}
+
+func rulesAdd(s *state, cmd command, user database.User) error {
+	// adds a rule that runs an action on new posts matching a condition
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	action := fs.String("action", "", "action to run on matching posts: read, star, tag or notify")
+	tag := fs.String("tag", "", "tag to add when the action is tag")
+	webhook := fs.String("webhook", "", "webhook to send matching posts to when the action is notify")
+	field := fs.String("field", "title", "post field to match: title, description, author or tag")
+	regex := fs.Bool("regex", false, "treat the pattern as a case insensitive regular expression")
+	feed := fs.String("feed", "", "only apply the rule to the feed with this name or url")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 || *action == "" {
+		return fmt.Errorf("usage: %v {name} {pattern} --action read|star|tag|notify [--tag t] [--webhook name] [--field title|description|author|tag] [--regex] [--feed name|url]", cmd.Name)
+	}
+	if !ruleActions[*action] {
+		return fmt.Errorf("unknown action %q: use read, star, tag or notify", *action)
+	}
+	if (*action == "tag") != (*tag != "") {
+		return fmt.Errorf("--tag is required for the tag action and only allowed with it")
+	}
+	if (*action == "notify") != (*webhook != "") {
+		return fmt.Errorf("--webhook is required for the notify action and only allowed with it")
+	}
+	if !filterFields[*field] {
+		return fmt.Errorf("unknown field %q: use title, description, author or tag", *field)
+	}
+
+	params := database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      args[0],
+		Field:     *field,
+		MatchType: "substring",
+		Pattern:   args[1],
+		Action:    *action,
+		ActionArg: *tag,
+	}
+	if *webhook != "" {
+		_, err := s.db.GetWebhookByName(context.Background(), database.GetWebhookByNameParams{
+			UserID: user.ID,
+			Name:   *webhook,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no webhook named %v, add it first with: webhook add", *webhook)
+		}
+		if err != nil {
+			return err
+		}
+		params.ActionArg = *webhook
+	}
+	if *regex {
+		if _, err := regexp.Compile(args[1]); err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+		params.MatchType = "regex"
+	}
+	if *feed != "" {
+		dbFeed, err := lookupFeed(s, user, *feed)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: dbFeed.ID, Valid: true}
+	}
+
+	rule, err := s.db.CreateRule(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't add rule %v: %v", args[0], err)
+	}
+	fmt.Printf("Rule added: %v\n", rule.Name)
+	return nil
+}
+
+func rulesList(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	rules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		println("No rules")
+		return nil
+	}
+	for i := 0; i < len(rules); i++ {
+		scope := "all feeds"
+		if rules[i].FeedName.Valid {
+			scope = rules[i].FeedName.String
+		}
+		action := rules[i].Action
+		if rules[i].ActionArg != "" {
+			action += " " + rules[i].ActionArg
+		}
+		if action == "notify" {
+			// added before notify rules named a webhook, so they do nothing
+			action += " (no webhook: remove the rule and add it again with --webhook)"
+		}
+		fmt.Printf("%v: %v if %v %v %q (%v)\n", rules[i].Name, action, rules[i].Field, rules[i].MatchType, rules[i].Pattern, scope)
+	}
+	return nil
+}
+
+func rulesRemove(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {name}", cmd.Name)
+	}
+
+	n, err := s.db.DeleteRule(context.Background(), database.DeleteRuleParams{
+		UserID: user.ID,
+		Name:   cmd.Args[0],
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no rule named %v", cmd.Args[0])
+	}
+	fmt.Printf("Removed rule %v\n", cmd.Args[0])
+	return nil
+}
+
+func rulesTest(s *state, cmd command, user database.User) error {
+	// dry-runs a rule against a feed's stored posts, or a live fetch of
+	// the feed if it hasn't been added to gator yet
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	against := fs.String("against", "", "name or url of the feed to test the rule against")
+	limit := fs.Int("limit", 50, "number of recent posts to test")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || *against == "" {
+		return fmt.Errorf("usage: %v {rule} --against {feed name|url} [--limit n]", cmd.Name)
+	}
+
+	rule, err := s.db.GetRuleByName(context.Background(), database.GetRuleByNameParams{
+		UserID: user.ID,
+		Name:   args[0],
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no rule named %v", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	type candidate struct {
+		title  string
+		url    string
+		target ruleTarget
+	}
+	candidates := make([]candidate, 0)
+
+	feed, err := lookupFeed(s, user, *against)
+	if err == nil {
+		if rule.FeedID.Valid && rule.FeedID.UUID != feed.ID {
+			fmt.Printf("Note: rule %v is scoped to another feed and would not run on %v\n", rule.Name, feed.Name)
+		}
+		posts, err := s.db.GetRecentPostsForFeed(context.Background(), database.GetRecentPostsForFeedParams{
+			FeedID: feed.ID,
+			Limit:  int32(*limit),
+		})
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			candidates = append(candidates, candidate{post.Title, post.Url, postRuleTarget(post)})
+		}
+	} else {
+		rssFeed, fetchErr := fetchFeed(context.Background(), *against)
+		if fetchErr != nil {
+			return fmt.Errorf("%v, and fetching it failed: %v", err, fetchErr)
+		}
+		for _, item := range rssFeed.Channel.Item {
+			candidates = append(candidates, candidate{item.Title, item.Link, itemRuleTarget(item)})
+		}
+	}
+
+	action := rule.Action
+	if rule.ActionArg != "" {
+		action += " " + rule.ActionArg
+	}
+	matched := 0
+	for _, c := range candidates {
+		ok, err := ruleMatches(rule.Field, rule.MatchType, rule.Pattern, c.target)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		matched++
+		fmt.Printf("would %v: %v\n", action, c.title)
+		fmt.Printf("  %v\n", c.url)
+	}
+	fmt.Printf("%d of %d posts matched rule %v\n", matched, len(candidates), rule.Name)
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+func handlerSavedSearch(s *state, cmd command, user database.User) error {
+	// dispatches the savedsearch subcommands
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: %v add|list|rm|browse", cmd.Name)
+	}
+	sub := command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+	switch cmd.Args[0] {
+	case "add":
+		return savedSearchAdd(s, sub, user)
+	case "list":
+		return savedSearchList(s, sub, user)
+	case "rm":
+		return savedSearchRemove(s, sub, user)
+	case "browse":
+		return savedSearchBrowse(s, sub, user)
+	}
+	return fmt.Errorf("unknown subcommand %q, usage: %v add|list|rm|browse", cmd.Args[0], cmd.Name)
+}
+
+func savedSearchAdd(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) < 2 {
+		return fmt.Errorf("usage: %v {name} {query}", cmd.Name)
+	}
+
+	search, err := s.db.CreateSavedSearch(context.Background(), database.CreateSavedSearchParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      cmd.Args[0],
+		Query:     normalizeSearchQuery(strings.Join(cmd.Args[1:], " ")),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't save search %v: %v", cmd.Args[0], err)
+	}
+	fmt.Printf("Saved search %v: %v\n", search.Name, search.Query)
+	return nil
+}
+
+func savedSearchList(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	searches, err := s.db.GetSavedSearchesForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if len(searches) == 0 {
+		println("No saved searches")
+		return nil
+	}
+	for i := 0; i < len(searches); i++ {
+		fmt.Printf("%v (%d unread): %v\n", searches[i].Name, searches[i].UnreadCount, searches[i].Query)
+	}
+	return nil
+}
+
+func savedSearchRemove(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {name}", cmd.Name)
+	}
+
+	n, err := s.db.DeleteSavedSearch(context.Background(), database.DeleteSavedSearchParams{
+		UserID: user.ID,
+		Name:   cmd.Args[0],
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no saved search named %v", cmd.Args[0])
+	}
+	fmt.Printf("Removed saved search %v\n", cmd.Args[0])
+	return nil
+}
+
+func savedSearchBrowse(s *state, cmd command, user database.User) error {
+	// prints the posts matching a saved search like a feed, marking them read
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	limit := fs.Int("limit", 10, "number of posts to show")
+	page := fs.Int("page", 1, "page of results to show, starting at 1")
+	unread := fs.Bool("unread", false, "only show unread posts")
+	keepUnread := fs.Bool("keep-unread", false, "don't mark the shown posts as read")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v {name} [--limit n] [--page n] [--unread] [--keep-unread]", cmd.Name)
+	}
+	if *limit < 1 || *page < 1 {
+		return fmt.Errorf("limit and page must be at least 1")
+	}
+
+	search, err := s.db.GetSavedSearchByName(context.Background(), database.GetSavedSearchByNameParams{
+		UserID: user.ID,
+		Name:   args[0],
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no saved search named %v", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.GetPostsForSavedSearch(context.Background(), database.GetPostsForSavedSearchParams{
+		UserID:     user.ID,
+		Query:      search.Query,
+		UnreadOnly: *unread,
+		Limit:      int32(*limit),
+		Offset:     int32((*page - 1) * *limit),
+	})
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		println("No posts found")
+		return nil
+	}
+	for i := 0; i < len(rows); i++ {
+		title := rows[i].Title
+		if !rows[i].ReadAt.Valid {
+			title = "* " + title
+		}
+		println(rows[i].Name)
+		println(title)
+		println(formatPublished(rows[i].PublishedAt))
+		println(rows[i].Url)
+		println()
+
+		if *keepUnread || rows[i].ReadAt.Valid {
+			continue
+		}
+		err = s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+			UserID: user.ID,
+			PostID: rows[i].ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
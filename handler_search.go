@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/cryptidcodes/gator/internal/database"
+)
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+	// prints posts matching a full text query, best matches first
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	all := fs.Bool("all", false, "search every feed instead of only followed feeds")
+	feed := fs.String("feed", "", "only search the feed with this name or url")
+	limit := fs.Int("limit", 10, "maximum number of results")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+
+	// the query may be passed as one quoted arg or as several words
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %v {query} [--all] [--feed name|url] [--limit n]", cmd.Name)
+	}
+	if *limit < 1 {
+		return fmt.Errorf("limit must be at least 1")
+	}
+
+	params := database.SearchPostsParams{
+		Query:    normalizeSearchQuery(strings.Join(args, " ")),
+		AllFeeds: *all,
+		UserID:   user.ID,
+		Limit:    int32(*limit),
+	}
+	if *feed != "" {
+		params.Feed = sql.NullString{String: *feed, Valid: true}
+	}
+
+	rows, err := s.db.SearchPosts(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		println("No posts found")
+		return nil
+	}
+	for i := 0; i < len(rows); i++ {
+		println(rows[i].FeedName)
+		println(rows[i].Title)
+		println(formatPublished(rows[i].PublishedAt))
+		println(rows[i].Url)
+		println()
+	}
+	return nil
+}
+
+// normalizeSearchQuery maps the boolean operators users tend to type onto
+// websearch_to_tsquery syntax, which already understands "quoted phrases",
+// OR and -negation, and treats every other pair of words as AND.
+func normalizeSearchQuery(query string) string {
+	words := strings.Fields(query)
+	out := make([]string, 0, len(words))
+	negateNext := false
+	for _, word := range words {
+		switch word {
+		case "AND", "&&":
+			continue
+		case "||":
+			out = append(out, "OR")
+			continue
+		case "NOT":
+			negateNext = true
+			continue
+		}
+		if negateNext {
+			word = "-" + word
+			negateNext = false
+		}
+		out = append(out, word)
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+)
+
+// server exposes gator over HTTP
+type server struct {
+	s *state
+	// publicURL is where hubs can reach the server, empty if they can't
+	publicURL string
+}
+
+func handlerServe(s *state, cmd command) error {
+	// runs the HTTP server until it fails
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	publicURL := fs.String("public-url", "", "url the server is reachable at from the internet, enables WebSub")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--addr host:port] [--public-url url]", cmd.Name)
+	}
+	if *publicURL != "" {
+		if err := validateFeedURL(*publicURL); err != nil {
+			return fmt.Errorf("invalid --public-url: %v", err)
+		}
+	}
+
+	srv := &server{s: s, publicURL: *publicURL}
+	if srv.publicURL != "" {
+		go srv.websubLoop(context.Background())
+	}
+	// pushed posts can trigger webhooks too
+	go webhookLoop(s)
+	if s.cfg.SMTP != nil {
+		go digestLoop(s)
+	}
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           srv.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("gator serving on http://%v", *addr)
+	return httpServer.ListenAndServe()
+}
+
+func (srv *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users", srv.authenticated(scopeRead, srv.apiUsers))
+	mux.HandleFunc("GET /api/feeds", srv.authenticated(scopeRead, srv.apiFeeds))
+	mux.HandleFunc("POST /api/feeds", srv.authenticated(scopeWrite, srv.apiCreateFeed))
+	mux.HandleFunc("GET /api/follows", srv.authenticated(scopeRead, srv.apiFollows))
+	mux.HandleFunc("POST /api/follows", srv.authenticated(scopeWrite, srv.apiFollow))
+	mux.HandleFunc("DELETE /api/follows/{feed}", srv.authenticated(scopeWrite, srv.apiUnfollow))
+	mux.HandleFunc("GET /api/posts", srv.authenticated(scopeRead, srv.apiPosts))
+	mux.HandleFunc("GET /api/posts/{post}", srv.authenticated(scopeRead, srv.apiPost))
+	mux.HandleFunc("PUT /api/posts/{post}/read", srv.authenticated(scopeWrite, srv.apiMarkPost("read")))
+	mux.HandleFunc("DELETE /api/posts/{post}/read", srv.authenticated(scopeWrite, srv.apiMarkPost("unread")))
+	mux.HandleFunc("PUT /api/posts/{post}/star", srv.authenticated(scopeWrite, srv.apiMarkPost("starred")))
+	mux.HandleFunc("DELETE /api/posts/{post}/star", srv.authenticated(scopeWrite, srv.apiMarkPost("unstarred")))
+	mux.HandleFunc("GET /api/publish", srv.authenticated(scopeRead, srv.apiPublish))
+	mux.HandleFunc("/fever/", srv.fever)
+	srv.readerRoutes(mux)
+	mux.HandleFunc("GET /websub/{feed}", srv.websubVerify)
+	mux.HandleFunc("POST /websub/{feed}", srv.websubPush)
+	srv.webRoutes(mux)
+	return logRequests(mux)
+}
+
+// authenticated mirrors middlewareLoggedIn for HTTP handlers: it resolves
+// the bearer token to its user and checks the token grants scope
+func (srv *server) authenticated(scope string, handler func(w http.ResponseWriter, r *http.Request, user database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gator"`)
+			respondWithError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		user, err := srv.authenticate(r.Context(), strings.TrimSpace(token), scope)
+		if err != nil {
+			var authErr authError
+			if errors.As(err, &authErr) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gator", error="`+authErr.code+`"`)
+				respondWithError(w, authErr.status, authErr.msg)
+				return
+			}
+			respondWithErr(w, err)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// authError is an authentication failure reported to the client
+type authError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e authError) Error() string {
+	return e.msg
+}
+
+func (srv *server) authenticate(ctx context.Context, token, scope string) (database.User, error) {
+	// looks up the user an API token belongs to
+	dbToken, err := srv.s.db.GetAPITokenByHash(ctx, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, authError{http.StatusUnauthorized, "invalid_token", "invalid token"}
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+	if dbToken.ExpiresAt.Valid && !dbToken.ExpiresAt.Time.After(time.Now()) {
+		return database.User{}, authError{http.StatusUnauthorized, "invalid_token", "token expired"}
+	}
+	if !slices.Contains(dbToken.Scopes, scope) {
+		return database.User{}, authError{http.StatusForbidden, "insufficient_scope", fmt.Sprintf("token lacks the %v scope", scope)}
+	}
+
+	if err := srv.s.db.MarkAPITokenUsed(ctx, dbToken.ID); err != nil {
+		return database.User{}, err
+	}
+	return srv.s.db.GetUserByID(ctx, dbToken.UserID)
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.Printf("%v %v %v", r.Method, r.URL.Path, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("error marshalling JSON: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	respondWithJSON(w, code, map[string]string{"error": msg})
+}
+
+func respondWithErr(w http.ResponseWriter, err error) {
+	// picks the status code for an error returned by the shared helpers
+	var nf notFoundError
+	if errors.As(err, &nf) {
+		respondWithError(w, http.StatusNotFound, nf.msg)
+		return
+	}
+	log.Println(err)
+	respondWithError(w, http.StatusInternalServerError, "something went wrong")
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
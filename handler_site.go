@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/cryptidcodes/gator/internal/htmltext"
+	"github.com/cryptidcodes/gator/internal/site"
+)
+
+const siteSummaryLength = 300
+
+func handlerSite(s *state, cmd command, user database.User) error {
+	// dispatches the site subcommands
+	if len(cmd.Args) == 0 || cmd.Args[0] != "build" {
+		return fmt.Errorf("usage: %v build --out {dir}", cmd.Name)
+	}
+	return siteBuild(s, command{Name: cmd.Name + " build", Args: cmd.Args[1:]}, user)
+}
+
+func siteBuild(s *state, cmd command, user database.User) error {
+	// renders aggregated posts to a static HTML site
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	out := fs.String("out", "", "directory to write the site to")
+	all := fs.Bool("all", false, "include posts from every feed instead of one user's follows")
+	userName := fs.String("user", "", "build the site from another user's follows")
+	folder := fs.String("folder", "", "only include feeds in this folder")
+	limit := fs.Int("limit", 500, "maximum number of posts to include")
+	perPage := fs.Int("per-page", 20, "posts per page")
+	templates := fs.String("templates", "", "directory with templates overriding base.html, list.html or feeds.html")
+	title := fs.String("title", "What's new", "site title")
+	baseURL := fs.String("base-url", "", "url the site will be hosted at, used in the Atom feed")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 || *out == "" {
+		return fmt.Errorf("usage: %v --out {dir} [--all | --user name] [--folder name] [--limit n] [--per-page n] [--templates dir] [--title t] [--base-url url]", cmd.Name)
+	}
+	if *all && (*userName != "" || *folder != "") {
+		return fmt.Errorf("--all can't be combined with --user or --folder")
+	}
+	if *limit < 1 {
+		return fmt.Errorf("limit must be at least 1")
+	}
+
+	posts := make([]site.Post, 0)
+	slugs := newSlugger()
+	if *all {
+		rows, err := s.db.GetRecentPosts(context.Background(), int32(*limit))
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			posts = append(posts, site.Post{
+				ID:          "urn:uuid:" + row.ID.String(),
+				Title:       row.Title,
+				URL:         row.Url,
+				FeedTitle:   row.FeedName,
+				FeedURL:     row.FeedUrl,
+				FeedSiteURL: row.FeedSiteUrl,
+				FeedSlug:    slugs.slug(row.FeedUrl, row.FeedName),
+				Author:      row.Author,
+				Summary:     summarize(row.Description),
+				Published:   publishedOrCreated(row.PublishedAt, row.CreatedAt),
+			})
+		}
+	} else {
+		if *userName != "" {
+			user, err = lookupUserAs(s, cmd, user, *userName)
+			if err != nil {
+				return err
+			}
+		}
+		filters := postFilters{limit: *limit, page: 1, folder: *folder, sort: "desc"}
+		params, err := filters.params(user)
+		if err != nil {
+			return err
+		}
+		rows, err := s.db.GetPostsForUser(context.Background(), params)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			posts = append(posts, site.Post{
+				ID:          "urn:uuid:" + row.ID.String(),
+				Title:       row.Title,
+				URL:         row.Url,
+				FeedTitle:   row.Name,
+				FeedURL:     row.FeedUrl,
+				FeedSiteURL: row.FeedSiteUrl,
+				FeedSlug:    slugs.slug(row.FeedUrl, row.Name),
+				Author:      row.Author,
+				Summary:     summarize(row.Description),
+				Published:   publishedOrCreated(row.PublishedAt, row.CreatedAt),
+			})
+		}
+	}
+
+	err = site.Build(*out, *templates, &site.Site{
+		Title:   *title,
+		BaseURL: *baseURL,
+		PerPage: *perPage,
+		Posts:   posts,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Built site with %d posts from %d feeds in %v\n", len(posts), len(slugs.byURL), *out)
+	return nil
+}
+
+// slugger hands out a unique slug per feed url
+type slugger struct {
+	byURL map[string]string
+	taken map[string]bool
+}
+
+func newSlugger() *slugger {
+	return &slugger{
+		byURL: make(map[string]string),
+		taken: make(map[string]bool),
+	}
+}
+
+func (sl *slugger) slug(feedURL, title string) string {
+	if slug, ok := sl.byURL[feedURL]; ok {
+		return slug
+	}
+	base := site.Slug(title)
+	slug := base
+	for i := 2; sl.taken[slug]; i++ {
+		slug = fmt.Sprintf("%v-%d", base, i)
+	}
+	sl.byURL[feedURL] = slug
+	sl.taken[slug] = true
+	return slug
+}
+
+var (
+	footnoteMarker = regexp.MustCompile(`\[\d+\]`)
+	footnoteLine   = regexp.MustCompile(`^\[\d+\] `)
+)
+
+func summarize(description string) string {
+	// converts a post description into a short plain text excerpt
+	lines := strings.Split(htmltext.Render(description, 1000), "\n")
+	// drop the link footnotes and their markers, an excerpt has no room for them
+	for len(lines) > 0 && footnoteLine.MatchString(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	text := footnoteMarker.ReplaceAllString(strings.Join(lines, " "), "")
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= siteSummaryLength {
+		return text
+	}
+	runes := []rune(text)
+	cut := string(runes[:siteSummaryLength])
+	if i := strings.LastIndex(cut, " "); i > siteSummaryLength/2 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
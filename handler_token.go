@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// scopeRead allows the GET endpoints of the server
+	scopeRead = "read"
+	// scopeWrite allows endpoints that change data
+	scopeWrite = "write"
+	// scopeFever makes the token a password for Fever API clients
+	scopeFever = "fever"
+	// scopeGReader allows the Google Reader API
+	scopeGReader = "greader"
+)
+
+var tokenScopes = []string{scopeRead, scopeWrite, scopeFever, scopeGReader}
+
+const tokenPrefix = "gator_"
+
+func handlerToken(s *state, cmd command, user database.User) error {
+	// dispatches the token subcommands
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: %v create|list|revoke", cmd.Name)
+	}
+	sub := command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+	switch cmd.Args[0] {
+	case "create":
+		return tokenCreate(s, sub, user)
+	case "list":
+		return tokenList(s, sub, user)
+	case "revoke":
+		return tokenRevoke(s, sub, user)
+	}
+	return fmt.Errorf("unknown subcommand %q, usage: %v create|list|revoke", cmd.Args[0], cmd.Name)
+}
+
+func tokenCreate(s *state, cmd command, user database.User) error {
+	// creates an API token and prints it, the only time it can be seen
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	scopes := fs.String("scopes", scopeRead, "comma separated scopes: "+strings.Join(tokenScopes, ", "))
+	expires := fs.String("expires", "", "date or duration after which the token stops working (e.g. 2025-12-31, 30d), default never")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v {name} [--scopes read,write] [--expires t]", cmd.Name)
+	}
+
+	scopeList, err := parseScopes(*scopes)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(scopeList, scopeFever) && len(scopeList) > 1 {
+		return fmt.Errorf("fever tokens can't have other scopes")
+	}
+	expiresAt := sql.NullTime{}
+	if *expires != "" {
+		t, err := parseFutureTimeArg(*expires, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if !t.After(time.Now()) {
+			return fmt.Errorf("expiry %v is in the past", *expires)
+		}
+		expiresAt = sql.NullTime{Time: t.UTC(), Valid: true}
+	}
+
+	token, err := newToken(tokenPrefix)
+	if err != nil {
+		return err
+	}
+	// Fever clients never send the token itself, only a key derived from it
+	tokenHash := hashToken(token)
+	if slices.Contains(scopeList, scopeFever) {
+		tokenHash = hashToken(feverAPIKey(user.Name, token))
+	}
+	_, err = s.db.CreateAPIToken(context.Background(), database.CreateAPITokenParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      args[0],
+		TokenHash: tokenHash,
+		Scopes:    scopeList,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create token %v: %v", args[0], err)
+	}
+
+	fmt.Printf("Created token %v with scopes %v\n", args[0], strings.Join(scopeList, ","))
+	if expiresAt.Valid {
+		fmt.Printf("Expires: %v\n", expiresAt.Time.Format(time.RFC3339))
+	}
+	println("Copy it now, it won't be shown again:")
+	println(token)
+	if slices.Contains(scopeList, scopeFever) {
+		fmt.Printf("In your Fever app, log in as %v with this token as the password\n", user.Name)
+	}
+	return nil
+}
+
+func tokenList(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	tokens, err := s.db.GetAPITokensForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		println("No tokens")
+		return nil
+	}
+	for _, token := range tokens {
+		expires := "never expires"
+		if token.ExpiresAt.Valid {
+			expires = "expires " + token.ExpiresAt.Time.Format("2006-01-02 15:04")
+			if token.ExpiresAt.Time.Before(time.Now()) {
+				expires = "expired " + token.ExpiresAt.Time.Format("2006-01-02 15:04")
+			}
+		}
+		used := "never used"
+		if token.LastUsedAt.Valid {
+			used = "last used " + token.LastUsedAt.Time.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%v [%v] created %v, %v, %v\n", token.Name, strings.Join(token.Scopes, ","), token.CreatedAt.Format("2006-01-02"), expires, used)
+	}
+	return nil
+}
+
+func tokenRevoke(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {name}", cmd.Name)
+	}
+
+	n, err := s.db.DeleteAPIToken(context.Background(), database.DeleteAPITokenParams{
+		UserID: user.ID,
+		Name:   cmd.Args[0],
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no token named %v", cmd.Args[0])
+	}
+	fmt.Printf("Revoked token %v\n", cmd.Args[0])
+	return nil
+}
+
+func parseScopes(value string) ([]string, error) {
+	scopes := make([]string, 0)
+	for _, scope := range strings.Split(value, ",") {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(tokenScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q, expected %v", scope, strings.Join(tokenScopes, ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+func newToken(prefix string) (string, error) {
+	// tokens carry 256 bits of randomness, so a plain hash is enough to store them
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
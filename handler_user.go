@@ -1,13 +1,22 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/cryptidcodes/gator/internal/database"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
 )
 
 func handlerLogin(s *state, cmd command) error {
@@ -21,11 +30,23 @@ func handlerLogin(s *state, cmd command) error {
 		log.Fatal("user does not exist!")
 	}
 
-	err = s.cfg.SetUser(user.Name)
+	// users without a password can still log in by name
+	if user.PasswordHash.Valid {
+		password, err := promptPassword("Password: ")
+		if err != nil {
+			return err
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password)) != nil {
+			audit(s, user, "login failed", user.Name, "wrong password")
+			return errors.New("wrong password")
+		}
+	}
 
+	err = startSession(s, user)
 	if err != nil {
 		return fmt.Errorf("couldn't login user: %v", err)
 	}
+	audit(s, user, "login", user.Name, "")
 	fmt.Printf("User: %v has logged in!\n", user.Name)
 	return nil
 }
@@ -42,46 +63,290 @@ func handlerRegister(s *state, cmd command) error {
 		log.Fatal("User already exists!")
 	}
 
+	// the password is optional, an empty one leaves the account unprotected
+	password, err := promptNewPassword("Password (leave empty for none): ")
+	if err != nil {
+		return err
+	}
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	// the first user of a fresh database administers it
+	count, err := s.db.CountUsers(context.Background())
+	if err != nil {
+		return err
+	}
+
 	// set user parameters
 	params := database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      cmd.Args[0],
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Name:         cmd.Args[0],
+		PasswordHash: passwordHash,
+		IsAdmin:      count == 0,
 	}
 
 	// create the user database entry
-	s.db.CreateUser(context.Background(), params)
+	user, err = s.db.CreateUser(context.Background(), params)
+	if err != nil {
+		return err
+	}
 
 	// set the user as currently logged in
-	s.cfg.SetUser(cmd.Args[0])
+	if err := startSession(s, user); err != nil {
+		return err
+	}
 
 	fmt.Println("New user registered!")
 	fmt.Printf("UUID: %v\n", params.ID)
 	fmt.Printf("CreatedAt: %v\n", params.CreatedAt)
 	fmt.Printf("UpdatedAt: %v\n", params.UpdatedAt)
 	fmt.Printf("Name: %v\n", params.Name)
+	if params.IsAdmin {
+		println("As the first user, you are an admin")
+		if !passwordHash.Valid {
+			println("Admin commands need a password, set one with: gator passwd")
+		}
+	}
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
-	err := s.db.Reset(context.Background())
+func handlerPasswd(s *state, cmd command, user database.User) error {
+	// sets, changes or removes the logged in user's password
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	remove := fs.Bool("remove", false, "remove the password so the account can be logged into by name")
+	args, err := parseFlags(fs, cmd.Args)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--remove]", cmd.Name)
+	}
+
+	if user.PasswordHash.Valid {
+		current, err := promptPassword("Current password: ")
+		if err != nil {
+			return err
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(current)) != nil {
+			return errors.New("wrong password")
+		}
+	}
+
+	passwordHash := sql.NullString{}
+	if !*remove {
+		password, err := promptNewPassword("New password: ")
+		if err != nil {
+			return err
+		}
+		if password == "" {
+			return fmt.Errorf("empty password, use %v --remove to go without one", cmd.Name)
+		}
+		passwordHash, err = hashPassword(password)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.db.SetUserPassword(context.Background(), database.SetUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: passwordHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	// log out every other session, which may have been started with the old password
+	if err := s.db.DeleteSessionsForUser(context.Background(), user.ID); err != nil {
+		return err
+	}
+	if err := startSession(s, user); err != nil {
+		return err
+	}
+	if *remove {
+		println("Password removed")
+		return nil
+	}
+	println("Password changed, other sessions have been logged out")
+	return nil
+}
+
+func handlerReset(s *state, cmd command, user database.User) error {
+	// deletes every user, and with them every feed, follow and post state
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--yes]", cmd.Name)
+	}
+
+	count, err := s.db.CountUsers(context.Background())
+	if err != nil {
+		return err
+	}
+	err = confirm(*yes, fmt.Sprintf("This deletes all %d users with their feeds, follows and posts. Type reset to continue: ", count), "reset")
+	if err != nil {
+		return err
+	}
+
+	// the audit entry outlives the users, so write it in the same transaction
+	tx, err := s.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.db.WithTx(tx)
+	err = recordAudit(context.Background(), q, user, "reset", "all users", fmt.Sprintf("%d users deleted", count))
+	if err != nil {
+		return err
+	}
+	if err := q.Reset(context.Background()); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// the session went with the users
+	if err := s.cfg.SetSession(""); err != nil {
+		return err
 	}
 	println("users table reset successfully")
 	return nil
 }
 
+func handlerDeleteUser(s *state, cmd command, user database.User) error {
+	// deletes a user along with everything they own
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v {name} [--yes]", cmd.Name)
+	}
+
+	target, err := lookupUser(s, args[0])
+	if err != nil {
+		return err
+	}
+	if target.IsAdmin {
+		admins, err := s.db.CountAdmins(context.Background())
+		if err != nil {
+			return err
+		}
+		if admins == 1 {
+			return fmt.Errorf("%v is the only admin, make someone else an admin first", target.Name)
+		}
+	}
+	err = confirm(*yes, fmt.Sprintf("This deletes %v with their feeds, follows and posts. Type their name to continue: ", target.Name), target.Name)
+	if err != nil {
+		return err
+	}
+
+	// feeds go with the user who added them, so note how many disappear
+	feeds, err := s.db.GetFeeds(context.Background())
+	if err != nil {
+		return err
+	}
+	owned := make([]string, 0)
+	for _, feed := range feeds {
+		if feed.UserID == target.ID {
+			owned = append(owned, feed.Url)
+		}
+	}
+
+	tx, err := s.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.db.WithTx(tx)
+	if err := recordAudit(context.Background(), q, user, "deluser", target.Name, deletedFeedsDetail(owned)); err != nil {
+		return err
+	}
+	if _, err := q.DeleteUser(context.Background(), target.Name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted user %v\n", target.Name)
+	return nil
+}
+
+func deletedFeedsDetail(urls []string) string {
+	switch len(urls) {
+	case 0:
+		return ""
+	case 1:
+		return "deleted feed " + urls[0]
+	}
+	return fmt.Sprintf("deleted %d feeds: %v", len(urls), strings.Join(urls, ", "))
+}
+
+func handlerAdmin(s *state, cmd command, user database.User) error {
+	// grants or revokes admin rights
+	if len(cmd.Args) != 2 || (cmd.Args[0] != "grant" && cmd.Args[0] != "revoke") {
+		return fmt.Errorf("usage: %v grant|revoke {name}", cmd.Name)
+	}
+	grant := cmd.Args[0] == "grant"
+
+	target, err := lookupUser(s, cmd.Args[1])
+	if err != nil {
+		return err
+	}
+	if grant && !target.PasswordHash.Valid {
+		return fmt.Errorf("%v has no password, so anyone could log in as them; they can set one with: gator passwd", target.Name)
+	}
+	if !grant && target.IsAdmin {
+		admins, err := s.db.CountAdmins(context.Background())
+		if err != nil {
+			return err
+		}
+		if admins == 1 {
+			return fmt.Errorf("%v is the only admin and can't be demoted", target.Name)
+		}
+	}
+
+	_, err = s.db.SetUserAdmin(context.Background(), database.SetUserAdminParams{
+		Name:    target.Name,
+		IsAdmin: grant,
+	})
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(context.Background(), s.db, user, "admin "+cmd.Args[0], target.Name, ""); err != nil {
+		return err
+	}
+	if grant {
+		fmt.Printf("%v is now an admin\n", target.Name)
+		return nil
+	}
+	fmt.Printf("%v is no longer an admin\n", target.Name)
+	return nil
+}
+
 func handlerGetUsers(s *state, cmd command) error {
 	users, err := s.db.GetUsers(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
+	current, _ := currentUser(s)
 	println("Registered users:")
 	for i := 0; i < len(users); i++ {
 		username := users[i].Name
-		if username == s.cfg.CurrentUserName {
+		if users[i].IsAdmin {
+			username += " (admin)"
+		}
+		if users[i].Name == current.Name {
 			println(username + " (current)")
 			continue
 		}
@@ -89,3 +354,116 @@ func handlerGetUsers(s *state, cmd command) error {
 	}
 	return nil
 }
+
+// sessionDuration is how long a login lasts before asking for the password again
+const sessionDuration = 30 * 24 * time.Hour
+
+func currentUser(s *state) (database.User, error) {
+	// resolves the session token in the config to the logged in user
+	if s.cfg.SessionToken == "" {
+		return database.User{}, errors.New("not logged in, run: gator login <name>")
+	}
+	user, err := s.db.GetUserBySession(context.Background(), database.GetUserBySessionParams{
+		TokenHash: hashToken(s.cfg.SessionToken),
+		Now:       time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, errors.New("session expired, log in again with: gator login <name>")
+	}
+	return user, err
+}
+
+func startSession(s *state, user database.User) error {
+	// replaces the session in the config with a new one for user
+	if s.cfg.SessionToken != "" {
+		if err := s.db.DeleteSession(context.Background(), hashToken(s.cfg.SessionToken)); err != nil {
+			return err
+		}
+	}
+	token, _, err := newSession(s, user)
+	if err != nil {
+		return err
+	}
+	return s.cfg.SetSession(token)
+}
+
+func newSession(s *state, user database.User) (string, time.Time, error) {
+	// creates a session for user, returning its token and expiry
+	token, err := newToken("")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	session, err := s.db.CreateSession(context.Background(), database.CreateSessionParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().UTC().Add(sessionDuration),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, session.ExpiresAt, nil
+}
+
+// stdin is shared by the prompts so piped input isn't lost between them
+var stdin = bufio.NewReader(os.Stdin)
+
+func promptPassword(prompt string) (string, error) {
+	// reads a password without echoing it, or a plain line when stdin isn't a terminal
+	fmt.Fprint(os.Stderr, prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("couldn't read password: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func promptNewPassword(prompt string) (string, error) {
+	// asks for a password twice so typos don't lock the user out
+	password, err := promptPassword(prompt)
+	if err != nil || password == "" {
+		return password, err
+	}
+	repeated, err := promptPassword("Repeat password: ")
+	if err != nil {
+		return "", err
+	}
+	if repeated != password {
+		return "", errors.New("passwords don't match")
+	}
+	return password, nil
+}
+
+func confirm(yes bool, prompt, expected string) error {
+	// makes the user type expected before a destructive action, unless --yes was passed
+	if yes {
+		return nil
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return errors.New("no confirmation given, pass --yes to skip it")
+	}
+	if strings.TrimSpace(line) != expected {
+		return errors.New("confirmation didn't match, nothing was changed")
+	}
+	return nil
+}
+
+func hashPassword(password string) (sql.NullString, error) {
+	if password == "" {
+		return sql.NullString{}, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+var webhookStatuses = map[string]bool{
+	"pending":   true,
+	"delivered": true,
+	"failed":    true,
+}
+
+func handlerWebhook(s *state, cmd command, user database.User) error {
+	// dispatches the webhook subcommands
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("usage: %v add|list|rm|test|log", cmd.Name)
+	}
+	sub := command{
+		Name: cmd.Name + " " + cmd.Args[0],
+		Args: cmd.Args[1:],
+	}
+	switch cmd.Args[0] {
+	case "add":
+		return webhookAdd(s, sub, user)
+	case "list":
+		return webhookList(s, sub, user)
+	case "rm":
+		return webhookRemove(s, sub, user)
+	case "test":
+		return webhookTest(s, sub, user)
+	case "log":
+		return webhookLog(s, sub, user)
+	}
+	return fmt.Errorf("unknown subcommand %q, usage: %v add|list|rm|test|log", cmd.Args[0], cmd.Name)
+}
+
+func webhookAdd(s *state, cmd command, user database.User) error {
+	// adds a webhook that is sent every new post in its scope
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	feed := fs.String("feed", "", "only send posts from the feed with this name or url")
+	folder := fs.String("folder", "", "only send posts from feeds in this folder")
+	match := fs.String("match", "", "only send posts matching this pattern")
+	field := fs.String("field", "title", "post field --match applies to: title, description, author or tag")
+	regex := fs.Bool("regex", false, "treat --match as a case insensitive regular expression")
+	templateFile := fs.String("template", "", "file with a text/template for the request body, default JSON")
+	contentType := fs.String("content-type", "application/json", "content type of the request body")
+	secret := fs.String("secret", "", "secret to sign requests with, generated if not given")
+	rulesOnly := fs.Bool("rules-only", false, "only send posts picked by rules with the notify action")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: %v {name} {url} [--feed name|url] [--folder name] [--match pattern] [--field title|description|author|tag] [--regex] [--template file] [--content-type type] [--secret s] [--rules-only]", cmd.Name)
+	}
+	if err := validateFeedURL(args[1]); err != nil {
+		return err
+	}
+	if err := checkWebhookURL(context.Background(), args[1]); err != nil {
+		return err
+	}
+	if !filterFields[*field] {
+		return fmt.Errorf("unknown field %q: use title, description, author or tag", *field)
+	}
+	if *rulesOnly && (*feed != "" || *folder != "" || *match != "") {
+		return fmt.Errorf("--rules-only webhooks get their posts from rules, so --feed, --folder and --match don't apply")
+	}
+
+	params := database.CreateWebhookParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		UserID:      user.ID,
+		Name:        args[0],
+		Url:         args[1],
+		Secret:      *secret,
+		Field:       *field,
+		MatchType:   "substring",
+		Pattern:     *match,
+		ContentType: *contentType,
+		RulesOnly:   *rulesOnly,
+	}
+	if *regex {
+		if _, err := regexp.Compile(*match); err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+		params.MatchType = "regex"
+	}
+	if *feed != "" {
+		dbFeed, err := lookupFeed(s, user, *feed)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: dbFeed.ID, Valid: true}
+	}
+	if *folder != "" {
+		dbFolder, err := lookupFolder(s, user, *folder)
+		if err != nil {
+			return err
+		}
+		params.FolderID = uuid.NullUUID{UUID: dbFolder.ID, Valid: true}
+	}
+	if *templateFile != "" {
+		data, err := os.ReadFile(*templateFile)
+		if err != nil {
+			return err
+		}
+		params.Template = string(data)
+		// catch mistakes now rather than on the first post
+		if _, err := renderWebhookPayload(params.Template, sampleWebhookData(args[0])); err != nil {
+			return fmt.Errorf("invalid template: %v", err)
+		}
+	}
+	if params.Secret == "" {
+		params.Secret, err = newToken("")
+		if err != nil {
+			return err
+		}
+	}
+
+	hook, err := s.db.CreateWebhook(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't add webhook %v: %v", args[0], err)
+	}
+	audit(s, user, "webhook add", hook.Url, hook.Name)
+	fmt.Printf("Webhook added: %v\n", hook.Name)
+	if *secret == "" {
+		println("Requests are signed with this secret, in the X-Gator-Signature-256 header:")
+		println(hook.Secret)
+	}
+	return nil
+}
+
+func webhookList(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	hooks, err := s.db.GetWebhooksForUser(context.Background(), user.ID)
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		println("No webhooks")
+		return nil
+	}
+	for _, hook := range hooks {
+		scope := "all feeds"
+		if hook.RulesOnly {
+			scope = "notify rules only"
+		}
+		if hook.FeedName.Valid {
+			scope = hook.FeedName.String
+		}
+		if hook.FolderName.Valid {
+			scope += " in " + hook.FolderName.String
+		}
+		if hook.Pattern != "" {
+			scope += fmt.Sprintf(", if %v %v %q", hook.Field, hook.MatchType, hook.Pattern)
+		}
+		payload := "JSON"
+		if hook.Template != "" {
+			payload = "template"
+		}
+		fmt.Printf("%v: %v (%v, %v %v)\n", hook.Name, hook.Url, scope, payload, hook.ContentType)
+	}
+	return nil
+}
+
+func webhookRemove(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {name}", cmd.Name)
+	}
+
+	// notify rules name their webhook, so they'd have nowhere to send posts
+	rules, err := s.db.GetNotifyRuleNames(context.Background(), database.GetNotifyRuleNamesParams{
+		UserID:  user.ID,
+		Webhook: cmd.Args[0],
+	})
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		return fmt.Errorf("webhook %v is used by notify rules %v, remove them first with: rules rm", cmd.Args[0], strings.Join(rules, ", "))
+	}
+
+	n, err := s.db.DeleteWebhook(context.Background(), database.DeleteWebhookParams{
+		UserID: user.ID,
+		Name:   cmd.Args[0],
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no webhook named %v", cmd.Args[0])
+	}
+	audit(s, user, "webhook rm", cmd.Args[0], "")
+	fmt.Printf("Removed webhook %v\n", cmd.Args[0])
+	return nil
+}
+
+func webhookTest(s *state, cmd command, user database.User) error {
+	// sends a sample post right away, without retries, and logs the delivery
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v {name}", cmd.Name)
+	}
+
+	hook, err := s.db.GetWebhookByName(context.Background(), database.GetWebhookByNameParams{
+		UserID: user.ID,
+		Name:   cmd.Args[0],
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no webhook named %v", cmd.Args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	payload, err := renderWebhookPayload(hook.Template, sampleWebhookData(hook.Name))
+	if err != nil {
+		return err
+	}
+	delivery, err := s.db.CreateWebhookDelivery(context.Background(), database.CreateWebhookDeliveryParams{
+		ID:        uuid.New(),
+		WebhookID: hook.ID,
+		Payload:   payload,
+		// sent below rather than by the queue
+		NextAttemptAt: time.Now().UTC().Add(time.Hour),
+	})
+	if err != nil {
+		return err
+	}
+	code, sendErr := sendWebhook(hook.Url, hook.Secret, hook.ContentType, delivery.ID, payload)
+	if err := recordWebhookAttempt(s, delivery.ID, webhookAttempts, code, sendErr); err != nil {
+		return err
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	fmt.Printf("Delivered a test post to %v (%d)\n", hook.Url, code)
+	return nil
+}
+
+func webhookLog(s *state, cmd command, user database.User) error {
+	// prints recent deliveries, newest first
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	hook := fs.String("webhook", "", "only show deliveries of this webhook")
+	status := fs.String("status", "", "only show deliveries that are pending, delivered or failed")
+	limit := fs.Int("limit", 20, "maximum number of deliveries to show")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--webhook name] [--status pending|delivered|failed] [--limit n]", cmd.Name)
+	}
+	if *limit < 1 {
+		return fmt.Errorf("limit must be at least 1")
+	}
+	if *status != "" && !webhookStatuses[*status] {
+		return fmt.Errorf("unknown status %q: use pending, delivered or failed", *status)
+	}
+
+	params := database.GetWebhookDeliveriesParams{
+		UserID: user.ID,
+		Limit:  int32(*limit),
+	}
+	if *hook != "" {
+		params.Webhook = sql.NullString{String: *hook, Valid: true}
+	}
+	if *status != "" {
+		params.Status = sql.NullString{String: *status, Valid: true}
+	}
+	deliveries, err := s.db.GetWebhookDeliveries(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		println("No deliveries")
+		return nil
+	}
+	for _, d := range deliveries {
+		post := "test post"
+		if d.PostTitle.Valid {
+			post = d.PostTitle.String
+		}
+		line := fmt.Sprintf("%v  %v  %v: %v, %d attempts", d.CreatedAt.Format("2006-01-02 15:04:05"), d.WebhookName, post, d.Status, d.Attempts)
+		if d.ResponseCode.Valid {
+			line += fmt.Sprintf(", last response %d", d.ResponseCode.Int32)
+		}
+		if d.Status == "pending" && d.Attempts > 0 {
+			line += ", next try " + d.NextAttemptAt.Local().Format("15:04:05")
+		}
+		println(line)
+		if d.Error != "" && d.Status != "delivered" {
+			println("  " + d.Error)
+		}
+	}
+	return nil
+}
+
+func sampleWebhookData(webhookName string) webhookData {
+	// a made up post for checking templates and receivers
+	return newWebhookData(webhookName, database.Feed{
+		Name:    "Example Feed",
+		Url:     "https://example.com/feed.xml",
+		SiteUrl: "https://example.com",
+	}, database.Post{
+		ID:          uuid.Nil,
+		CreatedAt:   time.Now(),
+		Title:       "Test post from gator",
+		Url:         "https://example.com/posts/test",
+		Description: "<p>This is a test delivery.</p>",
+		Author:      "gator",
+		Categories:  []string{"test"},
+	})
+}
@@ -0,0 +1,205 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Format identifies gator backup files
+const Format = "gator-backup"
+
+// Version is bumped whenever the archive layout changes incompatibly
+const Version = 1
+
+// Archive is a full copy of a gator database. Rows reference each other by
+// natural keys (user names, feed and post urls) rather than UUIDs, so an
+// archive can be merged into a database that already has data.
+type Archive struct {
+	Format        string        `json:"format"`
+	Version       int           `json:"version"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Users         []User        `json:"users"`
+	Feeds         []Feed        `json:"feeds"`
+	Folders       []Folder      `json:"folders"`
+	Follows       []Follow      `json:"follows"`
+	Posts         []Post        `json:"posts"`
+	PostStates    []PostState   `json:"post_states"`
+	PostTags      []PostTag     `json:"post_tags"`
+	SavedSearches []SavedSearch `json:"saved_searches"`
+	Filters       []Filter      `json:"filters"`
+	Rules         []Rule        `json:"rules"`
+	APITokens     []APIToken    `json:"api_tokens"`
+	Webhooks      []Webhook     `json:"webhooks"`
+	Digests       []Digest      `json:"digests"`
+	DigestPosts   []DigestPost  `json:"digest_posts"`
+	AuditLog      []AuditEntry  `json:"audit_log"`
+}
+
+type User struct {
+	Name string `json:"name"`
+	// PasswordHash is the bcrypt hash of the user's password, if they set one
+	PasswordHash string    `json:"password_hash,omitempty"`
+	IsAdmin      bool      `json:"is_admin,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Feed struct {
+	URL       string    `json:"url"`
+	Name      string    `json:"name"`
+	SiteURL   string    `json:"site_url,omitempty"`
+	Owner     string    `json:"owner"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Folder struct {
+	User      string    `json:"user"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Follow struct {
+	User      string    `json:"user"`
+	FeedURL   string    `json:"feed_url"`
+	Folder    string    `json:"folder,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Post struct {
+	URL         string     `json:"url"`
+	FeedURL     string     `json:"feed_url"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Content     string     `json:"content,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type PostState struct {
+	User      string     `json:"user"`
+	PostURL   string     `json:"post_url"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	StarredAt *time.Time `json:"starred_at,omitempty"`
+}
+
+type PostTag struct {
+	User    string `json:"user"`
+	PostURL string `json:"post_url"`
+	Tag     string `json:"tag"`
+}
+
+type SavedSearch struct {
+	User  string `json:"user"`
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+type Filter struct {
+	User      string `json:"user"`
+	FeedURL   string `json:"feed_url,omitempty"`
+	Field     string `json:"field"`
+	MatchType string `json:"match_type"`
+	Pattern   string `json:"pattern"`
+}
+
+type Rule struct {
+	User      string `json:"user"`
+	Name      string `json:"name"`
+	FeedURL   string `json:"feed_url,omitempty"`
+	Field     string `json:"field"`
+	MatchType string `json:"match_type"`
+	Pattern   string `json:"pattern"`
+	Action    string `json:"action"`
+	ActionArg string `json:"action_arg,omitempty"`
+}
+
+// APIToken is stored as the hash of the token, so the token itself keeps
+// working after a restore without the archive ever holding it
+type APIToken struct {
+	User       string     `json:"user"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"token_hash"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type Webhook struct {
+	User        string    `json:"user"`
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret"`
+	FeedURL     string    `json:"feed_url,omitempty"`
+	Folder      string    `json:"folder,omitempty"`
+	Field       string    `json:"field"`
+	MatchType   string    `json:"match_type"`
+	Pattern     string    `json:"pattern,omitempty"`
+	Template    string    `json:"template,omitempty"`
+	ContentType string    `json:"content_type"`
+	RulesOnly   bool      `json:"rules_only,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Digest struct {
+	User     string `json:"user"`
+	Email    string `json:"email"`
+	Schedule string `json:"schedule"`
+	Hour     int    `json:"hour"`
+	// Weekday is the day weekly digests go out on, 0 for Sunday
+	Weekday    int        `json:"weekday"`
+	Timezone   string     `json:"timezone"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DigestPost records a post a user was already sent in a digest
+type DigestPost struct {
+	User        string    `json:"user"`
+	PostURL     string    `json:"post_url"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+type AuditEntry struct {
+	// Actor is the name of the user at the time, who may since be deleted
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// New returns an empty archive stamped with the current format and version
+func New() *Archive {
+	return &Archive{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Write encodes the archive as indented JSON
+func (a *Archive) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(a)
+}
+
+// Read decodes an archive, rejecting files from other tools or newer versions
+func Read(r io.Reader) (*Archive, error) {
+	var a Archive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, fmt.Errorf("invalid backup: %v", err)
+	}
+	if a.Format != Format {
+		return nil, fmt.Errorf("not a gator backup")
+	}
+	if a.Version < 1 || a.Version > Version {
+		return nil, fmt.Errorf("unsupported backup version %d, this gator reads up to version %d", a.Version, Version)
+	}
+	return &a, nil
+}
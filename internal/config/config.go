@@ -8,8 +8,24 @@ import (
 // Export a Config struct that represents the JSON file structure including struct tags
 
 type Config struct {
-	DBURL           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DBURL string `json:"db_url"`
+	// SessionToken identifies the logged in user's session, see the sessions table
+	SessionToken string `json:"session_token,omitempty"`
+	// SMTP is the mail server agg and serve send digests through
+	SMTP *SMTPConfig `json:"smtp,omitempty"`
+}
+
+// SMTPConfig describes how to reach a mail server
+type SMTPConfig struct {
+	Host string `json:"host"`
+	// Port defaults to 587, or 465 with TLS
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// From is the sender address, e.g. "gator <gator@example.com>"
+	From string `json:"from"`
+	// TLS connects over TLS from the start instead of upgrading with STARTTLS
+	TLS bool `json:"tls,omitempty"`
 }
 
 // Export a Read function that reads the JSON file at ~/.gatorconfig.json and returns a Config struct -
@@ -38,13 +54,12 @@ func Read() (Config, error) {
 // it should read the file from the HOME directory, the decode the JSON string into a new Config struct -
 // use os.UserHomeDir()
 
-// Export a SetUser method on the Config struct that writes the config struct to the JSON file after setting the current_user_name field
-func (cfg *Config) SetUser(username string) error {
-	// set the CurrentUserName field of the Config struct
-	cfg.CurrentUserName = username
+// Export a SetSession method on the Config struct that writes the config struct to the JSON file after setting the session_token field
+func (cfg *Config) SetSession(token string) error {
+	// set the SessionToken field of the Config struct
+	cfg.SessionToken = token
 	// write the Config struct to the JSON file
-	write(*cfg)
-	return nil
+	return write(*cfg)
 }
 
 func write(cfg Config) error {
@@ -53,12 +68,15 @@ func write(cfg Config) error {
 	if err != nil {
 		return err
 	}
-	// create the json file
-	file, err := os.Create(fullpath)
+	// create the json file, readable only by its owner since it holds the session token
+	file, err := os.OpenFile(fullpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
+	if err := file.Chmod(0o600); err != nil {
+		return err
+	}
 	// encode the json data
 	encoder := json.NewEncoder(file)
 	err = encoder.Encode(cfg)
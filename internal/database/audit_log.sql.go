@@ -0,0 +1,102 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: audit_log.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createAuditLogEntry = `-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (id, created_at, actor_id, actor_name, action, target, details)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+`
+
+type CreateAuditLogEntryParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ActorID   uuid.NullUUID
+	ActorName string
+	Action    string
+	Target    string
+	Details   string
+}
+
+func (q *Queries) CreateAuditLogEntry(ctx context.Context, arg CreateAuditLogEntryParams) error {
+	_, err := q.db.ExecContext(ctx, createAuditLogEntry,
+		arg.ID,
+		arg.CreatedAt,
+		arg.ActorID,
+		arg.ActorName,
+		arg.Action,
+		arg.Target,
+		arg.Details,
+	)
+	return err
+}
+
+const getAuditLog = `-- name: GetAuditLog :many
+SELECT id, created_at, actor_id, actor_name, action, target, details FROM audit_log
+WHERE ($1::text IS NULL OR actor_name = $1)
+    AND ($2::timestamp IS NULL OR created_at >= $2)
+    AND ($3::text IS NULL OR action = $3)
+ORDER BY created_at DESC
+LIMIT $4
+`
+
+type GetAuditLogParams struct {
+	Actor  sql.NullString
+	Since  sql.NullTime
+	Action sql.NullString
+	Limit  int32
+}
+
+func (q *Queries) GetAuditLog(ctx context.Context, arg GetAuditLogParams) ([]AuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, getAuditLog,
+		arg.Actor,
+		arg.Since,
+		arg.Action,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ActorID,
+			&i.ActorName,
+			&i.Action,
+			&i.Target,
+			&i.Details,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,1025 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: backup.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getAllAPITokens = `-- name: GetAllAPITokens :many
+SELECT api_tokens.id, api_tokens.created_at, api_tokens.user_id, api_tokens.name, api_tokens.token_hash, api_tokens.scopes, api_tokens.expires_at, api_tokens.last_used_at, users.name AS user_name
+FROM api_tokens
+JOIN users ON api_tokens.user_id = users.id
+ORDER BY users.name, api_tokens.name
+`
+
+type GetAllAPITokensRow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	UserName   string
+}
+
+func (q *Queries) GetAllAPITokens(ctx context.Context) ([]GetAllAPITokensRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllAPITokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllAPITokensRow
+	for rows.Next() {
+		var i GetAllAPITokensRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.TokenHash,
+			pq.Array(&i.Scopes),
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllAuditLog = `-- name: GetAllAuditLog :many
+SELECT id, created_at, actor_id, actor_name, action, target, details FROM audit_log
+ORDER BY created_at, id
+`
+
+func (q *Queries) GetAllAuditLog(ctx context.Context) ([]AuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, getAllAuditLog)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ActorID,
+			&i.ActorName,
+			&i.Action,
+			&i.Target,
+			&i.Details,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllDigestPosts = `-- name: GetAllDigestPosts :many
+SELECT digest_posts.user_id, digest_posts.post_id, digest_posts.delivered_at, users.name AS user_name, posts.url AS post_url
+FROM digest_posts
+JOIN users ON digest_posts.user_id = users.id
+JOIN posts ON digest_posts.post_id = posts.id
+ORDER BY users.name, posts.url
+`
+
+type GetAllDigestPostsRow struct {
+	UserID      uuid.UUID
+	PostID      uuid.UUID
+	DeliveredAt time.Time
+	UserName    string
+	PostUrl     string
+}
+
+func (q *Queries) GetAllDigestPosts(ctx context.Context) ([]GetAllDigestPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllDigestPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllDigestPostsRow
+	for rows.Next() {
+		var i GetAllDigestPostsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.PostID,
+			&i.DeliveredAt,
+			&i.UserName,
+			&i.PostUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllDigests = `-- name: GetAllDigests :many
+SELECT digests.user_id, digests.created_at, digests.updated_at, digests.email, digests.schedule, digests.hour, digests.weekday, digests.timezone, digests.next_send_at, digests.last_sent_at, digests.last_error, users.name AS user_name
+FROM digests
+JOIN users ON digests.user_id = users.id
+ORDER BY users.name
+`
+
+type GetAllDigestsRow struct {
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Email      string
+	Schedule   string
+	Hour       int32
+	Weekday    int32
+	Timezone   string
+	NextSendAt time.Time
+	LastSentAt sql.NullTime
+	LastError  string
+	UserName   string
+}
+
+func (q *Queries) GetAllDigests(ctx context.Context) ([]GetAllDigestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllDigests)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllDigestsRow
+	for rows.Next() {
+		var i GetAllDigestsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.Schedule,
+			&i.Hour,
+			&i.Weekday,
+			&i.Timezone,
+			&i.NextSendAt,
+			&i.LastSentAt,
+			&i.LastError,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllFeedFollows = `-- name: GetAllFeedFollows :many
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, users.name AS user_name, feeds.url AS feed_url, folders.name AS folder_name
+FROM feed_follows
+JOIN users ON feed_follows.user_id = users.id
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+ORDER BY users.name, feeds.url
+`
+
+type GetAllFeedFollowsRow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	FeedID     uuid.UUID
+	FolderID   uuid.NullUUID
+	Title      sql.NullString
+	UserName   string
+	FeedUrl    string
+	FolderName sql.NullString
+}
+
+func (q *Queries) GetAllFeedFollows(ctx context.Context) ([]GetAllFeedFollowsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeedFollows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllFeedFollowsRow
+	for rows.Next() {
+		var i GetAllFeedFollowsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
+			&i.UserName,
+			&i.FeedUrl,
+			&i.FolderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllFilterRules = `-- name: GetAllFilterRules :many
+SELECT filter_rules.id, filter_rules.created_at, filter_rules.updated_at, filter_rules.user_id, filter_rules.feed_id, filter_rules.field, filter_rules.match_type, filter_rules.pattern, users.name AS user_name, feeds.url AS feed_url
+FROM filter_rules
+JOIN users ON filter_rules.user_id = users.id
+LEFT JOIN feeds ON filter_rules.feed_id = feeds.id
+ORDER BY users.name, filter_rules.created_at
+`
+
+type GetAllFilterRulesRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	UserName  string
+	FeedUrl   sql.NullString
+}
+
+func (q *Queries) GetAllFilterRules(ctx context.Context) ([]GetAllFilterRulesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFilterRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllFilterRulesRow
+	for rows.Next() {
+		var i GetAllFilterRulesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.UserName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllFolders = `-- name: GetAllFolders :many
+SELECT folders.id, folders.created_at, folders.updated_at, folders.user_id, folders.name, folders.serial_id, users.name AS user_name
+FROM folders
+JOIN users ON folders.user_id = users.id
+ORDER BY users.name, folders.name
+`
+
+type GetAllFoldersRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	SerialID  int64
+	UserName  string
+}
+
+func (q *Queries) GetAllFolders(ctx context.Context) ([]GetAllFoldersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFolders)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllFoldersRow
+	for rows.Next() {
+		var i GetAllFoldersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.SerialID,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllPostStates = `-- name: GetAllPostStates :many
+SELECT post_states.user_id, post_states.post_id, post_states.created_at, post_states.updated_at, post_states.read_at, post_states.starred_at, users.name AS user_name, posts.url AS post_url
+FROM post_states
+JOIN users ON post_states.user_id = users.id
+JOIN posts ON post_states.post_id = posts.id
+ORDER BY users.name, posts.url
+`
+
+type GetAllPostStatesRow struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+	UserName  string
+	PostUrl   string
+}
+
+func (q *Queries) GetAllPostStates(ctx context.Context) ([]GetAllPostStatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPostStates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllPostStatesRow
+	for rows.Next() {
+		var i GetAllPostStatesRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.PostID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReadAt,
+			&i.StarredAt,
+			&i.UserName,
+			&i.PostUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllPostTags = `-- name: GetAllPostTags :many
+SELECT post_tags.user_id, post_tags.post_id, post_tags.tag, post_tags.created_at, users.name AS user_name, posts.url AS post_url
+FROM post_tags
+JOIN users ON post_tags.user_id = users.id
+JOIN posts ON post_tags.post_id = posts.id
+ORDER BY users.name, posts.url, post_tags.tag
+`
+
+type GetAllPostTagsRow struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+	UserName  string
+	PostUrl   string
+}
+
+func (q *Queries) GetAllPostTags(ctx context.Context) ([]GetAllPostTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPostTags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllPostTagsRow
+	for rows.Next() {
+		var i GetAllPostTagsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.PostID,
+			&i.Tag,
+			&i.CreatedAt,
+			&i.UserName,
+			&i.PostUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllPosts = `-- name: GetAllPosts :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.search, posts.categories, posts.serial_id, feeds.url AS feed_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+ORDER BY posts.created_at
+`
+
+type GetAllPostsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description string
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      string
+	Content     string
+	Search      string
+	Categories  []string
+	SerialID    int64
+	FeedUrl     string
+}
+
+func (q *Queries) GetAllPosts(ctx context.Context) ([]GetAllPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllPostsRow
+	for rows.Next() {
+		var i GetAllPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Search,
+			pq.Array(&i.Categories),
+			&i.SerialID,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllRules = `-- name: GetAllRules :many
+SELECT rules.id, rules.created_at, rules.updated_at, rules.user_id, rules.name, rules.feed_id, rules.field, rules.match_type, rules.pattern, rules.action, rules.action_arg, users.name AS user_name, feeds.url AS feed_url
+FROM rules
+JOIN users ON rules.user_id = users.id
+LEFT JOIN feeds ON rules.feed_id = feeds.id
+ORDER BY users.name, rules.name
+`
+
+type GetAllRulesRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	ActionArg string
+	UserName  string
+	FeedUrl   sql.NullString
+}
+
+func (q *Queries) GetAllRules(ctx context.Context) ([]GetAllRulesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllRulesRow
+	for rows.Next() {
+		var i GetAllRulesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.FeedID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Action,
+			&i.ActionArg,
+			&i.UserName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllSavedSearches = `-- name: GetAllSavedSearches :many
+SELECT saved_searches.id, saved_searches.created_at, saved_searches.updated_at, saved_searches.user_id, saved_searches.name, saved_searches.query, users.name AS user_name
+FROM saved_searches
+JOIN users ON saved_searches.user_id = users.id
+ORDER BY users.name, saved_searches.name
+`
+
+type GetAllSavedSearchesRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	Query     string
+	UserName  string
+}
+
+func (q *Queries) GetAllSavedSearches(ctx context.Context) ([]GetAllSavedSearchesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllSavedSearches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllSavedSearchesRow
+	for rows.Next() {
+		var i GetAllSavedSearchesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.Query,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllWebhooks = `-- name: GetAllWebhooks :many
+SELECT webhooks.id, webhooks.created_at, webhooks.updated_at, webhooks.user_id, webhooks.name, webhooks.url, webhooks.secret, webhooks.feed_id, webhooks.folder_id, webhooks.field, webhooks.match_type, webhooks.pattern, webhooks.template, webhooks.content_type, webhooks.rules_only, users.name AS user_name, feeds.url AS feed_url, folders.name AS folder_name
+FROM webhooks
+JOIN users ON webhooks.user_id = users.id
+LEFT JOIN feeds ON webhooks.feed_id = feeds.id
+LEFT JOIN folders ON webhooks.folder_id = folders.id
+ORDER BY users.name, webhooks.name
+`
+
+type GetAllWebhooksRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	Url         string
+	Secret      string
+	FeedID      uuid.NullUUID
+	FolderID    uuid.NullUUID
+	Field       string
+	MatchType   string
+	Pattern     string
+	Template    string
+	ContentType string
+	RulesOnly   bool
+	UserName    string
+	FeedUrl     sql.NullString
+	FolderName  sql.NullString
+}
+
+func (q *Queries) GetAllWebhooks(ctx context.Context) ([]GetAllWebhooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllWebhooks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllWebhooksRow
+	for rows.Next() {
+		var i GetAllWebhooksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.Url,
+			&i.Secret,
+			&i.FeedID,
+			&i.FolderID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Template,
+			&i.ContentType,
+			&i.RulesOnly,
+			&i.UserName,
+			&i.FeedUrl,
+			&i.FolderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreAPIToken = `-- name: RestoreAPIToken :execrows
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, scopes, expires_at, last_used_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT DO NOTHING
+`
+
+type RestoreAPITokenParams struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+}
+
+func (q *Queries) RestoreAPIToken(ctx context.Context, arg RestoreAPITokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreAPIToken,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.Name,
+		arg.TokenHash,
+		pq.Array(arg.Scopes),
+		arg.ExpiresAt,
+		arg.LastUsedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreAuditLogEntry = `-- name: RestoreAuditLogEntry :execrows
+INSERT INTO audit_log (id, created_at, actor_id, actor_name, action, target, details)
+SELECT $1, $2, $3, $4, $5, $6, $7
+WHERE NOT EXISTS (
+    SELECT 1 FROM audit_log
+    WHERE created_at = $2
+        AND actor_name = $4
+        AND action = $5
+        AND target = $6
+        AND details = $7
+)
+`
+
+type RestoreAuditLogEntryParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ActorID   uuid.NullUUID
+	ActorName string
+	Action    string
+	Target    string
+	Details   string
+}
+
+// the log has no natural key, so an entry counts as present when one
+// matching it in everything but the id exists
+func (q *Queries) RestoreAuditLogEntry(ctx context.Context, arg RestoreAuditLogEntryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreAuditLogEntry,
+		arg.ID,
+		arg.CreatedAt,
+		arg.ActorID,
+		arg.ActorName,
+		arg.Action,
+		arg.Target,
+		arg.Details,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreDigest = `-- name: RestoreDigest :execrows
+INSERT INTO digests (user_id, created_at, updated_at, email, schedule, hour, weekday, timezone, next_send_at, last_sent_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (user_id) DO NOTHING
+`
+
+type RestoreDigestParams struct {
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Email      string
+	Schedule   string
+	Hour       int32
+	Weekday    int32
+	Timezone   string
+	NextSendAt time.Time
+	LastSentAt sql.NullTime
+}
+
+func (q *Queries) RestoreDigest(ctx context.Context, arg RestoreDigestParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreDigest,
+		arg.UserID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Email,
+		arg.Schedule,
+		arg.Hour,
+		arg.Weekday,
+		arg.Timezone,
+		arg.NextSendAt,
+		arg.LastSentAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreDigestPost = `-- name: RestoreDigestPost :exec
+INSERT INTO digest_posts (user_id, post_id, delivered_at)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`
+
+type RestoreDigestPostParams struct {
+	UserID      uuid.UUID
+	PostID      uuid.UUID
+	DeliveredAt time.Time
+}
+
+func (q *Queries) RestoreDigestPost(ctx context.Context, arg RestoreDigestPostParams) error {
+	_, err := q.db.ExecContext(ctx, restoreDigestPost, arg.UserID, arg.PostID, arg.DeliveredAt)
+	return err
+}
+
+const restoreFilterRule = `-- name: RestoreFilterRule :execrows
+INSERT INTO filter_rules (id, created_at, updated_at, user_id, feed_id, field, match_type, pattern)
+SELECT $1, $2, $3, $4, $5, $6, $7, $8
+WHERE NOT EXISTS (
+    SELECT 1 FROM filter_rules
+    WHERE user_id = $4
+        AND feed_id IS NOT DISTINCT FROM $5
+        AND field = $6
+        AND match_type = $7
+        AND pattern = $8
+)
+`
+
+type RestoreFilterRuleParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+}
+
+func (q *Queries) RestoreFilterRule(ctx context.Context, arg RestoreFilterRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreFilterRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Field,
+		arg.MatchType,
+		arg.Pattern,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restorePostState = `-- name: RestorePostState :exec
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, read_at, starred_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at),
+    starred_at = COALESCE(post_states.starred_at, EXCLUDED.starred_at),
+    updated_at = NOW()
+`
+
+type RestorePostStateParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+}
+
+func (q *Queries) RestorePostState(ctx context.Context, arg RestorePostStateParams) error {
+	_, err := q.db.ExecContext(ctx, restorePostState,
+		arg.UserID,
+		arg.PostID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.ReadAt,
+		arg.StarredAt,
+	)
+	return err
+}
+
+const restoreRule = `-- name: RestoreRule :execrows
+INSERT INTO rules (id, created_at, updated_at, user_id, name, feed_id, field, match_type, pattern, action, action_arg)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (user_id, name) DO NOTHING
+`
+
+type RestoreRuleParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	ActionArg string
+}
+
+func (q *Queries) RestoreRule(ctx context.Context, arg RestoreRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Name,
+		arg.FeedID,
+		arg.Field,
+		arg.MatchType,
+		arg.Pattern,
+		arg.Action,
+		arg.ActionArg,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreSavedSearch = `-- name: RestoreSavedSearch :execrows
+INSERT INTO saved_searches (id, created_at, updated_at, user_id, name, query)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, name) DO NOTHING
+`
+
+type RestoreSavedSearchParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	Query     string
+}
+
+func (q *Queries) RestoreSavedSearch(ctx context.Context, arg RestoreSavedSearchParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreSavedSearch,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Name,
+		arg.Query,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreWebhook = `-- name: RestoreWebhook :execrows
+INSERT INTO webhooks (id, created_at, updated_at, user_id, name, url, secret, feed_id, folder_id, field, match_type, pattern, template, content_type, rules_only)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+ON CONFLICT (user_id, name) DO NOTHING
+`
+
+type RestoreWebhookParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	Url         string
+	Secret      string
+	FeedID      uuid.NullUUID
+	FolderID    uuid.NullUUID
+	Field       string
+	MatchType   string
+	Pattern     string
+	Template    string
+	ContentType string
+	RulesOnly   bool
+}
+
+func (q *Queries) RestoreWebhook(ctx context.Context, arg RestoreWebhookParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreWebhook,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Name,
+		arg.Url,
+		arg.Secret,
+		arg.FeedID,
+		arg.FolderID,
+		arg.Field,
+		arg.MatchType,
+		arg.Pattern,
+		arg.Template,
+		arg.ContentType,
+		arg.RulesOnly,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,287 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: digests.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const claimDueDigests = `-- name: ClaimDueDigests :many
+WITH due AS (
+    SELECT digests.user_id
+    FROM digests
+    WHERE digests.next_send_at <= $2::timestamp
+    ORDER BY digests.next_send_at
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE digests
+SET next_send_at = $1::timestamp,
+    updated_at = NOW()
+FROM due
+WHERE digests.user_id = due.user_id
+RETURNING digests.user_id, digests.created_at, digests.updated_at, digests.email, digests.schedule, digests.hour, digests.weekday, digests.timezone, digests.next_send_at, digests.last_sent_at, digests.last_error
+`
+
+type ClaimDueDigestsParams struct {
+	LeaseUntil time.Time
+	Now        time.Time
+	Limit      int32
+}
+
+// pushes the next send of due digests back by lease_until, so another
+// process sending at the same time skips them
+func (q *Queries) ClaimDueDigests(ctx context.Context, arg ClaimDueDigestsParams) ([]Digest, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueDigests, arg.LeaseUntil, arg.Now, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Digest
+	for rows.Next() {
+		var i Digest
+		if err := rows.Scan(
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.Schedule,
+			&i.Hour,
+			&i.Weekday,
+			&i.Timezone,
+			&i.NextSendAt,
+			&i.LastSentAt,
+			&i.LastError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteDigest = `-- name: DeleteDigest :execrows
+DELETE FROM digests WHERE user_id = $1
+`
+
+func (q *Queries) DeleteDigest(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDigest, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDigest = `-- name: GetDigest :one
+SELECT user_id, created_at, updated_at, email, schedule, hour, weekday, timezone, next_send_at, last_sent_at, last_error FROM digests WHERE user_id = $1
+`
+
+func (q *Queries) GetDigest(ctx context.Context, userID uuid.UUID) (Digest, error) {
+	row := q.db.QueryRowContext(ctx, getDigest, userID)
+	var i Digest
+	err := row.Scan(
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Schedule,
+		&i.Hour,
+		&i.Weekday,
+		&i.Timezone,
+		&i.NextSendAt,
+		&i.LastSentAt,
+		&i.LastError,
+	)
+	return i, err
+}
+
+const getDigestPosts = `-- name: GetDigestPosts :many
+SELECT posts.id, posts.title, posts.url, posts.author, posts.description, posts.published_at, posts.created_at,
+    COALESCE(feed_follows.title, feeds.name)::text AS feed_name, feeds.url AS feed_url, feeds.site_url AS feed_site_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.read_at IS NULL
+    AND posts.created_at >= $2::timestamp
+    AND NOT EXISTS (
+        SELECT 1 FROM digest_posts
+        WHERE digest_posts.user_id = feed_follows.user_id AND digest_posts.post_id = posts.id
+    )
+    AND NOT post_is_filtered($1, posts.id)
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
+LIMIT $3
+`
+
+type GetDigestPostsParams struct {
+	UserID uuid.UUID
+	Since  time.Time
+	Limit  int32
+}
+
+type GetDigestPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Author      string
+	Description string
+	PublishedAt sql.NullTime
+	CreatedAt   time.Time
+	FeedName    string
+	FeedUrl     string
+	FeedSiteUrl string
+}
+
+// unread posts from followed feeds that no digest has included yet, newest first
+func (q *Queries) GetDigestPosts(ctx context.Context, arg GetDigestPostsParams) ([]GetDigestPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDigestPosts, arg.UserID, arg.Since, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDigestPostsRow
+	for rows.Next() {
+		var i GetDigestPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Author,
+			&i.Description,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPostsDelivered = `-- name: MarkPostsDelivered :exec
+INSERT INTO digest_posts (user_id, post_id, delivered_at)
+SELECT $1, unnest($2::uuid[]), NOW()
+ON CONFLICT DO NOTHING
+`
+
+type MarkPostsDeliveredParams struct {
+	UserID  uuid.UUID
+	PostIds []uuid.UUID
+}
+
+func (q *Queries) MarkPostsDelivered(ctx context.Context, arg MarkPostsDeliveredParams) error {
+	_, err := q.db.ExecContext(ctx, markPostsDelivered, arg.UserID, pq.Array(arg.PostIds))
+	return err
+}
+
+const scheduleDigest = `-- name: ScheduleDigest :exec
+UPDATE digests
+SET next_send_at = $1,
+    last_sent_at = COALESCE($2, last_sent_at),
+    last_error = $3,
+    updated_at = NOW()
+WHERE user_id = $4
+`
+
+type ScheduleDigestParams struct {
+	NextSendAt time.Time
+	SentAt     sql.NullTime
+	Error      string
+	UserID     uuid.UUID
+}
+
+func (q *Queries) ScheduleDigest(ctx context.Context, arg ScheduleDigestParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleDigest,
+		arg.NextSendAt,
+		arg.SentAt,
+		arg.Error,
+		arg.UserID,
+	)
+	return err
+}
+
+const upsertDigest = `-- name: UpsertDigest :one
+INSERT INTO digests (user_id, created_at, updated_at, email, schedule, hour, weekday, timezone, next_send_at)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (user_id) DO UPDATE
+SET email = EXCLUDED.email,
+    schedule = EXCLUDED.schedule,
+    hour = EXCLUDED.hour,
+    weekday = EXCLUDED.weekday,
+    timezone = EXCLUDED.timezone,
+    next_send_at = EXCLUDED.next_send_at,
+    last_error = '',
+    updated_at = NOW()
+RETURNING user_id, created_at, updated_at, email, schedule, hour, weekday, timezone, next_send_at, last_sent_at, last_error
+`
+
+type UpsertDigestParams struct {
+	UserID     uuid.UUID
+	Email      string
+	Schedule   string
+	Hour       int32
+	Weekday    int32
+	Timezone   string
+	NextSendAt time.Time
+}
+
+func (q *Queries) UpsertDigest(ctx context.Context, arg UpsertDigestParams) (Digest, error) {
+	row := q.db.QueryRowContext(ctx, upsertDigest,
+		arg.UserID,
+		arg.Email,
+		arg.Schedule,
+		arg.Hour,
+		arg.Weekday,
+		arg.Timezone,
+		arg.NextSendAt,
+	)
+	var i Digest
+	err := row.Scan(
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.Schedule,
+		&i.Hour,
+		&i.Weekday,
+		&i.Timezone,
+		&i.NextSendAt,
+		&i.LastSentAt,
+		&i.LastError,
+	)
+	return i, err
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,7 +23,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, last_fetched_at, name, url, user_id
+RETURNING id, created_at, updated_at, last_fetched_at, name, url, user_id, site_url, serial_id, hub_url, self_url
 `
 
 type CreateFeedParams struct {
@@ -52,18 +53,22 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.SiteUrl,
+		&i.SerialID,
+		&i.HubUrl,
+		&i.SelfUrl,
 	)
 	return i, err
 }
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id)
-    VALUES($1, $2, $3, $4, $5)
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id, folder_id)
+    VALUES($1, $2, $3, $4, $5, $6)
+    RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title
 )
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.folder_id, inserted_feed_follow.title,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -77,6 +82,7 @@ type CreateFeedFollowParams struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
 }
 
 type CreateFeedFollowRow struct {
@@ -85,6 +91,8 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
 	FeedName  string
 	UserName  string
 }
@@ -96,6 +104,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.FeedID,
+		arg.FolderID,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -104,6 +113,8 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -111,7 +122,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 }
 
 const getFeedByID = `-- name: GetFeedByID :one
-SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id FROM feeds WHERE id = $1
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, site_url, serial_id, hub_url, self_url FROM feeds WHERE id = $1
 `
 
 func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
@@ -125,12 +136,49 @@ func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.SiteUrl,
+		&i.SerialID,
+		&i.HubUrl,
+		&i.SelfUrl,
+	)
+	return i, err
+}
+
+const getFeedByNameOrUrl = `-- name: GetFeedByNameOrUrl :one
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.last_fetched_at, feeds.name, feeds.url, feeds.user_id, feeds.site_url, feeds.serial_id, feeds.hub_url, feeds.self_url FROM feeds
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = $1
+WHERE feeds.url = $2 OR feed_follows.title = $2 OR feeds.name = $2
+ORDER BY feeds.url = $2 DESC, COALESCE(feed_follows.title = $2, false) DESC, feeds.created_at
+LIMIT 1
+`
+
+type GetFeedByNameOrUrlParams struct {
+	UserID uuid.UUID
+	Ref    string
+}
+
+// prefers the url, then the title the user gave the feed, then its name
+func (q *Queries) GetFeedByNameOrUrl(ctx context.Context, arg GetFeedByNameOrUrlParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByNameOrUrl, arg.UserID, arg.Ref)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastFetchedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.SiteUrl,
+		&i.SerialID,
+		&i.HubUrl,
+		&i.SelfUrl,
 	)
 	return i, err
 }
 
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, site_url, serial_id, hub_url, self_url FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
@@ -144,30 +192,73 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.SiteUrl,
+		&i.SerialID,
+		&i.HubUrl,
+		&i.SelfUrl,
+	)
+	return i, err
+}
+
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id, folder_id, title FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Title,
 	)
 	return i, err
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feeds.name AS feed_name, users.name AS user_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder_id, feed_follows.title, COALESCE(feed_follows.title, feeds.name)::text AS feed_name, users.name AS user_name, folders.name AS folder_name,
+    feeds.url AS feed_url, feeds.site_url AS feed_site_url
 FROM feed_follows
 JOIN feeds ON feed_follows.feed_id = feeds.id
 JOIN users ON feed_follows.user_id = users.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
 WHERE feed_follows.user_id = $1
+    AND ($2::text IS NULL OR folders.name = $2)
+ORDER BY folders.name NULLS FIRST, COALESCE(feed_follows.title, feeds.name)
 `
 
+type GetFeedFollowsForUserParams struct {
+	UserID uuid.UUID
+	Folder sql.NullString
+}
+
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	FolderID    uuid.NullUUID
+	Title       sql.NullString
+	FeedName    string
+	UserName    string
+	FolderName  sql.NullString
+	FeedUrl     string
+	FeedSiteUrl string
 }
 
-func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, arg GetFeedFollowsForUserParams) ([]GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, arg.UserID, arg.Folder)
 	if err != nil {
 		return nil, err
 	}
@@ -181,8 +272,13 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.FolderID,
+			&i.Title,
 			&i.FeedName,
 			&i.UserName,
+			&i.FolderName,
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -198,7 +294,7 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id FROM feeds
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, site_url, serial_id, hub_url, self_url FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -218,6 +314,10 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Name,
 			&i.Url,
 			&i.UserID,
+			&i.SiteUrl,
+			&i.SerialID,
+			&i.HubUrl,
+			&i.SelfUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -233,7 +333,7 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, site_url, serial_id, hub_url, self_url
 FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1
@@ -250,23 +350,85 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.SiteUrl,
+		&i.SerialID,
+		&i.HubUrl,
+		&i.SelfUrl,
 	)
 	return i, err
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds 
-SET last_fetched_at = NOW(),
+SET last_fetched_at = NOW() AT TIME ZONE 'UTC',
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, last_fetched_at, name, url, user_id
+RETURNING id, created_at, updated_at, last_fetched_at, name, url, user_id, site_url, serial_id, hub_url, self_url
 `
 
+// last_fetched_at is in UTC like post times, whatever the server's time zone
 func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
 
+const setFeedFollowTitle = `-- name: SetFeedFollowTitle :execrows
+UPDATE feed_follows
+SET title = $3,
+    updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowTitleParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+	Title  sql.NullString
+}
+
+func (q *Queries) SetFeedFollowTitle(ctx context.Context, arg SetFeedFollowTitleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowTitle, arg.UserID, arg.FeedID, arg.Title)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedHub = `-- name: SetFeedHub :exec
+UPDATE feeds
+SET hub_url = $2,
+    self_url = $3,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedHubParams struct {
+	ID      uuid.UUID
+	HubUrl  string
+	SelfUrl string
+}
+
+func (q *Queries) SetFeedHub(ctx context.Context, arg SetFeedHubParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedHub, arg.ID, arg.HubUrl, arg.SelfUrl)
+	return err
+}
+
+const setFeedSiteUrl = `-- name: SetFeedSiteUrl :exec
+UPDATE feeds
+SET site_url = $2,
+    updated_at = NOW()
+WHERE id = $1 AND site_url <> $2
+`
+
+type SetFeedSiteUrlParams struct {
+	ID      uuid.UUID
+	SiteUrl string
+}
+
+func (q *Queries) SetFeedSiteUrl(ctx context.Context, arg SetFeedSiteUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSiteUrl, arg.ID, arg.SiteUrl)
+	return err
+}
+
 const unfollow = `-- name: Unfollow :exec
 DELETE FROM feed_follows
 WHERE feed_id = $1 AND user_id = $2
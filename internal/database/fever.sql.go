@@ -0,0 +1,295 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: fever.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const countFeverItems = `-- name: CountFeverItems :one
+SELECT count(*)
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+    AND NOT post_is_filtered($1, posts.id)
+`
+
+func (q *Queries) CountFeverItems(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeverItems, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getFeverFeeds = `-- name: GetFeverFeeds :many
+SELECT feeds.serial_id, COALESCE(feed_follows.title, feeds.name)::text AS title, feeds.url, feeds.site_url,
+    feeds.last_fetched_at, folders.serial_id AS folder_serial_id
+FROM feed_follows
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+ORDER BY COALESCE(feed_follows.title, feeds.name)
+`
+
+type GetFeverFeedsRow struct {
+	SerialID       int64
+	Title          string
+	Url            string
+	SiteUrl        string
+	LastFetchedAt  sql.NullTime
+	FolderSerialID sql.NullInt64
+}
+
+func (q *Queries) GetFeverFeeds(ctx context.Context, userID uuid.UUID) ([]GetFeverFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverFeeds, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverFeedsRow
+	for rows.Next() {
+		var i GetFeverFeedsRow
+		if err := rows.Scan(
+			&i.SerialID,
+			&i.Title,
+			&i.Url,
+			&i.SiteUrl,
+			&i.LastFetchedAt,
+			&i.FolderSerialID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverGroups = `-- name: GetFeverGroups :many
+SELECT serial_id, name FROM folders
+WHERE user_id = $1
+ORDER BY name
+`
+
+type GetFeverGroupsRow struct {
+	SerialID int64
+	Name     string
+}
+
+func (q *Queries) GetFeverGroups(ctx context.Context, userID uuid.UUID) ([]GetFeverGroupsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverGroups, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverGroupsRow
+	for rows.Next() {
+		var i GetFeverGroupsRow
+		if err := rows.Scan(&i.SerialID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverItemIds = `-- name: GetFeverItemIds :many
+SELECT posts.serial_id
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND (NOT $2::boolean OR post_states.read_at IS NULL)
+    AND (NOT $3::boolean OR post_states.starred_at IS NOT NULL)
+    AND NOT post_is_filtered($1, posts.id)
+ORDER BY posts.serial_id
+`
+
+type GetFeverItemIdsParams struct {
+	UserID      uuid.UUID
+	UnreadOnly  bool
+	StarredOnly bool
+}
+
+func (q *Queries) GetFeverItemIds(ctx context.Context, arg GetFeverItemIdsParams) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverItemIds, arg.UserID, arg.UnreadOnly, arg.StarredOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var serial_id int64
+		if err := rows.Scan(&serial_id); err != nil {
+			return nil, err
+		}
+		items = append(items, serial_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeverItems = `-- name: GetFeverItems :many
+SELECT posts.id, posts.serial_id, feeds.serial_id AS feed_serial_id, posts.title, posts.author, posts.description,
+    posts.content, posts.url, posts.published_at, posts.created_at, post_states.read_at, post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND ($2::bigint IS NULL OR posts.serial_id > $2)
+    AND ($3::bigint IS NULL OR posts.serial_id < $3)
+    AND ($4::bigint[] IS NULL OR posts.serial_id = ANY($4::bigint[]))
+    AND NOT post_is_filtered($1, posts.id)
+ORDER BY
+    CASE WHEN $5::boolean THEN posts.serial_id END DESC,
+    posts.serial_id ASC
+LIMIT $6
+`
+
+type GetFeverItemsParams struct {
+	UserID     uuid.UUID
+	SinceID    sql.NullInt64
+	MaxID      sql.NullInt64
+	WithIds    []int64
+	Descending bool
+	Limit      int32
+}
+
+type GetFeverItemsRow struct {
+	ID           uuid.UUID
+	SerialID     int64
+	FeedSerialID int64
+	Title        string
+	Author       string
+	Description  string
+	Content      string
+	Url          string
+	PublishedAt  sql.NullTime
+	CreatedAt    time.Time
+	ReadAt       sql.NullTime
+	StarredAt    sql.NullTime
+}
+
+func (q *Queries) GetFeverItems(ctx context.Context, arg GetFeverItemsParams) ([]GetFeverItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeverItems,
+		arg.UserID,
+		arg.SinceID,
+		arg.MaxID,
+		pq.Array(arg.WithIds),
+		arg.Descending,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeverItemsRow
+	for rows.Next() {
+		var i GetFeverItemsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SerialID,
+			&i.FeedSerialID,
+			&i.Title,
+			&i.Author,
+			&i.Description,
+			&i.Content,
+			&i.Url,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostIdBySerialId = `-- name: GetPostIdBySerialId :one
+SELECT posts.id
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $1
+WHERE posts.serial_id = $2
+`
+
+type GetPostIdBySerialIdParams struct {
+	UserID   uuid.UUID
+	SerialID int64
+}
+
+// only finds posts from feeds the user follows
+func (q *Queries) GetPostIdBySerialId(ctx context.Context, arg GetPostIdBySerialIdParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getPostIdBySerialId, arg.UserID, arg.SerialID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const markPostsReadBefore = `-- name: MarkPostsReadBefore :execrows
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, read_at)
+SELECT feed_follows.user_id, posts.id, NOW(), NOW(), NOW()
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+    AND COALESCE(posts.published_at, posts.created_at) <= $2::timestamp
+    AND ($3::bigint IS NULL OR feeds.serial_id = $3)
+    AND ($4::bigint IS NULL OR folders.serial_id = $4)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, NOW()),
+    updated_at = NOW()
+`
+
+type MarkPostsReadBeforeParams struct {
+	UserID         uuid.UUID
+	Before         time.Time
+	FeedSerialID   sql.NullInt64
+	FolderSerialID sql.NullInt64
+}
+
+func (q *Queries) MarkPostsReadBefore(ctx context.Context, arg MarkPostsReadBeforeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostsReadBefore,
+		arg.UserID,
+		arg.Before,
+		arg.FeedSerialID,
+		arg.FolderSerialID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
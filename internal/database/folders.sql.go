@@ -0,0 +1,165 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: folders.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFolder = `-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING id, created_at, updated_at, user_id, name, serial_id
+`
+
+type CreateFolderParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+}
+
+func (q *Queries) CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, createFolder,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Name,
+	)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.SerialID,
+	)
+	return i, err
+}
+
+const deleteFolder = `-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE user_id = $1 AND name = $2
+`
+
+type DeleteFolderParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFolder, arg.UserID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFolderByName = `-- name: GetFolderByName :one
+SELECT id, created_at, updated_at, user_id, name, serial_id FROM folders WHERE user_id = $1 AND name = $2
+`
+
+type GetFolderByNameParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, getFolderByName, arg.UserID, arg.Name)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.SerialID,
+	)
+	return i, err
+}
+
+const getFoldersForUser = `-- name: GetFoldersForUser :many
+SELECT folders.id, folders.created_at, folders.updated_at, folders.user_id, folders.name, folders.serial_id, count(feed_follows.id) AS feed_count
+FROM folders
+LEFT JOIN feed_follows ON feed_follows.folder_id = folders.id
+WHERE folders.user_id = $1
+GROUP BY folders.id
+ORDER BY folders.name
+`
+
+type GetFoldersForUserRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	SerialID  int64
+	FeedCount int64
+}
+
+func (q *Queries) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]GetFoldersForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFoldersForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFoldersForUserRow
+	for rows.Next() {
+		var i GetFoldersForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.SerialID,
+			&i.FeedCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $3,
+    updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowFolderParams struct {
+	UserID   uuid.UUID
+	FeedID   uuid.UUID
+	FolderID uuid.NullUUID
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder, arg.UserID, arg.FeedID, arg.FolderID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -11,6 +11,47 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+}
+
+type AuditLog struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ActorID   uuid.NullUUID
+	ActorName string
+	Action    string
+	Target    string
+	Details   string
+}
+
+type Digest struct {
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Email      string
+	Schedule   string
+	Hour       int32
+	Weekday    int32
+	Timezone   string
+	NextSendAt time.Time
+	LastSentAt sql.NullTime
+	LastError  string
+}
+
+type DigestPost struct {
+	UserID      uuid.UUID
+	PostID      uuid.UUID
+	DeliveredAt time.Time
+}
+
 type Feed struct {
 	ID            uuid.UUID
 	CreatedAt     time.Time
@@ -19,6 +60,10 @@ type Feed struct {
 	Name          string
 	Url           string
 	UserID        uuid.UUID
+	SiteUrl       string
+	SerialID      int64
+	HubUrl        string
+	SelfUrl       string
 }
 
 type FeedFollow struct {
@@ -27,6 +72,28 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	FolderID  uuid.NullUUID
+	Title     sql.NullString
+}
+
+type FilterRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	SerialID  int64
 }
 
 type Post struct {
@@ -36,13 +103,113 @@ type Post struct {
 	Title       string
 	Url         string
 	Description string
-	PublishedAt string
+	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      string
+	Content     string
+	Search      string
+	Categories  []string
+	SerialID    int64
 }
 
-type User struct {
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+}
+
+type PostTag struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type Rule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	ActionArg string
+}
+
+type SavedSearch struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	UserID    uuid.UUID
 	Name      string
+	Query     string
+}
+
+type Session struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	IsAdmin      bool
+}
+
+type Webhook struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	Url         string
+	Secret      string
+	FeedID      uuid.NullUUID
+	FolderID    uuid.NullUUID
+	Field       string
+	MatchType   string
+	Pattern     string
+	Template    string
+	ContentType string
+	RulesOnly   bool
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	WebhookID     uuid.UUID
+	PostID        uuid.NullUUID
+	Payload       string
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	ResponseCode  sql.NullInt32
+	Error         string
+	DeliveredAt   sql.NullTime
+}
+
+type WebsubSubscription struct {
+	FeedID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	HubUrl         string
+	TopicUrl       string
+	CallbackUrl    string
+	Secret         string
+	State          string
+	RequestedAt    time.Time
+	LeaseExpiresAt sql.NullTime
 }
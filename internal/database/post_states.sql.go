@@ -0,0 +1,197 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: post_states.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getTagsForPosts = `-- name: GetTagsForPosts :many
+SELECT post_id, tag FROM post_tags
+WHERE user_id = $1 AND post_id = ANY($2::uuid[])
+ORDER BY post_id, tag
+`
+
+type GetTagsForPostsParams struct {
+	UserID  uuid.UUID
+	PostIds []uuid.UUID
+}
+
+type GetTagsForPostsRow struct {
+	PostID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) GetTagsForPosts(ctx context.Context, arg GetTagsForPostsParams) ([]GetTagsForPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTagsForPosts, arg.UserID, pq.Array(arg.PostIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagsForPostsRow
+	for rows.Next() {
+		var i GetTagsForPostsRow
+		if err := rows.Scan(&i.PostID, &i.Tag); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadCountsForUser = `-- name: GetUnreadCountsForUser :many
+SELECT posts.feed_id, count(*) AS unread
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.read_at IS NULL
+    AND NOT post_is_filtered($1, posts.id)
+GROUP BY posts.feed_id
+`
+
+type GetUnreadCountsForUserRow struct {
+	FeedID uuid.UUID
+	Unread int64
+}
+
+func (q *Queries) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadCountsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsForUserRow
+	for rows.Next() {
+		var i GetUnreadCountsForUserRow
+		if err := rows.Scan(&i.FeedID, &i.Unread); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPostRead = `-- name: MarkPostRead :exec
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, read_at)
+VALUES ($1, $2, NOW(), NOW(), NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, NOW()),
+    updated_at = NOW()
+`
+
+type MarkPostReadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID)
+	return err
+}
+
+const markPostUnread = `-- name: MarkPostUnread :exec
+UPDATE post_states
+SET read_at = NULL,
+    updated_at = NOW()
+WHERE user_id = $1 AND post_id = $2
+`
+
+type MarkPostUnreadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostUnread, arg.UserID, arg.PostID)
+	return err
+}
+
+const starPost = `-- name: StarPost :exec
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, starred_at)
+VALUES ($1, $2, NOW(), NOW(), NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred_at = COALESCE(post_states.starred_at, NOW()),
+    updated_at = NOW()
+`
+
+type StarPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) StarPost(ctx context.Context, arg StarPostParams) error {
+	_, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.PostID)
+	return err
+}
+
+const tagPost = `-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type TagPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) TagPost(ctx context.Context, arg TagPostParams) error {
+	_, err := q.db.ExecContext(ctx, tagPost, arg.UserID, arg.PostID, arg.Tag)
+	return err
+}
+
+const unstarPost = `-- name: UnstarPost :exec
+UPDATE post_states
+SET starred_at = NULL,
+    updated_at = NOW()
+WHERE user_id = $1 AND post_id = $2
+`
+
+type UnstarPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) UnstarPost(ctx context.Context, arg UnstarPostParams) error {
+	_, err := q.db.ExecContext(ctx, unstarPost, arg.UserID, arg.PostID)
+	return err
+}
+
+const untagPost = `-- name: UntagPost :execrows
+DELETE FROM post_tags
+WHERE user_id = $1 AND post_id = $2 AND tag = $3
+`
+
+type UntagPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) UntagPost(ctx context.Context, arg UntagPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, untagPost, arg.UserID, arg.PostID, arg.Tag)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -7,13 +7,15 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, categories)
 VALUES (
     $1,
     $2,
@@ -22,9 +24,12 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, search, categories, serial_id
 `
 
 type CreatePostParams struct {
@@ -34,8 +39,11 @@ type CreatePostParams struct {
 	Title       string
 	Url         string
 	Description string
-	PublishedAt string
+	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      string
+	Content     string
+	Categories  []string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -48,6 +56,9 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		arg.Content,
+		pq.Array(arg.Categories),
 	)
 	var i Post
 	err := row.Scan(
@@ -59,12 +70,17 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.Search,
+		pq.Array(&i.Categories),
+		&i.SerialID,
 	)
 	return i, err
 }
 
 const getPostByUrl = `-- name: GetPostByUrl :one
-SELECT id, created_at, updated_at, title, url, description, published_at, feed_id
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, search, categories, serial_id
 FROM posts
 WHERE url = $1
 `
@@ -81,35 +97,153 @@ func (q *Queries) GetPostByUrl(ctx context.Context, url string) (Post, error) {
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.Search,
+		pq.Array(&i.Categories),
+		&i.SerialID,
+	)
+	return i, err
+}
+
+const getPostDetail = `-- name: GetPostDetail :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.search, posts.categories, posts.serial_id, COALESCE(feed_follows.title, feeds.name)::text AS feed_name, feeds.url AS feed_url,
+    post_states.read_at, post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = $1
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = $1
+WHERE (posts.url = $2 OR posts.id::text = $2)
+    AND (NOT $3::boolean OR feed_follows.id IS NOT NULL)
+LIMIT 1
+`
+
+type GetPostDetailParams struct {
+	UserID        uuid.UUID
+	Ref           string
+	FollowingOnly bool
+}
+
+type GetPostDetailRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description string
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      string
+	Content     string
+	Search      string
+	Categories  []string
+	SerialID    int64
+	FeedName    string
+	FeedUrl     string
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
+}
+
+func (q *Queries) GetPostDetail(ctx context.Context, arg GetPostDetailParams) (GetPostDetailRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostDetail, arg.UserID, arg.Ref, arg.FollowingOnly)
+	var i GetPostDetailRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Content,
+		&i.Search,
+		pq.Array(&i.Categories),
+		&i.SerialID,
+		&i.FeedName,
+		&i.FeedUrl,
+		&i.ReadAt,
+		&i.StarredAt,
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.title, posts.description, posts.published_at, posts.url, feeds.name 
+SELECT posts.id, posts.title, posts.description, posts.published_at, posts.url,
+    COALESCE(feed_follows.title, feeds.name)::text AS name, post_states.read_at, post_states.starred_at,
+    posts.created_at, posts.author, posts.content, posts.categories, feeds.url AS feed_url, feeds.site_url AS feed_site_url,
+    posts.feed_id
 FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
 JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2
+    AND ($2::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= $2)
+    AND ($3::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < $3)
+    AND ($4::text IS NULL OR feeds.name = $4 OR feeds.url = $4 OR feed_follows.title = $4)
+    AND ($5::text IS NULL OR folders.name = $5)
+    AND (NOT $6::boolean OR post_states.read_at IS NULL)
+    AND (NOT $7::boolean OR post_states.starred_at IS NOT NULL)
+    AND ($8::text IS NULL OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.user_id = feed_follows.user_id AND post_tags.post_id = posts.id AND post_tags.tag = $8
+    ))
+    AND NOT post_is_filtered($1, posts.id)
+ORDER BY
+    CASE WHEN $9::boolean THEN COALESCE(posts.published_at, posts.created_at) END ASC,
+    COALESCE(posts.published_at, posts.created_at) DESC,
+    posts.id
+LIMIT $11 OFFSET $10
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID      uuid.UUID
+	Since       sql.NullTime
+	Until       sql.NullTime
+	Feed        sql.NullString
+	Folder      sql.NullString
+	UnreadOnly  bool
+	StarredOnly bool
+	Tag         sql.NullString
+	Ascending   bool
+	Offset      int32
+	Limit       int32
 }
 
 type GetPostsForUserRow struct {
+	ID          uuid.UUID
 	Title       string
 	Description string
-	PublishedAt string
+	PublishedAt sql.NullTime
 	Url         string
 	Name        string
+	ReadAt      sql.NullTime
+	StarredAt   sql.NullTime
+	CreatedAt   time.Time
+	Author      string
+	Content     string
+	Categories  []string
+	FeedUrl     string
+	FeedSiteUrl string
+	FeedID      uuid.UUID
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Since,
+		arg.Until,
+		arg.Feed,
+		arg.Folder,
+		arg.UnreadOnly,
+		arg.StarredOnly,
+		arg.Tag,
+		arg.Ascending,
+		arg.Offset,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -118,11 +252,205 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	for rows.Next() {
 		var i GetPostsForUserRow
 		if err := rows.Scan(
+			&i.ID,
 			&i.Title,
 			&i.Description,
 			&i.PublishedAt,
 			&i.Url,
 			&i.Name,
+			&i.ReadAt,
+			&i.StarredAt,
+			&i.CreatedAt,
+			&i.Author,
+			&i.Content,
+			pq.Array(&i.Categories),
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
+			&i.FeedID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPosts = `-- name: GetRecentPosts :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.content, posts.search, posts.categories, posts.serial_id, feeds.name AS feed_name, feeds.url AS feed_url, feeds.site_url AS feed_site_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
+LIMIT $1
+`
+
+type GetRecentPostsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description string
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      string
+	Content     string
+	Search      string
+	Categories  []string
+	SerialID    int64
+	FeedName    string
+	FeedUrl     string
+	FeedSiteUrl string
+}
+
+func (q *Queries) GetRecentPosts(ctx context.Context, limit int32) ([]GetRecentPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPosts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentPostsRow
+	for rows.Next() {
+		var i GetRecentPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Search,
+			pq.Array(&i.Categories),
+			&i.SerialID,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPostsForFeed = `-- name: GetRecentPostsForFeed :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, search, categories, serial_id
+FROM posts
+WHERE feed_id = $1
+ORDER BY COALESCE(published_at, created_at) DESC
+LIMIT $2
+`
+
+type GetRecentPostsForFeedParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRecentPostsForFeed(ctx context.Context, arg GetRecentPostsForFeedParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostsForFeed, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Content,
+			&i.Search,
+			pq.Array(&i.Categories),
+			&i.SerialID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, COALESCE(feed_follows.title, feeds.name)::text AS feed_name,
+    ts_rank(posts.search, websearch_to_tsquery('english', $1::text))::real AS rank
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = $2
+WHERE posts.search @@ websearch_to_tsquery('english', $1::text)
+    AND ($3::boolean OR feed_follows.id IS NOT NULL)
+    AND ($4::text IS NULL OR feeds.name = $4 OR feeds.url = $4 OR feed_follows.title = $4)
+    AND NOT post_is_filtered($2, posts.id)
+ORDER BY rank DESC, COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT $5
+`
+
+type SearchPostsParams struct {
+	Query    string
+	UserID   uuid.UUID
+	AllFeeds bool
+	Feed     sql.NullString
+	Limit    int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt sql.NullTime
+	FeedName    string
+	Rank        float32
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.UserID,
+		arg.AllFeeds,
+		arg.Feed,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
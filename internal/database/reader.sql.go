@@ -0,0 +1,372 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: reader.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getFeedBySerialId = `-- name: GetFeedBySerialId :one
+SELECT id, created_at, updated_at, last_fetched_at, name, url, user_id, site_url, serial_id, hub_url, self_url FROM feeds WHERE serial_id = $1
+`
+
+func (q *Queries) GetFeedBySerialId(ctx context.Context, serialID int64) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedBySerialId, serialID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastFetchedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.SiteUrl,
+		&i.SerialID,
+		&i.HubUrl,
+		&i.SelfUrl,
+	)
+	return i, err
+}
+
+const getReaderItemIds = `-- name: GetReaderItemIds :many
+SELECT posts.serial_id, posts.created_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND ($2::bigint IS NULL OR feeds.serial_id = $2)
+    AND ($3::text IS NULL OR folders.name = $3)
+    AND (NOT $4::boolean OR post_states.read_at IS NOT NULL)
+    AND (NOT $5::boolean OR post_states.starred_at IS NOT NULL)
+    AND (NOT $6::boolean OR post_states.read_at IS NULL)
+    AND (NOT $7::boolean OR post_states.starred_at IS NULL)
+    AND ($8::timestamp IS NULL OR posts.created_at >= $8)
+    AND ($9::timestamp IS NULL OR posts.created_at < $9)
+    AND ($10::bigint IS NULL
+        OR ($11::boolean AND posts.serial_id > $10)
+        OR (NOT $11::boolean AND posts.serial_id < $10))
+    AND NOT post_is_filtered($1, posts.id)
+ORDER BY
+    CASE WHEN $11::boolean THEN posts.serial_id END ASC,
+    posts.serial_id DESC
+LIMIT $12
+`
+
+type GetReaderItemIdsParams struct {
+	UserID         uuid.UUID
+	FeedSerialID   sql.NullInt64
+	Folder         sql.NullString
+	ReadOnly       bool
+	StarredOnly    bool
+	ExcludeRead    bool
+	ExcludeStarred bool
+	NewerThan      sql.NullTime
+	OlderThan      sql.NullTime
+	Continuation   sql.NullInt64
+	OldestFirst    bool
+	Limit          int32
+}
+
+type GetReaderItemIdsRow struct {
+	SerialID  int64
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetReaderItemIds(ctx context.Context, arg GetReaderItemIdsParams) ([]GetReaderItemIdsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReaderItemIds,
+		arg.UserID,
+		arg.FeedSerialID,
+		arg.Folder,
+		arg.ReadOnly,
+		arg.StarredOnly,
+		arg.ExcludeRead,
+		arg.ExcludeStarred,
+		arg.NewerThan,
+		arg.OlderThan,
+		arg.Continuation,
+		arg.OldestFirst,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReaderItemIdsRow
+	for rows.Next() {
+		var i GetReaderItemIdsRow
+		if err := rows.Scan(&i.SerialID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReaderItems = `-- name: GetReaderItems :many
+SELECT posts.id, posts.serial_id, posts.title, posts.author, posts.description, posts.content, posts.url,
+    posts.published_at, posts.created_at, feeds.serial_id AS feed_serial_id,
+    COALESCE(feed_follows.title, feeds.name)::text AS feed_title, feeds.site_url,
+    folders.name AS folder_name, post_states.read_at, post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND ($2::bigint IS NULL OR feeds.serial_id = $2)
+    AND ($3::text IS NULL OR folders.name = $3)
+    AND (NOT $4::boolean OR post_states.read_at IS NOT NULL)
+    AND (NOT $5::boolean OR post_states.starred_at IS NOT NULL)
+    AND (NOT $6::boolean OR post_states.read_at IS NULL)
+    AND (NOT $7::boolean OR post_states.starred_at IS NULL)
+    AND ($8::timestamp IS NULL OR posts.created_at >= $8)
+    AND ($9::timestamp IS NULL OR posts.created_at < $9)
+    AND ($10::bigint[] IS NULL OR posts.serial_id = ANY($10::bigint[]))
+    AND ($11::bigint IS NULL
+        OR ($12::boolean AND posts.serial_id > $11)
+        OR (NOT $12::boolean AND posts.serial_id < $11))
+    AND NOT post_is_filtered($1, posts.id)
+ORDER BY
+    CASE WHEN $12::boolean THEN posts.serial_id END ASC,
+    posts.serial_id DESC
+LIMIT $13
+`
+
+type GetReaderItemsParams struct {
+	UserID         uuid.UUID
+	FeedSerialID   sql.NullInt64
+	Folder         sql.NullString
+	ReadOnly       bool
+	StarredOnly    bool
+	ExcludeRead    bool
+	ExcludeStarred bool
+	NewerThan      sql.NullTime
+	OlderThan      sql.NullTime
+	WithIds        []int64
+	Continuation   sql.NullInt64
+	OldestFirst    bool
+	Limit          int32
+}
+
+type GetReaderItemsRow struct {
+	ID           uuid.UUID
+	SerialID     int64
+	Title        string
+	Author       string
+	Description  string
+	Content      string
+	Url          string
+	PublishedAt  sql.NullTime
+	CreatedAt    time.Time
+	FeedSerialID int64
+	FeedTitle    string
+	SiteUrl      string
+	FolderName   sql.NullString
+	ReadAt       sql.NullTime
+	StarredAt    sql.NullTime
+}
+
+func (q *Queries) GetReaderItems(ctx context.Context, arg GetReaderItemsParams) ([]GetReaderItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReaderItems,
+		arg.UserID,
+		arg.FeedSerialID,
+		arg.Folder,
+		arg.ReadOnly,
+		arg.StarredOnly,
+		arg.ExcludeRead,
+		arg.ExcludeStarred,
+		arg.NewerThan,
+		arg.OlderThan,
+		pq.Array(arg.WithIds),
+		arg.Continuation,
+		arg.OldestFirst,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReaderItemsRow
+	for rows.Next() {
+		var i GetReaderItemsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SerialID,
+			&i.Title,
+			&i.Author,
+			&i.Description,
+			&i.Content,
+			&i.Url,
+			&i.PublishedAt,
+			&i.CreatedAt,
+			&i.FeedSerialID,
+			&i.FeedTitle,
+			&i.SiteUrl,
+			&i.FolderName,
+			&i.ReadAt,
+			&i.StarredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReaderSubscriptions = `-- name: GetReaderSubscriptions :many
+SELECT feeds.serial_id, COALESCE(feed_follows.title, feeds.name)::text AS title, feeds.url, feeds.site_url,
+    folders.name AS folder_name, feed_follows.created_at
+FROM feed_follows
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+ORDER BY COALESCE(feed_follows.title, feeds.name)
+`
+
+type GetReaderSubscriptionsRow struct {
+	SerialID   int64
+	Title      string
+	Url        string
+	SiteUrl    string
+	FolderName sql.NullString
+	CreatedAt  time.Time
+}
+
+func (q *Queries) GetReaderSubscriptions(ctx context.Context, userID uuid.UUID) ([]GetReaderSubscriptionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReaderSubscriptions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReaderSubscriptionsRow
+	for rows.Next() {
+		var i GetReaderSubscriptionsRow
+		if err := rows.Scan(
+			&i.SerialID,
+			&i.Title,
+			&i.Url,
+			&i.SiteUrl,
+			&i.FolderName,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReaderUnreadCounts = `-- name: GetReaderUnreadCounts :many
+SELECT feeds.serial_id, folders.name AS folder_name, count(*) AS unread,
+    max(posts.created_at)::timestamp AS newest
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND post_states.read_at IS NULL
+    AND NOT post_is_filtered($1, posts.id)
+GROUP BY feeds.serial_id, folders.name
+ORDER BY feeds.serial_id
+`
+
+type GetReaderUnreadCountsRow struct {
+	SerialID   int64
+	FolderName sql.NullString
+	Unread     int64
+	Newest     time.Time
+}
+
+func (q *Queries) GetReaderUnreadCounts(ctx context.Context, userID uuid.UUID) ([]GetReaderUnreadCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReaderUnreadCounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReaderUnreadCountsRow
+	for rows.Next() {
+		var i GetReaderUnreadCountsRow
+		if err := rows.Scan(
+			&i.SerialID,
+			&i.FolderName,
+			&i.Unread,
+			&i.Newest,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markReaderStreamRead = `-- name: MarkReaderStreamRead :execrows
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, read_at)
+SELECT feed_follows.user_id, posts.id, NOW(), NOW(), NOW()
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+    AND posts.created_at <= $2::timestamp
+    AND ($3::bigint IS NULL OR feeds.serial_id = $3)
+    AND ($4::text IS NULL OR folders.name = $4)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, NOW()),
+    updated_at = NOW()
+`
+
+type MarkReaderStreamReadParams struct {
+	UserID       uuid.UUID
+	Before       time.Time
+	FeedSerialID sql.NullInt64
+	Folder       sql.NullString
+}
+
+func (q *Queries) MarkReaderStreamRead(ctx context.Context, arg MarkReaderStreamReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markReaderStreamRead,
+		arg.UserID,
+		arg.Before,
+		arg.FeedSerialID,
+		arg.Folder,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
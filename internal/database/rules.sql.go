@@ -0,0 +1,277 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: rules.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createRule = `-- name: CreateRule :one
+INSERT INTO rules (id, created_at, updated_at, user_id, name, feed_id, field, match_type, pattern, action, action_arg)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11
+)
+RETURNING id, created_at, updated_at, user_id, name, feed_id, field, match_type, pattern, action, action_arg
+`
+
+type CreateRuleParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	ActionArg string
+}
+
+func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (Rule, error) {
+	row := q.db.QueryRowContext(ctx, createRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Name,
+		arg.FeedID,
+		arg.Field,
+		arg.MatchType,
+		arg.Pattern,
+		arg.Action,
+		arg.ActionArg,
+	)
+	var i Rule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.FeedID,
+		&i.Field,
+		&i.MatchType,
+		&i.Pattern,
+		&i.Action,
+		&i.ActionArg,
+	)
+	return i, err
+}
+
+const deleteRule = `-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE user_id = $1 AND name = $2
+`
+
+type DeleteRuleParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) DeleteRule(ctx context.Context, arg DeleteRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRule, arg.UserID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getNotifyRuleNames = `-- name: GetNotifyRuleNames :many
+SELECT name FROM rules
+WHERE user_id = $1 AND action = 'notify' AND action_arg = $2
+ORDER BY name
+`
+
+type GetNotifyRuleNamesParams struct {
+	UserID  uuid.UUID
+	Webhook string
+}
+
+func (q *Queries) GetNotifyRuleNames(ctx context.Context, arg GetNotifyRuleNamesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getNotifyRuleNames, arg.UserID, arg.Webhook)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRuleByName = `-- name: GetRuleByName :one
+SELECT id, created_at, updated_at, user_id, name, feed_id, field, match_type, pattern, action, action_arg FROM rules WHERE user_id = $1 AND name = $2
+`
+
+type GetRuleByNameParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetRuleByName(ctx context.Context, arg GetRuleByNameParams) (Rule, error) {
+	row := q.db.QueryRowContext(ctx, getRuleByName, arg.UserID, arg.Name)
+	var i Rule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.FeedID,
+		&i.Field,
+		&i.MatchType,
+		&i.Pattern,
+		&i.Action,
+		&i.ActionArg,
+	)
+	return i, err
+}
+
+const getRulesForFeed = `-- name: GetRulesForFeed :many
+SELECT rules.id, rules.created_at, rules.updated_at, rules.user_id, rules.name, rules.feed_id, rules.field, rules.match_type, rules.pattern, rules.action, rules.action_arg, users.name AS user_name
+FROM rules
+JOIN feed_follows ON rules.user_id = feed_follows.user_id
+JOIN users ON rules.user_id = users.id
+WHERE feed_follows.feed_id = $1
+    AND (rules.feed_id IS NULL OR rules.feed_id = $1)
+ORDER BY rules.user_id, rules.name
+`
+
+type GetRulesForFeedRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	ActionArg string
+	UserName  string
+}
+
+func (q *Queries) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]GetRulesForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRulesForFeedRow
+	for rows.Next() {
+		var i GetRulesForFeedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.FeedID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Action,
+			&i.ActionArg,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRulesForUser = `-- name: GetRulesForUser :many
+SELECT rules.id, rules.created_at, rules.updated_at, rules.user_id, rules.name, rules.feed_id, rules.field, rules.match_type, rules.pattern, rules.action, rules.action_arg, feeds.name AS feed_name
+FROM rules
+LEFT JOIN feeds ON rules.feed_id = feeds.id
+WHERE rules.user_id = $1
+ORDER BY rules.name
+`
+
+type GetRulesForUserRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	ActionArg string
+	FeedName  sql.NullString
+}
+
+func (q *Queries) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]GetRulesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRulesForUserRow
+	for rows.Next() {
+		var i GetRulesForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.FeedID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Action,
+			&i.ActionArg,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,222 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: saved_searches.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createSavedSearch = `-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (id, created_at, updated_at, user_id, name, query)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING id, created_at, updated_at, user_id, name, query
+`
+
+type CreateSavedSearchParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	Query     string
+}
+
+func (q *Queries) CreateSavedSearch(ctx context.Context, arg CreateSavedSearchParams) (SavedSearch, error) {
+	row := q.db.QueryRowContext(ctx, createSavedSearch,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Name,
+		arg.Query,
+	)
+	var i SavedSearch
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.Query,
+	)
+	return i, err
+}
+
+const deleteSavedSearch = `-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches
+WHERE user_id = $1 AND name = $2
+`
+
+type DeleteSavedSearchParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) DeleteSavedSearch(ctx context.Context, arg DeleteSavedSearchParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSavedSearch, arg.UserID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostsForSavedSearch = `-- name: GetPostsForSavedSearch :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, COALESCE(feed_follows.title, feeds.name)::text AS name, post_states.read_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND posts.search @@ websearch_to_tsquery('english', $2::text)
+    AND (NOT $3::boolean OR post_states.read_at IS NULL)
+    AND NOT post_is_filtered($1, posts.id)
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
+LIMIT $5 OFFSET $4
+`
+
+type GetPostsForSavedSearchParams struct {
+	UserID     uuid.UUID
+	Query      string
+	UnreadOnly bool
+	Offset     int32
+	Limit      int32
+}
+
+type GetPostsForSavedSearchRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt sql.NullTime
+	Name        string
+	ReadAt      sql.NullTime
+}
+
+func (q *Queries) GetPostsForSavedSearch(ctx context.Context, arg GetPostsForSavedSearchParams) ([]GetPostsForSavedSearchRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForSavedSearch,
+		arg.UserID,
+		arg.Query,
+		arg.UnreadOnly,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForSavedSearchRow
+	for rows.Next() {
+		var i GetPostsForSavedSearchRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.Name,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSavedSearchByName = `-- name: GetSavedSearchByName :one
+SELECT id, created_at, updated_at, user_id, name, query FROM saved_searches WHERE user_id = $1 AND name = $2
+`
+
+type GetSavedSearchByNameParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetSavedSearchByName(ctx context.Context, arg GetSavedSearchByNameParams) (SavedSearch, error) {
+	row := q.db.QueryRowContext(ctx, getSavedSearchByName, arg.UserID, arg.Name)
+	var i SavedSearch
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.Query,
+	)
+	return i, err
+}
+
+const getSavedSearchesForUser = `-- name: GetSavedSearchesForUser :many
+SELECT saved_searches.id, saved_searches.created_at, saved_searches.updated_at, saved_searches.user_id, saved_searches.name, saved_searches.query, (
+    SELECT count(*)
+    FROM posts
+    JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+    LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+    WHERE feed_follows.user_id = saved_searches.user_id
+        AND posts.search @@ websearch_to_tsquery('english', saved_searches.query)
+        AND post_states.read_at IS NULL
+        AND NOT post_is_filtered(saved_searches.user_id, posts.id)
+) AS unread_count
+FROM saved_searches
+WHERE saved_searches.user_id = $1
+ORDER BY saved_searches.name
+`
+
+type GetSavedSearchesForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	Query       string
+	UnreadCount int64
+}
+
+func (q *Queries) GetSavedSearchesForUser(ctx context.Context, userID uuid.UUID) ([]GetSavedSearchesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSavedSearchesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSavedSearchesForUserRow
+	for rows.Next() {
+		var i GetSavedSearchesForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.Query,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,508 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: webhooks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimWebhookDeliveries = `-- name: ClaimWebhookDeliveries :many
+WITH due AS (
+    SELECT webhook_deliveries.id
+    FROM webhook_deliveries
+    WHERE webhook_deliveries.status = 'pending' AND webhook_deliveries.next_attempt_at <= $2::timestamp
+    ORDER BY webhook_deliveries.next_attempt_at
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE webhook_deliveries
+SET next_attempt_at = $1::timestamp,
+    updated_at = NOW()
+FROM due, webhooks
+WHERE webhook_deliveries.id = due.id AND webhooks.id = webhook_deliveries.webhook_id
+RETURNING webhook_deliveries.id, webhook_deliveries.payload, webhook_deliveries.attempts,
+    webhooks.name AS webhook_name, webhooks.url, webhooks.secret, webhooks.content_type
+`
+
+type ClaimWebhookDeliveriesParams struct {
+	LeaseUntil time.Time
+	Now        time.Time
+	Limit      int32
+}
+
+type ClaimWebhookDeliveriesRow struct {
+	ID          uuid.UUID
+	Payload     string
+	Attempts    int32
+	WebhookName string
+	Url         string
+	Secret      string
+	ContentType string
+}
+
+// pushes the next attempt of due deliveries back by lease_until, so another
+// process delivering at the same time skips them
+func (q *Queries) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimWebhookDeliveries, arg.LeaseUntil, arg.Now, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimWebhookDeliveriesRow
+	for rows.Next() {
+		var i ClaimWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Payload,
+			&i.Attempts,
+			&i.WebhookName,
+			&i.Url,
+			&i.Secret,
+			&i.ContentType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, name, url, secret, feed_id, folder_id, field, match_type, pattern, template, content_type, rules_only)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15
+)
+RETURNING id, created_at, updated_at, user_id, name, url, secret, feed_id, folder_id, field, match_type, pattern, template, content_type, rules_only
+`
+
+type CreateWebhookParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	Url         string
+	Secret      string
+	FeedID      uuid.NullUUID
+	FolderID    uuid.NullUUID
+	Field       string
+	MatchType   string
+	Pattern     string
+	Template    string
+	ContentType string
+	RulesOnly   bool
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, createWebhook,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.Name,
+		arg.Url,
+		arg.Secret,
+		arg.FeedID,
+		arg.FolderID,
+		arg.Field,
+		arg.MatchType,
+		arg.Pattern,
+		arg.Template,
+		arg.ContentType,
+		arg.RulesOnly,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.Url,
+		&i.Secret,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Field,
+		&i.MatchType,
+		&i.Pattern,
+		&i.Template,
+		&i.ContentType,
+		&i.RulesOnly,
+	)
+	return i, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (id, created_at, updated_at, webhook_id, post_id, payload, status, next_attempt_at)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4,
+    'pending',
+    $5
+)
+ON CONFLICT (webhook_id, post_id) DO NOTHING
+RETURNING id, created_at, updated_at, webhook_id, post_id, payload, status, attempts, next_attempt_at, response_code, error, delivered_at
+`
+
+type CreateWebhookDeliveryParams struct {
+	ID            uuid.UUID
+	WebhookID     uuid.UUID
+	PostID        uuid.NullUUID
+	Payload       string
+	NextAttemptAt time.Time
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookDelivery,
+		arg.ID,
+		arg.WebhookID,
+		arg.PostID,
+		arg.Payload,
+		arg.NextAttemptAt,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.WebhookID,
+		&i.PostID,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseCode,
+		&i.Error,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE user_id = $1 AND name = $2
+`
+
+type DeleteWebhookParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWebhook, arg.UserID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getWebhookByName = `-- name: GetWebhookByName :one
+SELECT id, created_at, updated_at, user_id, name, url, secret, feed_id, folder_id, field, match_type, pattern, template, content_type, rules_only FROM webhooks WHERE user_id = $1 AND name = $2
+`
+
+type GetWebhookByNameParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetWebhookByName(ctx context.Context, arg GetWebhookByNameParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookByName, arg.UserID, arg.Name)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.Url,
+		&i.Secret,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Field,
+		&i.MatchType,
+		&i.Pattern,
+		&i.Template,
+		&i.ContentType,
+		&i.RulesOnly,
+	)
+	return i, err
+}
+
+const getWebhookDeliveries = `-- name: GetWebhookDeliveries :many
+SELECT webhook_deliveries.id, webhook_deliveries.created_at, webhook_deliveries.status, webhook_deliveries.attempts,
+    webhook_deliveries.next_attempt_at, webhook_deliveries.response_code, webhook_deliveries.error,
+    webhook_deliveries.delivered_at, webhooks.name AS webhook_name, posts.title AS post_title
+FROM webhook_deliveries
+JOIN webhooks ON webhook_deliveries.webhook_id = webhooks.id
+LEFT JOIN posts ON webhook_deliveries.post_id = posts.id
+WHERE webhooks.user_id = $1
+    AND ($2::text IS NULL OR webhooks.name = $2)
+    AND ($3::text IS NULL OR webhook_deliveries.status = $3)
+ORDER BY webhook_deliveries.created_at DESC
+LIMIT $4
+`
+
+type GetWebhookDeliveriesParams struct {
+	UserID  uuid.UUID
+	Webhook sql.NullString
+	Status  sql.NullString
+	Limit   int32
+}
+
+type GetWebhookDeliveriesRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	ResponseCode  sql.NullInt32
+	Error         string
+	DeliveredAt   sql.NullTime
+	WebhookName   string
+	PostTitle     sql.NullString
+}
+
+func (q *Queries) GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]GetWebhookDeliveriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookDeliveries,
+		arg.UserID,
+		arg.Webhook,
+		arg.Status,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetWebhookDeliveriesRow
+	for rows.Next() {
+		var i GetWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.ResponseCode,
+			&i.Error,
+			&i.DeliveredAt,
+			&i.WebhookName,
+			&i.PostTitle,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhooksForPost = `-- name: GetWebhooksForPost :many
+SELECT webhooks.id, webhooks.created_at, webhooks.updated_at, webhooks.user_id, webhooks.name, webhooks.url, webhooks.secret, webhooks.feed_id, webhooks.folder_id, webhooks.field, webhooks.match_type, webhooks.pattern, webhooks.template, webhooks.content_type, webhooks.rules_only, users.name AS user_name
+FROM webhooks
+JOIN users ON webhooks.user_id = users.id
+JOIN posts ON posts.id = $1
+JOIN feed_follows ON feed_follows.user_id = webhooks.user_id AND feed_follows.feed_id = posts.feed_id
+WHERE NOT webhooks.rules_only
+    AND (webhooks.feed_id IS NULL OR webhooks.feed_id = posts.feed_id)
+    AND (webhooks.folder_id IS NULL OR webhooks.folder_id = feed_follows.folder_id)
+    AND NOT post_is_filtered(webhooks.user_id, posts.id)
+ORDER BY webhooks.user_id, webhooks.name
+`
+
+type GetWebhooksForPostRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	Url         string
+	Secret      string
+	FeedID      uuid.NullUUID
+	FolderID    uuid.NullUUID
+	Field       string
+	MatchType   string
+	Pattern     string
+	Template    string
+	ContentType string
+	RulesOnly   bool
+	UserName    string
+}
+
+func (q *Queries) GetWebhooksForPost(ctx context.Context, postID uuid.UUID) ([]GetWebhooksForPostRow, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhooksForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetWebhooksForPostRow
+	for rows.Next() {
+		var i GetWebhooksForPostRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.Url,
+			&i.Secret,
+			&i.FeedID,
+			&i.FolderID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Template,
+			&i.ContentType,
+			&i.RulesOnly,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhooksForUser = `-- name: GetWebhooksForUser :many
+SELECT webhooks.id, webhooks.created_at, webhooks.updated_at, webhooks.user_id, webhooks.name, webhooks.url, webhooks.secret, webhooks.feed_id, webhooks.folder_id, webhooks.field, webhooks.match_type, webhooks.pattern, webhooks.template, webhooks.content_type, webhooks.rules_only, feeds.name AS feed_name, folders.name AS folder_name
+FROM webhooks
+LEFT JOIN feeds ON webhooks.feed_id = feeds.id
+LEFT JOIN folders ON webhooks.folder_id = folders.id
+WHERE webhooks.user_id = $1
+ORDER BY webhooks.name
+`
+
+type GetWebhooksForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Name        string
+	Url         string
+	Secret      string
+	FeedID      uuid.NullUUID
+	FolderID    uuid.NullUUID
+	Field       string
+	MatchType   string
+	Pattern     string
+	Template    string
+	ContentType string
+	RulesOnly   bool
+	FeedName    sql.NullString
+	FolderName  sql.NullString
+}
+
+func (q *Queries) GetWebhooksForUser(ctx context.Context, userID uuid.UUID) ([]GetWebhooksForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhooksForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetWebhooksForUserRow
+	for rows.Next() {
+		var i GetWebhooksForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.Url,
+			&i.Secret,
+			&i.FeedID,
+			&i.FolderID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Template,
+			&i.ContentType,
+			&i.RulesOnly,
+			&i.FeedName,
+			&i.FolderName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordWebhookAttempt = `-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    status = $2,
+    response_code = $3,
+    error = $4,
+    next_attempt_at = $5,
+    delivered_at = $6,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type RecordWebhookAttemptParams struct {
+	ID            uuid.UUID
+	Status        string
+	ResponseCode  sql.NullInt32
+	Error         string
+	NextAttemptAt time.Time
+	DeliveredAt   sql.NullTime
+}
+
+func (q *Queries) RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) error {
+	_, err := q.db.ExecContext(ctx, recordWebhookAttempt,
+		arg.ID,
+		arg.Status,
+		arg.ResponseCode,
+		arg.Error,
+		arg.NextAttemptAt,
+		arg.DeliveredAt,
+	)
+	return err
+}
@@ -0,0 +1,174 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: websub.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const activateWebSubSubscription = `-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'active',
+    lease_expires_at = $2,
+    updated_at = NOW()
+WHERE feed_id = $1
+`
+
+type ActivateWebSubSubscriptionParams struct {
+	FeedID         uuid.UUID
+	LeaseExpiresAt sql.NullTime
+}
+
+func (q *Queries) ActivateWebSubSubscription(ctx context.Context, arg ActivateWebSubSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, activateWebSubSubscription, arg.FeedID, arg.LeaseExpiresAt)
+	return err
+}
+
+const getFeedsToSubscribe = `-- name: GetFeedsToSubscribe :many
+SELECT feeds.id, feeds.hub_url, COALESCE(NULLIF(feeds.self_url, ''), feeds.url)::text AS topic_url
+FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.hub_url <> ''
+    AND (websub_subscriptions.feed_id IS NULL
+        OR websub_subscriptions.hub_url <> feeds.hub_url
+        OR websub_subscriptions.topic_url <> COALESCE(NULLIF(feeds.self_url, ''), feeds.url)
+        OR websub_subscriptions.callback_url <> $1::text || feeds.id::text
+        OR (websub_subscriptions.state = 'active' AND websub_subscriptions.lease_expires_at < $2::timestamp)
+        OR (websub_subscriptions.state <> 'active' AND websub_subscriptions.requested_at < $3::timestamp))
+`
+
+type GetFeedsToSubscribeParams struct {
+	CallbackPrefix string
+	RenewBefore    time.Time
+	RetryBefore    time.Time
+}
+
+type GetFeedsToSubscribeRow struct {
+	ID       uuid.UUID
+	HubUrl   string
+	TopicUrl string
+}
+
+func (q *Queries) GetFeedsToSubscribe(ctx context.Context, arg GetFeedsToSubscribeParams) ([]GetFeedsToSubscribeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsToSubscribe, arg.CallbackPrefix, arg.RenewBefore, arg.RetryBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedsToSubscribeRow
+	for rows.Next() {
+		var i GetFeedsToSubscribeRow
+		if err := rows.Scan(&i.ID, &i.HubUrl, &i.TopicUrl); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebSubSubscription = `-- name: GetWebSubSubscription :one
+SELECT feed_id, created_at, updated_at, hub_url, topic_url, callback_url, secret, state, requested_at, lease_expires_at FROM websub_subscriptions WHERE feed_id = $1
+`
+
+func (q *Queries) GetWebSubSubscription(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscription, feedID)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.CallbackUrl,
+		&i.Secret,
+		&i.State,
+		&i.RequestedAt,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
+
+const setWebSubSubscriptionState = `-- name: SetWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET state = $2,
+    updated_at = NOW()
+WHERE feed_id = $1
+`
+
+type SetWebSubSubscriptionStateParams struct {
+	FeedID uuid.UUID
+	State  string
+}
+
+func (q *Queries) SetWebSubSubscriptionState(ctx context.Context, arg SetWebSubSubscriptionStateParams) error {
+	_, err := q.db.ExecContext(ctx, setWebSubSubscriptionState, arg.FeedID, arg.State)
+	return err
+}
+
+const upsertWebSubSubscription = `-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (feed_id, created_at, updated_at, hub_url, topic_url, callback_url, secret, state, requested_at)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    'pending',
+    NOW()
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    callback_url = EXCLUDED.callback_url,
+    state = 'pending',
+    requested_at = NOW(),
+    updated_at = NOW()
+RETURNING feed_id, created_at, updated_at, hub_url, topic_url, callback_url, secret, state, requested_at, lease_expires_at
+`
+
+type UpsertWebSubSubscriptionParams struct {
+	FeedID      uuid.UUID
+	HubUrl      string
+	TopicUrl    string
+	CallbackUrl string
+	Secret      string
+}
+
+func (q *Queries) UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, upsertWebSubSubscription,
+		arg.FeedID,
+		arg.HubUrl,
+		arg.TopicUrl,
+		arg.CallbackUrl,
+		arg.Secret,
+	)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HubUrl,
+		&i.TopicUrl,
+		&i.CallbackUrl,
+		&i.Secret,
+		&i.State,
+		&i.RequestedAt,
+		&i.LeaseExpiresAt,
+	)
+	return i, err
+}
@@ -0,0 +1,185 @@
+package digest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Digest is a summary of unread posts, grouped by feed
+type Digest struct {
+	Title string
+	// Date is when the digest was put together, in the reader's timezone
+	Date  time.Time
+	Feeds []Feed
+}
+
+// Feed is a feed with unread posts
+type Feed struct {
+	Name    string
+	URL     string
+	SiteURL string
+	Posts   []Post
+}
+
+// Post is a single unread post. Summary is plain text.
+type Post struct {
+	Title     string
+	URL       string
+	Author    string
+	Published time.Time
+	Summary   string
+}
+
+// Count is the number of posts in the digest
+func (d Digest) Count() int {
+	n := 0
+	for _, feed := range d.Feeds {
+		n += len(feed.Posts)
+	}
+	return n
+}
+
+// Text renders the digest as plain text
+func Text(d Digest) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v\n%v\n", d.Title, d.Date.Format("Monday 2 January 2006"))
+	for _, feed := range d.Feeds {
+		fmt.Fprintf(&sb, "\n== %v ==\n", feed.Name)
+		if link := feed.Link(); link != "" {
+			sb.WriteString(link + "\n")
+		}
+		for _, post := range feed.Posts {
+			fmt.Fprintf(&sb, "\n* %v\n  %v\n  %v\n", post.Title, post.URL, post.Byline())
+			if post.Summary != "" {
+				sb.WriteString("  " + post.Summary + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// HTML renders the digest as an HTML document
+func HTML(d Digest) (string, error) {
+	var sb strings.Builder
+	if err := htmlTemplate.Execute(&sb, d); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Message builds an email carrying the digest as both plain text and HTML,
+// ready to hand to an SMTP server
+func Message(from, to, subject string, d Digest) ([]byte, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %v", from, err)
+	}
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %v", to, err)
+	}
+	html, err := HTML(d)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	_, domain, _ := strings.Cut(sender.Address, "@")
+
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+	headers := [][2]string{
+		{"From", sender.String()},
+		{"To", recipient.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", d.Date.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%v@%v>", hex.EncodeToString(id), domain)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + body.Boundary()},
+	}
+	var msg bytes.Buffer
+	for _, header := range headers {
+		fmt.Fprintf(&msg, "%v: %v\r\n", header[0], header[1])
+	}
+	msg.WriteString("\r\n")
+
+	// clients show the last part they understand, so HTML goes last
+	for _, part := range [][2]string{
+		{"text/plain; charset=utf-8", Text(d)},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part[0]},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part[1])); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	msg.Write(buf.Bytes())
+	return msg.Bytes(), nil
+}
+
+// Link is the feed's website, or the feed itself if it has none
+func (f Feed) Link() string {
+	if f.SiteURL != "" {
+		return f.SiteURL
+	}
+	return f.URL
+}
+
+// Byline is who wrote the post and when
+func (p Post) Byline() string {
+	published := p.Published.Format("Mon 2 Jan 15:04")
+	if p.Author == "" {
+		return published
+	}
+	return "by " + p.Author + ", " + published
+}
+
+var htmlTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 0 auto; color: #222;">
+<h1 style="font-size: 1.4em;">{{.Title}}</h1>
+<p style="color: #666;">{{.Date.Format "Monday 2 January 2006"}}</p>
+{{- range .Feeds}}
+<h2 style="font-size: 1.2em; border-bottom: 1px solid #ddd; padding-bottom: 0.2em;"><a href="{{.Link}}" style="color: #222;">{{.Name}}</a></h2>
+{{- range .Posts}}
+<div style="margin-bottom: 1em;">
+<a href="{{.URL}}" style="font-weight: bold;">{{.Title}}</a>
+<div style="color: #666; font-size: 0.9em;">{{.Byline}}</div>
+{{- if .Summary}}
+<p style="margin: 0.3em 0;">{{.Summary}}</p>
+{{- end}}
+</div>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
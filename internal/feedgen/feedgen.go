@@ -0,0 +1,231 @@
+package feedgen
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Feed is an aggregated timeline to be published as Atom or RSS
+type Feed struct {
+	ID       string
+	Title    string
+	Subtitle string
+	// Link is the url the document itself will be served from, if known
+	Link    string
+	Author  string
+	Updated time.Time
+	Items   []Item
+}
+
+// Item is a single post in a published feed
+type Item struct {
+	ID          string
+	Title       string
+	Link        string
+	Summary     string
+	Content     string
+	Author      string
+	Categories  []string
+	Published   time.Time
+	SourceTitle string
+	SourceLink  string
+}
+
+const generator = "gator"
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Author    *atomPerson `xml:"author"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Links      []atomLink     `xml:"link"`
+	Author     *atomPerson    `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+	Source     *atomSource    `xml:"source"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomSource struct {
+	Title string     `xml:"title"`
+	Links []atomLink `xml:"link"`
+}
+
+// WriteAtom renders the feed as an Atom 1.0 document
+func WriteAtom(w io.Writer, f Feed) error {
+	doc := atomFeed{
+		Title:     f.Title,
+		Subtitle:  f.Subtitle,
+		ID:        f.ID,
+		Updated:   f.Updated.UTC().Format(time.RFC3339),
+		Author:    &atomPerson{Name: f.Author},
+		Generator: generator,
+	}
+	if f.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Rel: "self", Type: "application/atom+xml", Href: f.Link})
+	}
+
+	for _, item := range f.Items {
+		published := item.Published.UTC().Format(time.RFC3339)
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.ID,
+			Updated:   published,
+			Published: published,
+			Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: item.Link}},
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		if item.Summary != "" {
+			entry.Summary = &atomText{Type: "html", Body: item.Summary}
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Body: item.Content}
+		}
+		if item.SourceTitle != "" {
+			entry.Source = &atomSource{Title: item.SourceTitle}
+			if item.SourceLink != "" {
+				entry.Source.Links = []atomLink{{Rel: "self", Href: item.SourceLink}}
+			}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return encode(w, doc)
+}
+
+type rssDocument struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	XMLNSAtom string     `xml:"xmlns:atom,attr"`
+	XMLNSDC   string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string        `xml:"title"`
+	Link          string        `xml:"link"`
+	Description   string        `xml:"description"`
+	LastBuildDate string        `xml:"lastBuildDate"`
+	Generator     string        `xml:"generator"`
+	AtomLink      *rssAtomLink  `xml:"atom:link"`
+	Items         []rssItemNode `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type rssItemNode struct {
+	Title       string     `xml:"title"`
+	Link        string     `xml:"link"`
+	GUID        rssGUID    `xml:"guid"`
+	PubDate     string     `xml:"pubDate"`
+	Creator     string     `xml:"dc:creator,omitempty"`
+	Categories  []string   `xml:"category"`
+	Description string     `xml:"description"`
+	Source      *rssSource `xml:"source"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssSource struct {
+	URL   string `xml:"url,attr"`
+	Title string `xml:",chardata"`
+}
+
+// WriteRSS renders the feed as an RSS 2.0 document
+func WriteRSS(w io.Writer, f Feed) error {
+	doc := rssDocument{
+		Version:   "2.0",
+		XMLNSAtom: "http://www.w3.org/2005/Atom",
+		XMLNSDC:   "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Subtitle,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Generator:     generator,
+		},
+	}
+	if doc.Channel.Description == "" {
+		doc.Channel.Description = f.Title
+	}
+	if f.Link != "" {
+		doc.Channel.AtomLink = &rssAtomLink{Rel: "self", Type: "application/rss+xml", Href: f.Link}
+	}
+
+	for _, item := range f.Items {
+		node := rssItemNode{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{Value: item.ID},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+			Creator:     item.Author,
+			Categories:  item.Categories,
+			Description: item.Summary,
+		}
+		if item.Content != "" {
+			node.Description = item.Content
+		}
+		// RSS requires a url on source elements, so skip it without one
+		if item.SourceTitle != "" && item.SourceLink != "" {
+			node.Source = &rssSource{URL: item.SourceLink, Title: item.SourceTitle}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, node)
+	}
+	return encode(w, doc)
+}
+
+func encode(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
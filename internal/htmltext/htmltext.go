@@ -0,0 +1,272 @@
+package htmltext
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Render converts an HTML fragment (such as an RSS item description) into
+// plain text wrapped at the given width. Scripts and styles are dropped and
+// links are replaced by numbered markers listed as footnotes at the end.
+func Render(fragment string, width int) string {
+	if width < 20 {
+		width = 20
+	}
+	return render(fragment, &renderer{width: width})
+}
+
+// Markdown converts an HTML fragment into unwrapped Markdown. Headings,
+// emphasis, code and images are kept, and links become numbered reference
+// links defined at the end.
+func Markdown(fragment string) string {
+	return render(fragment, &renderer{width: math.MaxInt, markdown: true})
+}
+
+func render(fragment string, r *renderer) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		// fall back to the raw text if the markup can't be parsed at all
+		return fragment
+	}
+
+	for _, n := range nodes {
+		r.walk(n)
+	}
+	r.flush()
+
+	// trim blank lines from the end of the body
+	for len(r.out) > 0 && r.out[len(r.out)-1] == "" {
+		r.out = r.out[:len(r.out)-1]
+	}
+
+	// append the collected links as footnotes
+	if len(r.links) > 0 {
+		r.out = append(r.out, "")
+		for i, link := range r.links {
+			if r.markdown {
+				r.out = append(r.out, fmt.Sprintf("[%d]: %s", i+1, link))
+				continue
+			}
+			r.out = append(r.out, fmt.Sprintf("[%d] %s", i+1, link))
+		}
+	}
+	return strings.Join(r.out, "\n")
+}
+
+type renderer struct {
+	width    int
+	markdown bool
+	out      []string
+	inline   strings.Builder
+	// prefixes are prepended to every line, e.g. list indentation or quotes
+	prefixes []string
+	// bullet is written before the first line of the next flushed block
+	bullet string
+	// lists holds a counter for each open list, -1 for unordered lists
+	lists []int
+	links []string
+	pre   int
+}
+
+func (r *renderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.inline.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.walkChildren(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Noscript, atom.Iframe, atom.Template, atom.Svg, atom.Object:
+		return
+	case atom.Br:
+		// preformatted text keeps its own line breaks rather than being flushed
+		if r.pre > 0 {
+			r.inline.WriteString("\n")
+			break
+		}
+		if strings.TrimSpace(r.inline.String()) == "" {
+			r.blankLine()
+		}
+		r.flush()
+	case atom.Hr:
+		r.paragraph()
+		r.out = append(r.out, r.prefix()+strings.Repeat("-", min(r.width-len(r.prefix()), 40)))
+		r.paragraph()
+	case atom.Img:
+		if src := attr(n, "src"); r.markdown && src != "" && safeURL(src) {
+			fmt.Fprintf(&r.inline, " ![%s](%s) ", attr(n, "alt"), src)
+		} else if alt := attr(n, "alt"); alt != "" {
+			r.inline.WriteString(" [image: " + alt + "] ")
+		}
+	case atom.A:
+		href := attr(n, "href")
+		// links Sanitize would drop aren't listed either, since Markdown may end up as HTML
+		if href == "" || strings.HasPrefix(href, "#") || !safeURL(href) {
+			r.walkChildren(n)
+			break
+		}
+		r.links = append(r.links, href)
+		if r.markdown {
+			r.inline.WriteString("[")
+			r.walkChildren(n)
+			fmt.Fprintf(&r.inline, "][%d]", len(r.links))
+			break
+		}
+		r.walkChildren(n)
+		fmt.Fprintf(&r.inline, "[%d]", len(r.links))
+	case atom.Pre:
+		r.paragraph()
+		r.pre++
+		r.walkChildren(n)
+		r.pre--
+		for _, line := range strings.Split(strings.Trim(r.inline.String(), "\n"), "\n") {
+			r.out = append(r.out, r.prefix()+"    "+line)
+		}
+		r.inline.Reset()
+		r.paragraph()
+	case atom.Blockquote:
+		r.paragraph()
+		r.prefixes = append(r.prefixes, "> ")
+		r.walkChildren(n)
+		r.flush()
+		r.prefixes = r.prefixes[:len(r.prefixes)-1]
+		r.paragraph()
+	case atom.Ul, atom.Ol:
+		if len(r.lists) == 0 {
+			r.paragraph()
+		} else {
+			r.flush()
+		}
+		counter := -1
+		if n.DataAtom == atom.Ol {
+			counter = 0
+		}
+		r.lists = append(r.lists, counter)
+		if len(r.lists) > 1 {
+			r.prefixes = append(r.prefixes, "  ")
+		}
+		r.walkChildren(n)
+		r.flush()
+		if len(r.lists) > 1 {
+			r.prefixes = r.prefixes[:len(r.prefixes)-1]
+		}
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.paragraph()
+		}
+	case atom.Li:
+		r.flush()
+		r.bullet = "* "
+		if len(r.lists) > 0 && r.lists[len(r.lists)-1] >= 0 {
+			r.lists[len(r.lists)-1]++
+			r.bullet = fmt.Sprintf("%d. ", r.lists[len(r.lists)-1])
+		}
+		r.walkChildren(n)
+		r.flush()
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.paragraph()
+		if r.markdown {
+			r.bullet = strings.Repeat("#", int(n.Data[1]-'0')) + " "
+		}
+		r.walkChildren(n)
+		r.paragraph()
+	case atom.Strong, atom.B, atom.Em, atom.I, atom.Code:
+		mark := ""
+		if r.markdown && r.pre == 0 {
+			mark = map[atom.Atom]string{atom.Strong: "**", atom.B: "**", atom.Em: "*", atom.I: "*", atom.Code: "`"}[n.DataAtom]
+		}
+		r.inline.WriteString(mark)
+		r.walkChildren(n)
+		r.inline.WriteString(mark)
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer,
+		atom.Table, atom.Figure, atom.Figcaption, atom.Dl, atom.Dt, atom.Dd:
+		r.paragraph()
+		r.walkChildren(n)
+		r.paragraph()
+	case atom.Tr:
+		r.flush()
+		r.walkChildren(n)
+		r.flush()
+	case atom.Td, atom.Th:
+		r.walkChildren(n)
+		r.inline.WriteString("  ")
+	default:
+		r.walkChildren(n)
+	}
+}
+
+func (r *renderer) walkChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c)
+	}
+}
+
+func (r *renderer) prefix() string {
+	return strings.Join(r.prefixes, "")
+}
+
+// flush wraps any pending inline text into output lines
+func (r *renderer) flush() {
+	if r.pre > 0 {
+		return
+	}
+	words := strings.Fields(r.inline.String())
+	r.inline.Reset()
+	if len(words) == 0 {
+		return
+	}
+
+	first := r.prefix() + r.bullet
+	rest := r.prefix() + strings.Repeat(" ", len(r.bullet))
+	r.bullet = ""
+
+	line := first
+	empty := true
+	for _, word := range words {
+		if !empty && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > r.width {
+			r.out = append(r.out, line)
+			line = rest
+			empty = true
+		}
+		if !empty {
+			line += " "
+		}
+		line += word
+		empty = false
+	}
+	r.out = append(r.out, line)
+}
+
+// paragraph ends the current block and separates it from the next one
+func (r *renderer) paragraph() {
+	r.flush()
+	r.blankLine()
+}
+
+func (r *renderer) blankLine() {
+	if len(r.out) > 0 && r.out[len(r.out)-1] != "" {
+		r.out = append(r.out, "")
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package htmltext
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	// Render and Markdown share a renderer, so each case checks both
+	tests := []struct {
+		name     string
+		in       string
+		text     string
+		markdown string
+	}{
+		{
+			name:     "br inside pre",
+			in:       "<pre>line one<br>line two\n  indented</pre><p>after</p>",
+			text:     "    line one\n    line two\n      indented\n\nafter",
+			markdown: "    line one\n    line two\n      indented\n\nafter",
+		},
+		{
+			name:     "blocks",
+			in:       "<h2>Title</h2><ul><li><strong>bold</strong> item</li><li>two</li></ul><ol><li>first</li></ol><blockquote>quoted</blockquote>",
+			text:     "Title\n\n* bold item\n* two\n\n1. first\n\n> quoted",
+			markdown: "## Title\n\n* **bold** item\n* two\n\n1. first\n\n> quoted",
+		},
+		{
+			name:     "link",
+			in:       `<p>Read <a href="https://go.dev/">Go</a></p>`,
+			text:     "Read Go[1]\n\n[1] https://go.dev/",
+			markdown: "Read [Go][1]\n\n[1]: https://go.dev/",
+		},
+		{
+			name:     "javascript link",
+			in:       `<p>Read <a href="javascript:alert(1)">this</a></p>`,
+			text:     "Read this",
+			markdown: "Read this",
+		},
+		{
+			name:     "mixed case javascript link",
+			in:       `<p>Read <a href="JaVaScRiPt:alert(1)">this</a></p>`,
+			text:     "Read this",
+			markdown: "Read this",
+		},
+		{
+			name:     "entity encoded javascript link",
+			in:       `<p>Read <a href="&#106;avascript&#x3a;alert(1)">this</a></p>`,
+			text:     "Read this",
+			markdown: "Read this",
+		},
+		{
+			name:     "event handler",
+			in:       `<p onclick="alert(1)">Hello</p>`,
+			text:     "Hello",
+			markdown: "Hello",
+		},
+		{
+			name:     "svg",
+			in:       `<p>Hello <svg><a href="https://evil.example"><text>svg text</text></a></svg>world</p>`,
+			text:     "Hello world",
+			markdown: "Hello world",
+		},
+		{
+			name:     "data image",
+			in:       `<p>An <img src="data:image/png;base64,AAAA" alt="inline"></p>`,
+			text:     "An [image: inline]",
+			markdown: "An [image: inline]",
+		},
+		{
+			name:     "image",
+			in:       `<p>An <img src="https://go.dev/logo.png" alt="logo"></p>`,
+			text:     "An [image: logo]",
+			markdown: "An ![logo](https://go.dev/logo.png)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.in, 80); got != tt.text {
+				t.Errorf("Render() = %q, want %q", got, tt.text)
+			}
+			if got := Markdown(tt.in); got != tt.markdown {
+				t.Errorf("Markdown() = %q, want %q", got, tt.markdown)
+			}
+		})
+	}
+}
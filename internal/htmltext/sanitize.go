@@ -0,0 +1,159 @@
+package htmltext
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedElements are kept by Sanitize, mapped to the attributes they may keep
+var allowedElements = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: nil,
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Li:         nil,
+	atom.Ol:         nil,
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          nil,
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// droppedElements are removed together with everything inside them
+var droppedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Head:     true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Math:     true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+}
+
+// Sanitize reduces an HTML fragment to a small set of formatting elements
+// so it can be embedded in a page. Scripts, styles, event handlers and
+// links to anything but http, https and mailto urls are removed; other
+// unknown elements are replaced by their content.
+func Sanitize(fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return html.EscapeString(fragment)
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		sanitizeNode(&sb, n)
+	}
+	return sb.String()
+}
+
+func sanitizeNode(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		sanitizeChildren(sb, n)
+		return
+	}
+
+	if droppedElements[n.DataAtom] {
+		return
+	}
+	allowed, ok := allowedElements[n.DataAtom]
+	if !ok {
+		sanitizeChildren(sb, n)
+		return
+	}
+
+	sb.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		if a.Namespace != "" || !contains(allowed, a.Key) {
+			continue
+		}
+		if (a.Key == "href" || a.Key == "src") && !safeURL(a.Val) {
+			continue
+		}
+		sb.WriteString(" " + a.Key + `="` + html.EscapeString(a.Val) + `"`)
+	}
+	if n.DataAtom == atom.A {
+		sb.WriteString(` rel="nofollow noopener"`)
+	}
+	sb.WriteString(">")
+	if n.DataAtom == atom.Br || n.DataAtom == atom.Hr || n.DataAtom == atom.Img {
+		return
+	}
+	sanitizeChildren(sb, n)
+	sb.WriteString("</" + n.Data + ">")
+}
+
+func sanitizeChildren(sb *strings.Builder, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(sb, c)
+	}
+}
+
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto", "":
+		return true
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
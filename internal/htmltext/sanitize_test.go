@@ -0,0 +1,74 @@
+package htmltext
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "link",
+			in:   `<a href="https://go.dev/" title="Go">Go</a>`,
+			want: `<a href="https://go.dev/" title="Go" rel="nofollow noopener">Go</a>`,
+		},
+		{
+			name: "javascript link",
+			in:   `<a href="javascript:alert(1)">this</a>`,
+			want: `<a rel="nofollow noopener">this</a>`,
+		},
+		{
+			name: "mixed case javascript link",
+			in:   `<a href="JaVaScRiPt:alert(1)">this</a>`,
+			want: `<a rel="nofollow noopener">this</a>`,
+		},
+		{
+			name: "entity encoded javascript link",
+			in:   `<a href="&#106;avascript&#x3a;alert(1)">this</a>`,
+			want: `<a rel="nofollow noopener">this</a>`,
+		},
+		{
+			name: "javascript link split by a tab",
+			in:   `<a href="java&#x09;script:alert(1)">this</a>`,
+			want: `<a rel="nofollow noopener">this</a>`,
+		},
+		{
+			name: "event handlers",
+			in:   `<p onclick="alert(1)" ONMOUSEOVER="alert(2)">Hello <img src="https://go.dev/logo.png" onerror="alert(3)"></p>`,
+			want: `<p>Hello <img src="https://go.dev/logo.png"></p>`,
+		},
+		{
+			name: "svg",
+			in:   `<p>Hello <svg onload="alert(1)"><a href="https://evil.example"><text>svg text</text></a></svg>world</p>`,
+			want: `<p>Hello world</p>`,
+		},
+		{
+			name: "math",
+			in:   `<p>x <math><mi xlink:href="javascript:alert(1)">y</mi></math> z</p>`,
+			want: `<p>x  z</p>`,
+		},
+		{
+			name: "data image",
+			in:   `<img src="data:image/png;base64,AAAA" alt="inline">`,
+			want: `<img alt="inline">`,
+		},
+		{
+			name: "script",
+			in:   `<p>Hello</p><script>alert(1)</script>`,
+			want: `<p>Hello</p>`,
+		},
+		{
+			name: "br inside pre",
+			in:   "<pre>line one<br>line two</pre>",
+			want: "<pre>line one<br>line two</pre>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package opml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Document is an OPML 1.0 or 2.0 subscription list
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+	OwnerName   string `xml:"ownerName,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is either a feed (when XMLURL is set) or a folder of outlines
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	URL      string    `xml:"url,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Entry is a feed found in a document, with the path of folders it was nested in
+type Entry struct {
+	Title   string
+	XMLURL  string
+	HTMLURL string
+	Folder  string
+}
+
+// Parse reads an OPML document
+func Parse(r io.Reader) (*Document, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charsetReader
+	// many exporters produce HTML entities and unescaped ampersands in titles
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid opml: %v", err)
+	}
+	if doc.XMLName.Local != "opml" {
+		return nil, fmt.Errorf("invalid opml: root element is %v", doc.XMLName.Local)
+	}
+	return &doc, nil
+}
+
+// Entries flattens the outline tree into a list of feeds. Nested folders
+// are joined with a slash, so a feed in tech > go has the folder "tech/go".
+func (d *Document) Entries() []Entry {
+	entries := make([]Entry, 0)
+	var walk func(outlines []Outline, folder string)
+	walk = func(outlines []Outline, folder string) {
+		for _, o := range outlines {
+			title := strings.TrimSpace(o.Title)
+			if title == "" {
+				title = strings.TrimSpace(o.Text)
+			}
+
+			// OPML 1.0 files sometimes put the feed url in the url attribute
+			feedURL := strings.TrimSpace(o.XMLURL)
+			if feedURL == "" && strings.EqualFold(o.Type, "rss") {
+				feedURL = strings.TrimSpace(o.URL)
+			}
+
+			if feedURL != "" || len(o.Outlines) == 0 {
+				entries = append(entries, Entry{
+					Title:   title,
+					XMLURL:  feedURL,
+					HTMLURL: strings.TrimSpace(o.HTMLURL),
+					Folder:  folder,
+				})
+			}
+			if len(o.Outlines) > 0 {
+				walk(o.Outlines, joinFolder(folder, title))
+			}
+		}
+	}
+	walk(d.Body.Outlines, "")
+	return entries
+}
+
+func joinFolder(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	if name == "" {
+		return parent
+	}
+	return parent + "/" + name
+}
+
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	// decodes the single byte charsets old OPML exporters declare
+	switch strings.ToLower(label) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1", "windows-1252", "cp1252":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		for _, b := range data {
+			buf.WriteRune(rune(b))
+		}
+		return &buf, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %v", label)
+}
+
+// Build creates an OPML 2.0 document from a list of feeds, nesting them in
+// outlines for each part of their slash separated folder path
+func Build(title string, entries []Entry) *Document {
+	doc := &Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123),
+		},
+	}
+
+	for _, entry := range entries {
+		outlines := &doc.Body.Outlines
+		if entry.Folder != "" {
+			for _, name := range strings.Split(entry.Folder, "/") {
+				outlines = folderOutlines(outlines, name)
+			}
+		}
+		*outlines = append(*outlines, Outline{
+			Text:    entry.Title,
+			Title:   entry.Title,
+			Type:    "rss",
+			XMLURL:  entry.XMLURL,
+			HTMLURL: entry.HTMLURL,
+		})
+	}
+	return doc
+}
+
+func folderOutlines(outlines *[]Outline, name string) *[]Outline {
+	// returns the children of the folder outline with this name, adding it if needed
+	for i := range *outlines {
+		o := &(*outlines)[i]
+		if o.XMLURL == "" && o.Text == name {
+			return &o.Outlines
+		}
+	}
+	*outlines = append(*outlines, Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1].Outlines
+}
+
+// Write encodes the document as indented XML
+func (d *Document) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,276 @@
+package postexport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/htmltext"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{"md", "html", "json", "csv"}
+
+// Post is a single exported post. Content is the post's HTML as found in the feed.
+type Post struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	Feed       string    `json:"feed"`
+	FeedURL    string    `json:"feed_url"`
+	Author     string    `json:"author,omitempty"`
+	Published  time.Time `json:"published"`
+	Categories []string  `json:"categories"`
+	Tags       []string  `json:"tags"`
+	Read       bool      `json:"read"`
+	Starred    bool      `json:"starred"`
+	Content    string    `json:"content"`
+}
+
+// Document is a set of posts exported together
+type Document struct {
+	Title    string    `json:"title"`
+	Exported time.Time `json:"exported"`
+	Posts    []Post    `json:"posts"`
+}
+
+// Write encodes every post in the document as one combined file
+func Write(w io.Writer, format string, doc Document) error {
+	switch format {
+	case "md":
+		return writeMarkdown(w, doc)
+	case "html":
+		return documentTemplate.Execute(w, doc)
+	case "json":
+		return writeJSON(w, doc)
+	case "csv":
+		return writeCSV(w, doc.Posts)
+	}
+	return fmt.Errorf("unknown format %q, expected one of %v", format, strings.Join(Formats, ", "))
+}
+
+// WritePost encodes a single post as a file of its own. CSV has no
+// sensible single post layout, so it is only available through Write.
+func WritePost(w io.Writer, format string, post Post) error {
+	switch format {
+	case "md":
+		return writeMarkdownPost(w, post)
+	case "html":
+		return postTemplate.Execute(w, post)
+	case "json":
+		return writeJSON(w, post)
+	case "csv":
+		return fmt.Errorf("csv can only be exported as a single combined file")
+	}
+	return fmt.Errorf("unknown format %q, expected one of %v", format, strings.Join(Formats, ", "))
+}
+
+func writeMarkdown(w io.Writer, doc Document) error {
+	// one front matter block describing the export, then a section per post
+	var sb strings.Builder
+	frontMatter(&sb, [][2]string{
+		{"title", yamlString(doc.Title)},
+		{"exported", doc.Exported.UTC().Format(time.RFC3339)},
+		{"count", strconv.Itoa(len(doc.Posts))},
+	})
+	fmt.Fprintf(&sb, "\n# %v\n", doc.Title)
+	for _, post := range doc.Posts {
+		fmt.Fprintf(&sb, "\n## [%v](%v)\n\n", markdownText(post.Title), post.URL)
+		sb.WriteString(markdownMeta(post))
+		if body := htmltext.Markdown(post.Content); body != "" {
+			// demote headings in the post so they nest under its title
+			for _, line := range strings.Split(body, "\n") {
+				if strings.HasPrefix(line, "#") && strings.HasPrefix(strings.TrimLeft(line, "#"), " ") {
+					line = "##" + line
+				}
+				sb.WriteString("\n" + line)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeMarkdownPost(w io.Writer, post Post) error {
+	var sb strings.Builder
+	fields := [][2]string{
+		{"title", yamlString(post.Title)},
+		{"url", yamlString(post.URL)},
+		{"feed", yamlString(post.Feed)},
+		{"feed_url", yamlString(post.FeedURL)},
+	}
+	if post.Author != "" {
+		fields = append(fields, [2]string{"author", yamlString(post.Author)})
+	}
+	fields = append(fields,
+		[2]string{"published", post.Published.UTC().Format(time.RFC3339)},
+		[2]string{"categories", yamlList(post.Categories)},
+		[2]string{"tags", yamlList(post.Tags)},
+		[2]string{"read", strconv.FormatBool(post.Read)},
+		[2]string{"starred", strconv.FormatBool(post.Starred)},
+	)
+	frontMatter(&sb, fields)
+	fmt.Fprintf(&sb, "\n# %v\n", markdownText(post.Title))
+	if body := htmltext.Markdown(post.Content); body != "" {
+		sb.WriteString("\n" + body + "\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func frontMatter(sb *strings.Builder, fields [][2]string) {
+	sb.WriteString("---\n")
+	for _, field := range fields {
+		fmt.Fprintf(sb, "%v: %v\n", field[0], field[1])
+	}
+	sb.WriteString("---\n")
+}
+
+func markdownMeta(post Post) string {
+	// the per post metadata of a combined markdown document
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "- feed: [%v](%v)\n", markdownText(post.Feed), post.FeedURL)
+	if post.Author != "" {
+		fmt.Fprintf(&sb, "- author: %v\n", markdownText(post.Author))
+	}
+	fmt.Fprintf(&sb, "- published: %v\n", post.Published.UTC().Format(time.RFC3339))
+	if len(post.Categories) > 0 {
+		fmt.Fprintf(&sb, "- categories: %v\n", strings.Join(post.Categories, ", "))
+	}
+	if len(post.Tags) > 0 {
+		fmt.Fprintf(&sb, "- tags: %v\n", strings.Join(post.Tags, ", "))
+	}
+	if post.Starred {
+		sb.WriteString("- starred\n")
+	}
+	return sb.String()
+}
+
+// yamlString quotes s for YAML. JSON strings are valid YAML scalars.
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func yamlList(list []string) string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = yamlString(s)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func markdownText(s string) string {
+	// escapes the characters that would change how a title renders
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`").Replace(s)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeCSV(w io.Writer, posts []Post) error {
+	// content is converted to plain text so it stays readable in a spreadsheet
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"id", "title", "url", "feed", "feed_url", "author", "published", "categories", "tags", "read", "starred", "content"})
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		err := cw.Write([]string{
+			post.ID,
+			post.Title,
+			post.URL,
+			post.Feed,
+			post.FeedURL,
+			post.Author,
+			post.Published.UTC().Format(time.RFC3339),
+			strings.Join(post.Categories, ";"),
+			strings.Join(post.Tags, ";"),
+			strconv.FormatBool(post.Read),
+			strconv.FormatBool(post.Starred),
+			htmltext.Render(post.Content, 80),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var templateFuncs = template.FuncMap{
+	"sanitize": func(content string) template.HTML {
+		return template.HTML(htmltext.Sanitize(content))
+	},
+	"rfc3339": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+	"join": strings.Join,
+}
+
+const articleTemplate = `{{define "article"}}<article>
+<header>
+<h1><a href="{{.URL}}">{{.Title}}</a></h1>
+<dl>
+<dt>Feed</dt><dd><a href="{{.FeedURL}}">{{.Feed}}</a></dd>
+{{- if .Author}}
+<dt>Author</dt><dd>{{.Author}}</dd>
+{{- end}}
+<dt>Published</dt><dd><time datetime="{{rfc3339 .Published}}">{{.Published.Format "02 Jan 2006 15:04 MST"}}</time></dd>
+{{- if .Categories}}
+<dt>Categories</dt><dd>{{join .Categories ", "}}</dd>
+{{- end}}
+{{- if .Tags}}
+<dt>Tags</dt><dd>{{join .Tags ", "}}</dd>
+{{- end}}
+{{- if .Starred}}
+<dt>Starred</dt><dd>yes</dd>
+{{- end}}
+</dl>
+</header>
+{{sanitize .Content}}
+</article>
+{{end}}`
+
+var postTemplate = template.Must(template.New("post").Funcs(templateFuncs).Parse(articleTemplate + `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="canonical" href="{{.URL}}">
+{{- if .Author}}
+<meta name="author" content="{{.Author}}">
+{{- end}}
+<meta name="gator:feed" content="{{.FeedURL}}">
+<meta name="gator:published" content="{{rfc3339 .Published}}">
+{{- if .Tags}}
+<meta name="keywords" content="{{join .Tags ", "}}">
+{{- end}}
+</head>
+<body>
+{{template "article" .}}</body>
+</html>
+`))
+
+var documentTemplate = template.Must(template.New("document").Funcs(templateFuncs).Parse(articleTemplate + `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta name="gator:exported" content="{{rfc3339 .Exported}}">
+<meta name="gator:count" content="{{len .Posts}}">
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Posts}}{{template "article" .}}{{end}}</body>
+</html>
+`))
@@ -0,0 +1,278 @@
+package site
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/feedgen"
+)
+
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+// Site is the content of a generated static site
+type Site struct {
+	Title string
+	// BaseURL is where the site will be hosted, used for absolute links in the Atom feed
+	BaseURL string
+	PerPage int
+	// Posts are expected newest first
+	Posts []Post
+}
+
+// Post is a single aggregated post. Summary is plain text, never HTML from the feed.
+type Post struct {
+	ID          string
+	Title       string
+	URL         string
+	FeedTitle   string
+	FeedURL     string
+	FeedSiteURL string
+	FeedSlug    string
+	Author      string
+	Summary     string
+	Published   time.Time
+}
+
+// FeedSummary is a feed listed on the feeds page
+type FeedSummary struct {
+	Title   string
+	Slug    string
+	SiteURL string
+	Count   int
+}
+
+type pageData struct {
+	Site      *Site
+	Title     string
+	Heading   string
+	Root      string
+	Posts     []Post
+	Feeds     []FeedSummary
+	Page      int
+	Pages     int
+	PrevURL   string
+	NextURL   string
+	Generated time.Time
+}
+
+// Build renders the site into outDir. Templates found in templatesDir
+// replace the built in ones with the same file name.
+func Build(outDir, templatesDir string, site *Site) error {
+	if site.PerPage < 1 {
+		site.PerPage = 20
+	}
+	list, err := loadTemplate(templatesDir, "list.html")
+	if err != nil {
+		return err
+	}
+	feedsPage, err := loadTemplate(templatesDir, "feeds.html")
+	if err != nil {
+		return err
+	}
+
+	b := builder{
+		outDir:    outDir,
+		site:      site,
+		generated: time.Now(),
+	}
+
+	// the front page timeline
+	if err := b.writeListing(list, "", site.Title, "Latest posts", site.Posts); err != nil {
+		return err
+	}
+
+	// one timeline per feed, keeping the order feeds first appear in
+	feeds := make([]FeedSummary, 0)
+	byFeed := make(map[string][]Post)
+	for _, post := range site.Posts {
+		if _, ok := byFeed[post.FeedSlug]; !ok {
+			feeds = append(feeds, FeedSummary{Title: post.FeedTitle, Slug: post.FeedSlug, SiteURL: post.FeedSiteURL})
+		}
+		byFeed[post.FeedSlug] = append(byFeed[post.FeedSlug], post)
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		return strings.ToLower(feeds[i].Title) < strings.ToLower(feeds[j].Title)
+	})
+	for i := range feeds {
+		posts := byFeed[feeds[i].Slug]
+		feeds[i].Count = len(posts)
+		dir := filepath.Join("feeds", feeds[i].Slug)
+		if err := b.writeListing(list, dir, feeds[i].Title+" - "+site.Title, feeds[i].Title, posts); err != nil {
+			return err
+		}
+	}
+
+	err = b.writePage(feedsPage, "feeds.html", pageData{
+		Title:   "Feeds - " + site.Title,
+		Heading: "Feeds",
+		Feeds:   feeds,
+	})
+	if err != nil {
+		return err
+	}
+	return b.writeAtom()
+}
+
+// Slug turns a feed title into a file name safe path segment
+func Slug(title string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(sb.String(), "-")
+	if slug == "" {
+		slug = "feed"
+	}
+	return slug
+}
+
+func loadTemplate(templatesDir, name string) (*template.Template, error) {
+	// parses the base layout together with one page template
+	tmpl := template.New("base")
+	for _, file := range []string{"base.html", name} {
+		text, err := readTemplate(templatesDir, file)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tmpl.Parse(text); err != nil {
+			return nil, fmt.Errorf("template %v: %v", file, err)
+		}
+	}
+	return tmpl, nil
+}
+
+func readTemplate(templatesDir, name string) (string, error) {
+	if templatesDir != "" {
+		data, err := os.ReadFile(filepath.Join(templatesDir, name))
+		if err == nil {
+			return string(data), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	data, err := defaultTemplates.ReadFile("templates/" + name)
+	return string(data), err
+}
+
+type builder struct {
+	outDir    string
+	site      *Site
+	generated time.Time
+}
+
+func (b *builder) writeListing(tmpl *template.Template, dir, title, heading string, posts []Post) error {
+	// writes dir/index.html and dir/page/N.html for a paginated list of posts
+	pages := (len(posts) + b.site.PerPage - 1) / b.site.PerPage
+	if pages == 0 {
+		pages = 1
+	}
+	for page := 1; page <= pages; page++ {
+		start := (page - 1) * b.site.PerPage
+		end := min(start+b.site.PerPage, len(posts))
+
+		data := pageData{
+			Title:   title,
+			Heading: heading,
+			Posts:   posts[start:end],
+			Page:    page,
+			Pages:   pages,
+		}
+		path := filepath.Join(dir, "index.html")
+		if page > 1 {
+			path = filepath.Join(dir, "page", fmt.Sprintf("%d.html", page))
+			data.Title = fmt.Sprintf("%v (page %d)", title, page)
+		}
+
+		// page links are relative to the page they appear on
+		if page == 2 {
+			data.PrevURL = "../index.html"
+		} else if page > 2 {
+			data.PrevURL = fmt.Sprintf("%d.html", page-1)
+		}
+		if page == 1 && pages > 1 {
+			data.NextURL = "page/2.html"
+		} else if page < pages {
+			data.NextURL = fmt.Sprintf("%d.html", page+1)
+		}
+
+		if err := b.writePage(tmpl, path, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *builder) writePage(tmpl *template.Template, path string, data pageData) error {
+	data.Site = b.site
+	data.Generated = b.generated
+	data.Root = strings.Repeat("../", strings.Count(filepath.ToSlash(path), "/"))
+
+	return b.create(path, func(w io.Writer) error {
+		return tmpl.ExecuteTemplate(w, "base", data)
+	})
+}
+
+func (b *builder) writeAtom() error {
+	feed := feedgen.Feed{
+		ID:      "urn:gator:site:" + Slug(b.site.Title),
+		Title:   b.site.Title,
+		Author:  "gator",
+		Updated: b.generated,
+	}
+	if b.site.BaseURL != "" {
+		feed.ID = strings.TrimSuffix(b.site.BaseURL, "/") + "/atom.xml"
+		feed.Link = feed.ID
+	}
+	if len(b.site.Posts) > 0 {
+		feed.Updated = b.site.Posts[0].Published
+	}
+	for _, post := range b.site.Posts[:min(len(b.site.Posts), 50)] {
+		feed.Items = append(feed.Items, feedgen.Item{
+			ID:          post.ID,
+			Title:       post.Title,
+			Link:        post.URL,
+			Summary:     template.HTMLEscapeString(post.Summary),
+			Author:      post.Author,
+			Published:   post.Published,
+			SourceTitle: post.FeedTitle,
+			SourceLink:  post.FeedURL,
+		})
+	}
+	return b.create("atom.xml", func(w io.Writer) error {
+		return feedgen.WriteAtom(w, feed)
+	})
+}
+
+func (b *builder) create(path string, write func(io.Writer) error) error {
+	full := filepath.Join(b.outDir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(full)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("%v: %v", path, err)
+	}
+	return file.Close()
+}
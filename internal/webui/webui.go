@@ -0,0 +1,104 @@
+package webui
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/htmltext"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// Page is what every page of the web UI shows around its content
+type Page struct {
+	Title string
+	// User is the logged in user's name, empty on the login page
+	User string
+	// CSRF is sent back with every form so requests from other sites are refused
+	CSRF string
+	// Path is the current page, where forms return to after posting
+	Path   string
+	Feeds  []Feed
+	Unread int64
+	Error  string
+}
+
+// Feed is a followed feed
+type Feed struct {
+	ID      string
+	Title   string
+	URL     string
+	SiteURL string
+	Folder  string
+	Unread  int64
+}
+
+// Post is a post in a listing. Content is the post's HTML as found in the
+// feed and is sanitized when rendered.
+type Post struct {
+	ID        string
+	Title     string
+	URL       string
+	FeedID    string
+	Feed      string
+	Author    string
+	Summary   string
+	Content   string
+	Published time.Time
+	Read      bool
+	Starred   bool
+}
+
+// PostsPage lists posts, either the whole timeline or a single feed
+type PostsPage struct {
+	Page
+	Heading string
+	// Feed is set when the page lists a single feed
+	Feed *Feed
+	// Folder is set when the timeline is limited to a folder
+	Folder     string
+	Posts      []Post
+	UnreadOnly bool
+	// AllURL and UnreadURL switch between all and unread posts
+	AllURL    string
+	UnreadURL string
+	PrevURL   string
+	NextURL   string
+}
+
+// PostPage shows a single post in full
+type PostPage struct {
+	Page
+	Post Post
+}
+
+// SubscriptionsPage manages the feeds the user follows
+type SubscriptionsPage struct {
+	Page
+	Folders []string
+}
+
+var funcs = template.FuncMap{
+	"sanitize": func(content string) template.HTML {
+		return template.HTML(htmltext.Sanitize(content))
+	},
+	"date": func(t time.Time) string {
+		return t.Format("02 Jan 2006 15:04")
+	},
+}
+
+var pages = map[string]*template.Template{}
+
+func init() {
+	for _, name := range []string{"login.html", "posts.html", "post.html", "subscriptions.html"} {
+		pages[name] = template.Must(template.New("base").Funcs(funcs).ParseFS(templateFiles, "templates/base.html", "templates/"+name))
+	}
+}
+
+// Render writes one of the pages: login.html, posts.html, post.html or subscriptions.html
+func Render(w io.Writer, name string, data any) error {
+	return pages[name].ExecuteTemplate(w, "base", data)
+}
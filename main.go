@@ -12,8 +12,9 @@ import (
 )
 
 type state struct {
-	db  *database.Queries
-	cfg *config.Config
+	db   *database.Queries
+	conn *sql.DB
+	cfg  *config.Config
 }
 
 func main() {
@@ -39,8 +40,9 @@ func main() {
 	
 	// create a new state
 	s := state{
-		db:  dbQueries,
-		cfg: &cfg,
+		db:   dbQueries,
+		conn: db,
+		cfg:  &cfg,
 	}
 	
 	// create an instance of the commands struct and initialize the cmdmap
@@ -51,15 +53,38 @@ func main() {
 	// register commands
 	cmds.register("login", handlerLogin)
 	cmds.register("register", handlerRegister)
-	cmds.register("reset", handlerReset)
+	cmds.register("reset", middlewareAdmin(handlerReset))
 	cmds.register("users", handlerGetUsers)
+	cmds.register("passwd", middlewareLoggedIn(handlerPasswd))
+	cmds.register("deluser", middlewareAdmin(handlerDeleteUser))
+	cmds.register("admin", middlewareAdmin(handlerAdmin))
+	cmds.register("audit", middlewareLoggedIn(handlerAudit))
+	cmds.register("webhook", middlewareLoggedIn(handlerWebhook))
+	cmds.register("digest", middlewareLoggedIn(handlerDigest))
 	cmds.register("agg", handlerAgg)
 	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
 	cmds.register("feeds", handlerFeeds)
 	cmds.register("follow", middlewareLoggedIn(handlerFollow))
 	cmds.register("following", middlewareLoggedIn(handlerFollowing))
 	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
+	cmds.register("retitle", middlewareLoggedIn(handlerRetitle))
 	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	cmds.register("show", middlewareLoggedIn(handlerShow))
+	cmds.register("search", middlewareLoggedIn(handlerSearch))
+	cmds.register("savedsearch", middlewareLoggedIn(handlerSavedSearch))
+	cmds.register("filter", middlewareLoggedIn(handlerFilter))
+	cmds.register("mark", middlewareLoggedIn(handlerMark))
+	cmds.register("tag", middlewareLoggedIn(handlerTag))
+	cmds.register("rules", middlewareLoggedIn(handlerRules))
+	cmds.register("folder", middlewareLoggedIn(handlerFolder))
+	cmds.register("import", middlewareLoggedIn(handlerImport))
+	cmds.register("export", middlewareLoggedIn(handlerExport))
+	cmds.register("publish", middlewareLoggedIn(handlerPublish))
+	cmds.register("site", middlewareLoggedIn(handlerSite))
+	cmds.register("serve", handlerServe)
+	cmds.register("token", middlewareLoggedIn(handlerToken))
+	cmds.register("backup", middlewareAdmin(handlerBackup))
+	cmds.register("restore", middlewareAdminOrEmpty(handlerRestore))
 	
 	// confirm the user input at least two args. Example: gator login
 	if len(os.Args) < 2 {
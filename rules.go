@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// errNotifyWithoutWebhook is the error for notify rules added before they
+// named a webhook to send posts to
+var errNotifyWithoutWebhook = errors.New("notify rule has no webhook, remove it and add it again with --webhook")
+
+// ruleTarget holds the parts of a post that rule conditions can match
+type ruleTarget struct {
+	Title       string
+	Description string
+	Content     string
+	Author      string
+	Categories  []string
+}
+
+func postRuleTarget(post database.Post) ruleTarget {
+	return ruleTarget{
+		Title:       post.Title,
+		Description: post.Description,
+		Content:     post.Content,
+		Author:      post.Author,
+		Categories:  post.Categories,
+	}
+}
+
+func itemRuleTarget(item RSSItem) ruleTarget {
+	return ruleTarget{
+		Title:       item.Title,
+		Description: item.Description,
+		Content:     item.Content,
+		Author:      item.Author,
+		Categories:  item.Categories,
+	}
+}
+
+func ruleMatches(field, matchType, pattern string, target ruleTarget) (bool, error) {
+	// reports whether a rule condition matches, case insensitively
+	var values []string
+	switch field {
+	case "title":
+		values = []string{target.Title}
+	case "description":
+		values = []string{target.Description, target.Content}
+	case "author":
+		values = []string{target.Author}
+	case "tag":
+		values = target.Categories
+	default:
+		return false, fmt.Errorf("unknown field %q", field)
+	}
+
+	if matchType == "regex" {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return false, err
+		}
+		for _, value := range values {
+			if re.MatchString(value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	pattern = strings.ToLower(pattern)
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), pattern) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func applyRules(s *state, rules []database.GetRulesForFeedRow, post database.Post) {
+	// runs the action of every rule matching a newly inserted post
+	target := postRuleTarget(post)
+	for _, rule := range rules {
+		ok, err := ruleMatches(rule.Field, rule.MatchType, rule.Pattern, target)
+		if err != nil {
+			log.Printf("rule %v for %v: %v", rule.Name, rule.UserName, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		err = runRuleAction(s, rule.UserID, rule.Action, rule.ActionArg, post)
+		if err != nil {
+			log.Printf("rule %v for %v: %v", rule.Name, rule.UserName, err)
+		}
+	}
+}
+
+func runRuleAction(s *state, userID uuid.UUID, action, arg string, post database.Post) error {
+	switch action {
+	case "read":
+		return s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+			UserID: userID,
+			PostID: post.ID,
+		})
+	case "star":
+		return s.db.StarPost(context.Background(), database.StarPostParams{
+			UserID: userID,
+			PostID: post.ID,
+		})
+	case "tag":
+		return s.db.TagPost(context.Background(), database.TagPostParams{
+			UserID: userID,
+			PostID: post.ID,
+			Tag:    arg,
+		})
+	case "notify":
+		// sends the post to the webhook named by the rule
+		if arg == "" {
+			return errNotifyWithoutWebhook
+		}
+		hook, err := s.db.GetWebhookByName(context.Background(), database.GetWebhookByNameParams{
+			UserID: userID,
+			Name:   arg,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no webhook named %q to notify", arg)
+		}
+		if err != nil {
+			return err
+		}
+		feed, err := s.db.GetFeedByID(context.Background(), post.FeedID)
+		if err != nil {
+			return err
+		}
+		return queueWebhookDelivery(s, hook.ID, hook.Name, hook.Template, feed, post)
+	}
+	return fmt.Errorf("unknown action %q", action)
+}
@@ -0,0 +1,19 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (id, created_at, actor_id, actor_name, action, target, details)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+);
+
+-- name: GetAuditLog :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg('actor')::text IS NULL OR actor_name = sqlc.narg('actor'))
+    AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+    AND (sqlc.narg('action')::text IS NULL OR action = sqlc.narg('action'))
+ORDER BY created_at DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,148 @@
+-- name: GetAllFolders :many
+SELECT folders.*, users.name AS user_name
+FROM folders
+JOIN users ON folders.user_id = users.id
+ORDER BY users.name, folders.name;
+
+-- name: GetAllFeedFollows :many
+SELECT feed_follows.*, users.name AS user_name, feeds.url AS feed_url, folders.name AS folder_name
+FROM feed_follows
+JOIN users ON feed_follows.user_id = users.id
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+ORDER BY users.name, feeds.url;
+
+-- name: GetAllPosts :many
+SELECT posts.*, feeds.url AS feed_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+ORDER BY posts.created_at;
+
+-- name: GetAllPostStates :many
+SELECT post_states.*, users.name AS user_name, posts.url AS post_url
+FROM post_states
+JOIN users ON post_states.user_id = users.id
+JOIN posts ON post_states.post_id = posts.id
+ORDER BY users.name, posts.url;
+
+-- name: GetAllPostTags :many
+SELECT post_tags.*, users.name AS user_name, posts.url AS post_url
+FROM post_tags
+JOIN users ON post_tags.user_id = users.id
+JOIN posts ON post_tags.post_id = posts.id
+ORDER BY users.name, posts.url, post_tags.tag;
+
+-- name: GetAllSavedSearches :many
+SELECT saved_searches.*, users.name AS user_name
+FROM saved_searches
+JOIN users ON saved_searches.user_id = users.id
+ORDER BY users.name, saved_searches.name;
+
+-- name: GetAllFilterRules :many
+SELECT filter_rules.*, users.name AS user_name, feeds.url AS feed_url
+FROM filter_rules
+JOIN users ON filter_rules.user_id = users.id
+LEFT JOIN feeds ON filter_rules.feed_id = feeds.id
+ORDER BY users.name, filter_rules.created_at;
+
+-- name: GetAllRules :many
+SELECT rules.*, users.name AS user_name, feeds.url AS feed_url
+FROM rules
+JOIN users ON rules.user_id = users.id
+LEFT JOIN feeds ON rules.feed_id = feeds.id
+ORDER BY users.name, rules.name;
+
+-- name: GetAllAPITokens :many
+SELECT api_tokens.*, users.name AS user_name
+FROM api_tokens
+JOIN users ON api_tokens.user_id = users.id
+ORDER BY users.name, api_tokens.name;
+
+-- name: GetAllWebhooks :many
+SELECT webhooks.*, users.name AS user_name, feeds.url AS feed_url, folders.name AS folder_name
+FROM webhooks
+JOIN users ON webhooks.user_id = users.id
+LEFT JOIN feeds ON webhooks.feed_id = feeds.id
+LEFT JOIN folders ON webhooks.folder_id = folders.id
+ORDER BY users.name, webhooks.name;
+
+-- name: GetAllDigests :many
+SELECT digests.*, users.name AS user_name
+FROM digests
+JOIN users ON digests.user_id = users.id
+ORDER BY users.name;
+
+-- name: GetAllDigestPosts :many
+SELECT digest_posts.*, users.name AS user_name, posts.url AS post_url
+FROM digest_posts
+JOIN users ON digest_posts.user_id = users.id
+JOIN posts ON digest_posts.post_id = posts.id
+ORDER BY users.name, posts.url;
+
+-- name: GetAllAuditLog :many
+SELECT * FROM audit_log
+ORDER BY created_at, id;
+
+-- name: RestorePostState :exec
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, read_at, starred_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at),
+    starred_at = COALESCE(post_states.starred_at, EXCLUDED.starred_at),
+    updated_at = NOW();
+
+-- name: RestoreSavedSearch :execrows
+INSERT INTO saved_searches (id, created_at, updated_at, user_id, name, query)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, name) DO NOTHING;
+
+-- name: RestoreFilterRule :execrows
+INSERT INTO filter_rules (id, created_at, updated_at, user_id, feed_id, field, match_type, pattern)
+SELECT $1, $2, $3, $4, $5, $6, $7, $8
+WHERE NOT EXISTS (
+    SELECT 1 FROM filter_rules
+    WHERE user_id = $4
+        AND feed_id IS NOT DISTINCT FROM $5
+        AND field = $6
+        AND match_type = $7
+        AND pattern = $8
+);
+
+-- name: RestoreRule :execrows
+INSERT INTO rules (id, created_at, updated_at, user_id, name, feed_id, field, match_type, pattern, action, action_arg)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (user_id, name) DO NOTHING;
+
+-- name: RestoreAPIToken :execrows
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, scopes, expires_at, last_used_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT DO NOTHING;
+
+-- name: RestoreWebhook :execrows
+INSERT INTO webhooks (id, created_at, updated_at, user_id, name, url, secret, feed_id, folder_id, field, match_type, pattern, template, content_type, rules_only)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+ON CONFLICT (user_id, name) DO NOTHING;
+
+-- name: RestoreDigest :execrows
+INSERT INTO digests (user_id, created_at, updated_at, email, schedule, hour, weekday, timezone, next_send_at, last_sent_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: RestoreDigestPost :exec
+INSERT INTO digest_posts (user_id, post_id, delivered_at)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: RestoreAuditLogEntry :execrows
+-- the log has no natural key, so an entry counts as present when one
+-- matching it in everything but the id exists
+INSERT INTO audit_log (id, created_at, actor_id, actor_name, action, target, details)
+SELECT $1, $2, $3, $4, $5, $6, $7
+WHERE NOT EXISTS (
+    SELECT 1 FROM audit_log
+    WHERE created_at = $2
+        AND actor_name = $4
+        AND action = $5
+        AND target = $6
+        AND details = $7
+);
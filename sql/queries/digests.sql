@@ -0,0 +1,79 @@
+-- name: UpsertDigest :one
+INSERT INTO digests (user_id, created_at, updated_at, email, schedule, hour, weekday, timezone, next_send_at)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (user_id) DO UPDATE
+SET email = EXCLUDED.email,
+    schedule = EXCLUDED.schedule,
+    hour = EXCLUDED.hour,
+    weekday = EXCLUDED.weekday,
+    timezone = EXCLUDED.timezone,
+    next_send_at = EXCLUDED.next_send_at,
+    last_error = '',
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetDigest :one
+SELECT * FROM digests WHERE user_id = $1;
+
+-- name: DeleteDigest :execrows
+DELETE FROM digests WHERE user_id = $1;
+
+-- name: ClaimDueDigests :many
+-- pushes the next send of due digests back by lease_until, so another
+-- process sending at the same time skips them
+WITH due AS (
+    SELECT digests.user_id
+    FROM digests
+    WHERE digests.next_send_at <= sqlc.arg('now')::timestamp
+    ORDER BY digests.next_send_at
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE digests
+SET next_send_at = sqlc.arg('lease_until')::timestamp,
+    updated_at = NOW()
+FROM due
+WHERE digests.user_id = due.user_id
+RETURNING digests.*;
+
+-- name: ScheduleDigest :exec
+UPDATE digests
+SET next_send_at = sqlc.arg('next_send_at'),
+    last_sent_at = COALESCE(sqlc.narg('sent_at'), last_sent_at),
+    last_error = sqlc.arg('error'),
+    updated_at = NOW()
+WHERE user_id = sqlc.arg('user_id');
+
+-- name: GetDigestPosts :many
+-- unread posts from followed feeds that no digest has included yet, newest first
+SELECT posts.id, posts.title, posts.url, posts.author, posts.description, posts.published_at, posts.created_at,
+    COALESCE(feed_follows.title, feeds.name)::text AS feed_name, feeds.url AS feed_url, feeds.site_url AS feed_site_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND post_states.read_at IS NULL
+    AND posts.created_at >= sqlc.arg('since')::timestamp
+    AND NOT EXISTS (
+        SELECT 1 FROM digest_posts
+        WHERE digest_posts.user_id = feed_follows.user_id AND digest_posts.post_id = posts.id
+    )
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
+LIMIT sqlc.arg('limit');
+
+-- name: MarkPostsDelivered :exec
+INSERT INTO digest_posts (user_id, post_id, delivered_at)
+SELECT sqlc.arg('user_id'), unnest(sqlc.arg('post_ids')::uuid[]), NOW()
+ON CONFLICT DO NOTHING;
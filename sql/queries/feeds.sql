@@ -16,13 +16,21 @@ SELECT * FROM feeds WHERE id = $1;
 -- name: GetFeedByUrl :one
 SELECT * FROM feeds WHERE url = $1;
 
+-- name: GetFeedByNameOrUrl :one
+-- prefers the url, then the title the user gave the feed, then its name
+SELECT feeds.* FROM feeds
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = @user_id
+WHERE feeds.url = @ref OR feed_follows.title = @ref OR feeds.name = @ref
+ORDER BY feeds.url = @ref DESC, COALESCE(feed_follows.title = @ref, false) DESC, feeds.created_at
+LIMIT 1;
+
 -- name: GetFeeds :many
 SELECT * FROM feeds;
 
 -- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id)
-    VALUES($1, $2, $3, $4, $5)
+    INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id, folder_id)
+    VALUES($1, $2, $3, $4, $5, $6)
     RETURNING *
 )
 SELECT
@@ -34,25 +42,52 @@ JOIN feeds ON inserted_feed_follow.feed_id = feeds.id
 JOIN users ON inserted_feed_follow.user_id = users.id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, feeds.name AS feed_name, users.name AS user_name
+SELECT feed_follows.*, COALESCE(feed_follows.title, feeds.name)::text AS feed_name, users.name AS user_name, folders.name AS folder_name,
+    feeds.url AS feed_url, feeds.site_url AS feed_site_url
 FROM feed_follows
 JOIN feeds ON feed_follows.feed_id = feeds.id
 JOIN users ON feed_follows.user_id = users.id
-WHERE feed_follows.user_id = $1;
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND (sqlc.narg('folder')::text IS NULL OR folders.name = sqlc.narg('folder'))
+ORDER BY folders.name NULLS FIRST, COALESCE(feed_follows.title, feeds.name);
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: SetFeedFollowTitle :execrows
+UPDATE feed_follows
+SET title = $3,
+    updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2;
 
 -- name: Unfollow :exec
 DELETE FROM feed_follows
 WHERE feed_id = $1 AND user_id = $2;
 
 -- name: MarkFeedFetched :exec
+-- last_fetched_at is in UTC like post times, whatever the server's time zone
 UPDATE feeds 
-SET last_fetched_at = NOW(),
+SET last_fetched_at = NOW() AT TIME ZONE 'UTC',
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
+-- name: SetFeedSiteUrl :exec
+UPDATE feeds
+SET site_url = $2,
+    updated_at = NOW()
+WHERE id = $1 AND site_url <> $2;
+
 -- name: GetNextFeedToFetch :one
 SELECT *
 FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+-- name: SetFeedHub :exec
+UPDATE feeds
+SET hub_url = $2,
+    self_url = $3,
+    updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,70 @@
+-- name: GetFeverGroups :many
+SELECT serial_id, name FROM folders
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetFeverFeeds :many
+SELECT feeds.serial_id, COALESCE(feed_follows.title, feeds.name)::text AS title, feeds.url, feeds.site_url,
+    feeds.last_fetched_at, folders.serial_id AS folder_serial_id
+FROM feed_follows
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+ORDER BY COALESCE(feed_follows.title, feeds.name);
+
+-- name: GetFeverItems :many
+SELECT posts.id, posts.serial_id, feeds.serial_id AS feed_serial_id, posts.title, posts.author, posts.description,
+    posts.content, posts.url, posts.published_at, posts.created_at, post_states.read_at, post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND (sqlc.narg('since_id')::bigint IS NULL OR posts.serial_id > sqlc.narg('since_id'))
+    AND (sqlc.narg('max_id')::bigint IS NULL OR posts.serial_id < sqlc.narg('max_id'))
+    AND (sqlc.narg('with_ids')::bigint[] IS NULL OR posts.serial_id = ANY(sqlc.narg('with_ids')::bigint[]))
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+ORDER BY
+    CASE WHEN sqlc.arg('descending')::boolean THEN posts.serial_id END DESC,
+    posts.serial_id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: CountFeverItems :one
+SELECT count(*)
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id);
+
+-- name: GetFeverItemIds :many
+SELECT posts.serial_id
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND (NOT sqlc.arg('unread_only')::boolean OR post_states.read_at IS NULL)
+    AND (NOT sqlc.arg('starred_only')::boolean OR post_states.starred_at IS NOT NULL)
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+ORDER BY posts.serial_id;
+
+-- name: GetPostIdBySerialId :one
+-- only finds posts from feeds the user follows
+SELECT posts.id
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg('user_id')
+WHERE posts.serial_id = sqlc.arg('serial_id');
+
+-- name: MarkPostsReadBefore :execrows
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, read_at)
+SELECT feed_follows.user_id, posts.id, NOW(), NOW(), NOW()
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND COALESCE(posts.published_at, posts.created_at) <= sqlc.arg('before')::timestamp
+    AND (sqlc.narg('feed_serial_id')::bigint IS NULL OR feeds.serial_id = sqlc.narg('feed_serial_id'))
+    AND (sqlc.narg('folder_serial_id')::bigint IS NULL OR folders.serial_id = sqlc.narg('folder_serial_id'))
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, NOW()),
+    updated_at = NOW();
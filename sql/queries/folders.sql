@@ -0,0 +1,31 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders WHERE user_id = $1 AND name = $2;
+
+-- name: GetFoldersForUser :many
+SELECT folders.*, count(feed_follows.id) AS feed_count
+FROM folders
+LEFT JOIN feed_follows ON feed_follows.folder_id = folders.id
+WHERE folders.user_id = $1
+GROUP BY folders.id
+ORDER BY folders.name;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE user_id = $1 AND name = $2;
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $3,
+    updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2;
@@ -0,0 +1,49 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, read_at)
+VALUES ($1, $2, NOW(), NOW(), NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, NOW()),
+    updated_at = NOW();
+
+-- name: MarkPostUnread :exec
+UPDATE post_states
+SET read_at = NULL,
+    updated_at = NOW()
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: StarPost :exec
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, starred_at)
+VALUES ($1, $2, NOW(), NOW(), NOW())
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred_at = COALESCE(post_states.starred_at, NOW()),
+    updated_at = NOW();
+
+-- name: UnstarPost :exec
+UPDATE post_states
+SET starred_at = NULL,
+    updated_at = NOW()
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UntagPost :execrows
+DELETE FROM post_tags
+WHERE user_id = $1 AND post_id = $2 AND tag = $3;
+
+-- name: GetTagsForPosts :many
+SELECT post_id, tag FROM post_tags
+WHERE user_id = sqlc.arg('user_id') AND post_id = ANY(sqlc.arg('post_ids')::uuid[])
+ORDER BY post_id, tag;
+
+-- name: GetUnreadCountsForUser :many
+SELECT posts.feed_id, count(*) AS unread
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND post_states.read_at IS NULL
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+GROUP BY posts.feed_id;
@@ -1,5 +1,5 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, content, categories)
 VALUES (
     $1,
     $2,
@@ -8,20 +8,80 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11
 )
 RETURNING *;
 
 -- name: GetPostsForUser :many
-SELECT posts.title, posts.description, posts.published_at, posts.url, feeds.name 
+SELECT posts.id, posts.title, posts.description, posts.published_at, posts.url,
+    COALESCE(feed_follows.title, feeds.name)::text AS name, post_states.read_at, post_states.starred_at,
+    posts.created_at, posts.author, posts.content, posts.categories, feeds.url AS feed_url, feeds.site_url AS feed_site_url,
+    posts.feed_id
 FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
 JOIN feed_follows ON feeds.id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND (sqlc.narg('since')::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg('since'))
+    AND (sqlc.narg('until')::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg('until'))
+    AND (sqlc.narg('feed')::text IS NULL OR feeds.name = sqlc.narg('feed') OR feeds.url = sqlc.narg('feed') OR feed_follows.title = sqlc.narg('feed'))
+    AND (sqlc.narg('folder')::text IS NULL OR folders.name = sqlc.narg('folder'))
+    AND (NOT sqlc.arg('unread_only')::boolean OR post_states.read_at IS NULL)
+    AND (NOT sqlc.arg('starred_only')::boolean OR post_states.starred_at IS NOT NULL)
+    AND (sqlc.narg('tag')::text IS NULL OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.user_id = feed_follows.user_id AND post_tags.post_id = posts.id AND post_tags.tag = sqlc.narg('tag')
+    ))
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+ORDER BY
+    CASE WHEN sqlc.arg('ascending')::boolean THEN COALESCE(posts.published_at, posts.created_at) END ASC,
+    COALESCE(posts.published_at, posts.created_at) DESC,
+    posts.id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: GetPostByUrl :one
 SELECT *
 FROM posts
-WHERE url = $1;
\ No newline at end of file
+WHERE url = $1;
+
+-- name: GetPostDetail :one
+SELECT posts.*, COALESCE(feed_follows.title, feeds.name)::text AS feed_name, feeds.url AS feed_url,
+    post_states.read_at, post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = @user_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = @user_id
+WHERE (posts.url = @ref OR posts.id::text = @ref)
+    AND (NOT @following_only::boolean OR feed_follows.id IS NOT NULL)
+LIMIT 1;
+
+-- name: SearchPosts :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, COALESCE(feed_follows.title, feeds.name)::text AS feed_name,
+    ts_rank(posts.search, websearch_to_tsquery('english', sqlc.arg('query')::text))::real AS rank
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = sqlc.arg('user_id')
+WHERE posts.search @@ websearch_to_tsquery('english', sqlc.arg('query')::text)
+    AND (sqlc.arg('all_feeds')::boolean OR feed_follows.id IS NOT NULL)
+    AND (sqlc.narg('feed')::text IS NULL OR feeds.name = sqlc.narg('feed') OR feeds.url = sqlc.narg('feed') OR feed_follows.title = sqlc.narg('feed'))
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+ORDER BY rank DESC, COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetRecentPostsForFeed :many
+SELECT *
+FROM posts
+WHERE feed_id = $1
+ORDER BY COALESCE(published_at, created_at) DESC
+LIMIT $2;
+
+-- name: GetRecentPosts :many
+SELECT posts.*, feeds.name AS feed_name, feeds.url AS feed_url, feeds.site_url AS feed_site_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
+LIMIT $1;
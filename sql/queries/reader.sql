@@ -0,0 +1,94 @@
+-- name: GetReaderSubscriptions :many
+SELECT feeds.serial_id, COALESCE(feed_follows.title, feeds.name)::text AS title, feeds.url, feeds.site_url,
+    folders.name AS folder_name, feed_follows.created_at
+FROM feed_follows
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+ORDER BY COALESCE(feed_follows.title, feeds.name);
+
+-- name: GetReaderItems :many
+SELECT posts.id, posts.serial_id, posts.title, posts.author, posts.description, posts.content, posts.url,
+    posts.published_at, posts.created_at, feeds.serial_id AS feed_serial_id,
+    COALESCE(feed_follows.title, feeds.name)::text AS feed_title, feeds.site_url,
+    folders.name AS folder_name, post_states.read_at, post_states.starred_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND (sqlc.narg('feed_serial_id')::bigint IS NULL OR feeds.serial_id = sqlc.narg('feed_serial_id'))
+    AND (sqlc.narg('folder')::text IS NULL OR folders.name = sqlc.narg('folder'))
+    AND (NOT sqlc.arg('read_only')::boolean OR post_states.read_at IS NOT NULL)
+    AND (NOT sqlc.arg('starred_only')::boolean OR post_states.starred_at IS NOT NULL)
+    AND (NOT sqlc.arg('exclude_read')::boolean OR post_states.read_at IS NULL)
+    AND (NOT sqlc.arg('exclude_starred')::boolean OR post_states.starred_at IS NULL)
+    AND (sqlc.narg('newer_than')::timestamp IS NULL OR posts.created_at >= sqlc.narg('newer_than'))
+    AND (sqlc.narg('older_than')::timestamp IS NULL OR posts.created_at < sqlc.narg('older_than'))
+    AND (sqlc.narg('with_ids')::bigint[] IS NULL OR posts.serial_id = ANY(sqlc.narg('with_ids')::bigint[]))
+    AND (sqlc.narg('continuation')::bigint IS NULL
+        OR (sqlc.arg('oldest_first')::boolean AND posts.serial_id > sqlc.narg('continuation'))
+        OR (NOT sqlc.arg('oldest_first')::boolean AND posts.serial_id < sqlc.narg('continuation')))
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+ORDER BY
+    CASE WHEN sqlc.arg('oldest_first')::boolean THEN posts.serial_id END ASC,
+    posts.serial_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetReaderItemIds :many
+SELECT posts.serial_id, posts.created_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND (sqlc.narg('feed_serial_id')::bigint IS NULL OR feeds.serial_id = sqlc.narg('feed_serial_id'))
+    AND (sqlc.narg('folder')::text IS NULL OR folders.name = sqlc.narg('folder'))
+    AND (NOT sqlc.arg('read_only')::boolean OR post_states.read_at IS NOT NULL)
+    AND (NOT sqlc.arg('starred_only')::boolean OR post_states.starred_at IS NOT NULL)
+    AND (NOT sqlc.arg('exclude_read')::boolean OR post_states.read_at IS NULL)
+    AND (NOT sqlc.arg('exclude_starred')::boolean OR post_states.starred_at IS NULL)
+    AND (sqlc.narg('newer_than')::timestamp IS NULL OR posts.created_at >= sqlc.narg('newer_than'))
+    AND (sqlc.narg('older_than')::timestamp IS NULL OR posts.created_at < sqlc.narg('older_than'))
+    AND (sqlc.narg('continuation')::bigint IS NULL
+        OR (sqlc.arg('oldest_first')::boolean AND posts.serial_id > sqlc.narg('continuation'))
+        OR (NOT sqlc.arg('oldest_first')::boolean AND posts.serial_id < sqlc.narg('continuation')))
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+ORDER BY
+    CASE WHEN sqlc.arg('oldest_first')::boolean THEN posts.serial_id END ASC,
+    posts.serial_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetReaderUnreadCounts :many
+SELECT feeds.serial_id, folders.name AS folder_name, count(*) AS unread,
+    max(posts.created_at)::timestamp AS newest
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND post_states.read_at IS NULL
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+GROUP BY feeds.serial_id, folders.name
+ORDER BY feeds.serial_id;
+
+-- name: MarkReaderStreamRead :execrows
+INSERT INTO post_states (user_id, post_id, created_at, updated_at, read_at)
+SELECT feed_follows.user_id, posts.id, NOW(), NOW(), NOW()
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND posts.created_at <= sqlc.arg('before')::timestamp
+    AND (sqlc.narg('feed_serial_id')::bigint IS NULL OR feeds.serial_id = sqlc.narg('feed_serial_id'))
+    AND (sqlc.narg('folder')::text IS NULL OR folders.name = sqlc.narg('folder'))
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, NOW()),
+    updated_at = NOW();
+
+-- name: GetFeedBySerialId :one
+SELECT * FROM feeds WHERE serial_id = $1;
@@ -0,0 +1,44 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, updated_at, user_id, name, feed_id, field, match_type, pattern, action, action_arg)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11
+)
+RETURNING *;
+
+-- name: GetRuleByName :one
+SELECT * FROM rules WHERE user_id = $1 AND name = $2;
+
+-- name: GetRulesForUser :many
+SELECT rules.*, feeds.name AS feed_name
+FROM rules
+LEFT JOIN feeds ON rules.feed_id = feeds.id
+WHERE rules.user_id = $1
+ORDER BY rules.name;
+
+-- name: GetRulesForFeed :many
+SELECT rules.*, users.name AS user_name
+FROM rules
+JOIN feed_follows ON rules.user_id = feed_follows.user_id
+JOIN users ON rules.user_id = users.id
+WHERE feed_follows.feed_id = @feed_id
+    AND (rules.feed_id IS NULL OR rules.feed_id = @feed_id)
+ORDER BY rules.user_id, rules.name;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetNotifyRuleNames :many
+SELECT name FROM rules
+WHERE user_id = $1 AND action = 'notify' AND action_arg = sqlc.arg('webhook')
+ORDER BY name;
@@ -0,0 +1,46 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (id, created_at, updated_at, user_id, name, query)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetSavedSearchByName :one
+SELECT * FROM saved_searches WHERE user_id = $1 AND name = $2;
+
+-- name: GetSavedSearchesForUser :many
+SELECT saved_searches.*, (
+    SELECT count(*)
+    FROM posts
+    JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+    LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+    WHERE feed_follows.user_id = saved_searches.user_id
+        AND posts.search @@ websearch_to_tsquery('english', saved_searches.query)
+        AND post_states.read_at IS NULL
+        AND NOT post_is_filtered(saved_searches.user_id, posts.id)
+) AS unread_count
+FROM saved_searches
+WHERE saved_searches.user_id = $1
+ORDER BY saved_searches.name;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetPostsForSavedSearch :many
+SELECT posts.id, posts.title, posts.url, posts.published_at, COALESCE(feed_follows.title, feeds.name)::text AS name, post_states.read_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND posts.search @@ websearch_to_tsquery('english', sqlc.arg('query')::text)
+    AND (NOT sqlc.arg('unread_only')::boolean OR post_states.read_at IS NULL)
+    AND NOT post_is_filtered(sqlc.arg('user_id'), posts.id)
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
@@ -0,0 +1,23 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, created_at, user_id, token_hash, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetUserBySession :one
+SELECT users.* FROM sessions
+JOIN users ON sessions.user_id = users.id
+WHERE sessions.token_hash = sqlc.arg('token_hash') AND sessions.expires_at > sqlc.arg('now');
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteSessionsForUser :exec
+DELETE FROM sessions
+WHERE user_id = $1;
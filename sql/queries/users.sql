@@ -1,10 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
@@ -18,4 +20,25 @@ SELECT * FROM users WHERE id = $1;
 DELETE FROM users;
 
 -- name: GetUsers :many
-SELECT * FROM users;
\ No newline at end of file
+SELECT * FROM users;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = $2,
+    updated_at = NOW()
+WHERE name = $1;
+
+-- name: CountAdmins :one
+SELECT count(*) FROM users WHERE is_admin;
+
+-- name: CountUsers :one
+SELECT count(*) FROM users;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE name = $1;
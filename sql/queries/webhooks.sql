@@ -0,0 +1,105 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, name, url, secret, feed_id, folder_id, field, match_type, pattern, template, content_type, rules_only)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15
+)
+RETURNING *;
+
+-- name: GetWebhookByName :one
+SELECT * FROM webhooks WHERE user_id = $1 AND name = $2;
+
+-- name: GetWebhooksForUser :many
+SELECT webhooks.*, feeds.name AS feed_name, folders.name AS folder_name
+FROM webhooks
+LEFT JOIN feeds ON webhooks.feed_id = feeds.id
+LEFT JOIN folders ON webhooks.folder_id = folders.id
+WHERE webhooks.user_id = $1
+ORDER BY webhooks.name;
+
+-- name: GetWebhooksForPost :many
+SELECT webhooks.*, users.name AS user_name
+FROM webhooks
+JOIN users ON webhooks.user_id = users.id
+JOIN posts ON posts.id = sqlc.arg('post_id')
+JOIN feed_follows ON feed_follows.user_id = webhooks.user_id AND feed_follows.feed_id = posts.feed_id
+WHERE NOT webhooks.rules_only
+    AND (webhooks.feed_id IS NULL OR webhooks.feed_id = posts.feed_id)
+    AND (webhooks.folder_id IS NULL OR webhooks.folder_id = feed_follows.folder_id)
+    AND NOT post_is_filtered(webhooks.user_id, posts.id)
+ORDER BY webhooks.user_id, webhooks.name;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE user_id = $1 AND name = $2;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (id, created_at, updated_at, webhook_id, post_id, payload, status, next_attempt_at)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    $4,
+    'pending',
+    $5
+)
+ON CONFLICT (webhook_id, post_id) DO NOTHING
+RETURNING *;
+
+-- name: ClaimWebhookDeliveries :many
+-- pushes the next attempt of due deliveries back by lease_until, so another
+-- process delivering at the same time skips them
+WITH due AS (
+    SELECT webhook_deliveries.id
+    FROM webhook_deliveries
+    WHERE webhook_deliveries.status = 'pending' AND webhook_deliveries.next_attempt_at <= sqlc.arg('now')::timestamp
+    ORDER BY webhook_deliveries.next_attempt_at
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg('lease_until')::timestamp,
+    updated_at = NOW()
+FROM due, webhooks
+WHERE webhook_deliveries.id = due.id AND webhooks.id = webhook_deliveries.webhook_id
+RETURNING webhook_deliveries.id, webhook_deliveries.payload, webhook_deliveries.attempts,
+    webhooks.name AS webhook_name, webhooks.url, webhooks.secret, webhooks.content_type;
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1,
+    status = $2,
+    response_code = $3,
+    error = $4,
+    next_attempt_at = $5,
+    delivered_at = $6,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetWebhookDeliveries :many
+SELECT webhook_deliveries.id, webhook_deliveries.created_at, webhook_deliveries.status, webhook_deliveries.attempts,
+    webhook_deliveries.next_attempt_at, webhook_deliveries.response_code, webhook_deliveries.error,
+    webhook_deliveries.delivered_at, webhooks.name AS webhook_name, posts.title AS post_title
+FROM webhook_deliveries
+JOIN webhooks ON webhook_deliveries.webhook_id = webhooks.id
+LEFT JOIN posts ON webhook_deliveries.post_id = posts.id
+WHERE webhooks.user_id = sqlc.arg('user_id')
+    AND (sqlc.narg('webhook')::text IS NULL OR webhooks.name = sqlc.narg('webhook'))
+    AND (sqlc.narg('status')::text IS NULL OR webhook_deliveries.status = sqlc.narg('status'))
+ORDER BY webhook_deliveries.created_at DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,49 @@
+-- name: GetFeedsToSubscribe :many
+SELECT feeds.id, feeds.hub_url, COALESCE(NULLIF(feeds.self_url, ''), feeds.url)::text AS topic_url
+FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.hub_url <> ''
+    AND (websub_subscriptions.feed_id IS NULL
+        OR websub_subscriptions.hub_url <> feeds.hub_url
+        OR websub_subscriptions.topic_url <> COALESCE(NULLIF(feeds.self_url, ''), feeds.url)
+        OR websub_subscriptions.callback_url <> sqlc.arg('callback_prefix')::text || feeds.id::text
+        OR (websub_subscriptions.state = 'active' AND websub_subscriptions.lease_expires_at < sqlc.arg('renew_before')::timestamp)
+        OR (websub_subscriptions.state <> 'active' AND websub_subscriptions.requested_at < sqlc.arg('retry_before')::timestamp));
+
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (feed_id, created_at, updated_at, hub_url, topic_url, callback_url, secret, state, requested_at)
+VALUES (
+    sqlc.arg('feed_id'),
+    NOW(),
+    NOW(),
+    sqlc.arg('hub_url'),
+    sqlc.arg('topic_url'),
+    sqlc.arg('callback_url'),
+    sqlc.arg('secret'),
+    'pending',
+    NOW()
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    callback_url = EXCLUDED.callback_url,
+    state = 'pending',
+    requested_at = NOW(),
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions WHERE feed_id = $1;
+
+-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'active',
+    lease_expires_at = $2,
+    updated_at = NOW()
+WHERE feed_id = $1;
+
+-- name: SetWebSubSubscriptionState :exec
+UPDATE websub_subscriptions
+SET state = $2,
+    updated_at = NOW()
+WHERE feed_id = $1;
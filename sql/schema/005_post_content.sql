@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts
+    ADD COLUMN author TEXT NOT NULL DEFAULT '',
+    ADD COLUMN content TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE posts
+    DROP COLUMN author,
+    DROP COLUMN content;
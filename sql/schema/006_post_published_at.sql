@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE posts ALTER COLUMN published_at DROP NOT NULL;
+-- pubDates are stored in UTC like the ones parsed in Go; a zone name such
+-- as GMT has no offset postgres can parse, so it's taken as UTC
+ALTER TABLE posts ALTER COLUMN published_at TYPE TIMESTAMP USING (
+    CASE
+        WHEN published_at ~ '^[A-Za-z]{3}, [0-9]{1,2} [A-Za-z]{3} [0-9]{4} [0-9]{2}:[0-9]{2}:[0-9]{2} [+-][0-9]{4}$'
+        THEN to_timestamp(
+            substring(published_at FROM '^[A-Za-z]{3}, (.*)$'),
+            'DD Mon YYYY HH24:MI:SS TZHTZM'
+        ) AT TIME ZONE 'UTC'
+        WHEN published_at ~ '^[A-Za-z]{3}, [0-9]{1,2} [A-Za-z]{3} [0-9]{4} [0-9]{2}:[0-9]{2}:[0-9]{2}'
+        THEN to_timestamp(
+            substring(published_at FROM '^[A-Za-z]{3}, ([0-9]{1,2} [A-Za-z]{3} [0-9]{4} [0-9]{2}:[0-9]{2}:[0-9]{2})') || ' +0000',
+            'DD Mon YYYY HH24:MI:SS TZHTZM'
+        ) AT TIME ZONE 'UTC'
+        ELSE NULL
+    END
+);
+CREATE INDEX posts_feed_id_published_at_idx ON posts (feed_id, published_at);
+
+-- +goose Down
+DROP INDEX posts_feed_id_published_at_idx;
+ALTER TABLE posts ALTER COLUMN published_at TYPE TEXT USING (
+    COALESCE(to_char(published_at, 'Dy, DD Mon YYYY HH24:MI:SS +0000'), '')
+);
+ALTER TABLE posts ALTER COLUMN published_at SET NOT NULL;
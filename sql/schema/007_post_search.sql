@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN search TSVECTOR NOT NULL GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', description || ' ' || content), 'B')
+) STORED;
+CREATE INDEX posts_search_idx ON posts USING GIN (search);
+
+-- +goose Down
+DROP INDEX posts_search_idx;
+ALTER TABLE posts DROP COLUMN search;
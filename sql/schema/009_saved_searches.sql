@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE saved_searches (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+
+    UNIQUE(user_id, name)
+);
+
+-- +goose Down
+DROP TABLE saved_searches;
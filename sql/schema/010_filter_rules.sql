@@ -0,0 +1,45 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE filter_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    feed_id UUID,
+    field TEXT NOT NULL CHECK (field IN ('title', 'description', 'author', 'tag')),
+    match_type TEXT NOT NULL CHECK (match_type IN ('substring', 'regex')),
+    pattern TEXT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- post_is_filtered reports whether any of the user's filter rules hides the post
+-- +goose StatementBegin
+CREATE FUNCTION post_is_filtered(filter_user_id UUID, filter_post_id UUID) RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1
+        FROM filter_rules
+        JOIN posts ON posts.id = filter_post_id
+        CROSS JOIN LATERAL unnest(
+            CASE filter_rules.field
+                WHEN 'title' THEN ARRAY[posts.title]
+                WHEN 'description' THEN ARRAY[posts.description, posts.content]
+                WHEN 'author' THEN ARRAY[posts.author]
+                ELSE posts.categories
+            END
+        ) AS value
+        WHERE filter_rules.user_id = filter_user_id
+            AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = posts.feed_id)
+            AND CASE filter_rules.match_type
+                WHEN 'regex' THEN value ~* filter_rules.pattern
+                ELSE strpos(lower(value), lower(filter_rules.pattern)) > 0
+            END
+    );
+$$ LANGUAGE SQL STABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION post_is_filtered;
+DROP TABLE filter_rules;
+ALTER TABLE posts DROP COLUMN categories;
@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE post_states ADD COLUMN starred_at TIMESTAMP;
+
+CREATE TABLE post_tags (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (user_id, post_id, tag)
+);
+
+CREATE TABLE rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    feed_id UUID,
+    field TEXT NOT NULL CHECK (field IN ('title', 'description', 'author', 'tag')),
+    match_type TEXT NOT NULL CHECK (match_type IN ('substring', 'regex')),
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('read', 'star', 'tag', 'notify')),
+    action_arg TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+
+    UNIQUE(user_id, name)
+);
+
+-- +goose Down
+DROP TABLE rules;
+DROP TABLE post_tags;
+ALTER TABLE post_states DROP COLUMN starred_at;
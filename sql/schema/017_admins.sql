@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- the oldest account becomes the first admin of an existing database
+UPDATE users SET is_admin = TRUE
+WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1);
+
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    actor_name TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+
+-- +goose Down
+DROP TABLE audit_log;
+ALTER TABLE users DROP COLUMN is_admin;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- sync APIs like Fever and Google Reader identify items, feeds and groups by number
+ALTER TABLE posts ADD COLUMN serial_id BIGSERIAL NOT NULL UNIQUE;
+ALTER TABLE feeds ADD COLUMN serial_id BIGSERIAL NOT NULL UNIQUE;
+ALTER TABLE folders ADD COLUMN serial_id BIGSERIAL NOT NULL UNIQUE;
+
+-- +goose Down
+ALTER TABLE folders DROP COLUMN serial_id;
+ALTER TABLE feeds DROP COLUMN serial_id;
+ALTER TABLE posts DROP COLUMN serial_id;
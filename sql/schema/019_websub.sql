@@ -0,0 +1,24 @@
+-- +goose Up
+-- hub_url and self_url come from the feed's <atom:link rel="hub"> and rel="self"
+ALTER TABLE feeds ADD COLUMN hub_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN self_url TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE websub_subscriptions (
+    feed_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    callback_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    -- pending until the hub verifies intent, then active or denied
+    state TEXT NOT NULL,
+    requested_at TIMESTAMP NOT NULL,
+    lease_expires_at TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
+ALTER TABLE feeds DROP COLUMN self_url;
+ALTER TABLE feeds DROP COLUMN hub_url;
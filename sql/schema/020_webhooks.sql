@@ -0,0 +1,48 @@
+-- +goose Up
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    feed_id UUID,
+    folder_id UUID,
+    -- an empty pattern matches every post in scope
+    field TEXT NOT NULL CHECK (field IN ('title', 'description', 'author', 'tag')),
+    match_type TEXT NOT NULL CHECK (match_type IN ('substring', 'regex')),
+    pattern TEXT NOT NULL DEFAULT '',
+    -- a text/template for the request body, empty for the default JSON payload
+    template TEXT NOT NULL DEFAULT '',
+    content_type TEXT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE CASCADE,
+
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    webhook_id UUID NOT NULL,
+    post_id UUID,
+    payload TEXT NOT NULL,
+    -- pending until the receiver answers 2xx (delivered) or retries run out (failed)
+    status TEXT NOT NULL CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    response_code INTEGER,
+    error TEXT NOT NULL DEFAULT '',
+    delivered_at TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE SET NULL
+);
+
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE digests (
+    user_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    email TEXT NOT NULL,
+    schedule TEXT NOT NULL CHECK (schedule IN ('daily', 'weekly')),
+    -- the hour and, for weekly digests, the day of the week (0 is Sunday) to send at, in timezone
+    hour INTEGER NOT NULL CHECK (hour BETWEEN 0 AND 23),
+    weekday INTEGER NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    timezone TEXT NOT NULL,
+    next_send_at TIMESTAMP NOT NULL,
+    last_sent_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX digests_due ON digests(next_send_at);
+
+-- posts already sent to a user in a digest, so the next one skips them
+CREATE TABLE digest_posts (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    delivered_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE digest_posts;
+DROP TABLE digests;
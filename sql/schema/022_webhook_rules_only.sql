@@ -0,0 +1,6 @@
+-- +goose Up
+-- rules_only webhooks are skipped for new posts and only sent posts picked by notify rules
+ALTER TABLE webhooks ADD COLUMN rules_only BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE webhooks DROP COLUMN rules_only;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- a post is queued at most once per webhook, even when a notify rule picks
+-- one the webhook already gets for its own scope; test deliveries have no
+-- post and aren't limited
+DELETE FROM webhook_deliveries AS later
+USING webhook_deliveries AS earlier
+WHERE later.webhook_id = earlier.webhook_id
+    AND later.post_id = earlier.post_id
+    AND (later.created_at, later.id) > (earlier.created_at, earlier.id);
+ALTER TABLE webhook_deliveries ADD UNIQUE (webhook_id, post_id);
+
+-- +goose Down
+ALTER TABLE webhook_deliveries DROP CONSTRAINT webhook_deliveries_webhook_id_post_id_key;
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/cryptidcodes/gator/internal/webui"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The web UI is rendered on the server from the templates in internal/webui
+// and works without JavaScript. Browsers log in with a username and
+// password and get a session cookie, the same sessions the CLI uses.
+
+const (
+	webSessionCookie = "gator_session"
+	// webPostsPerPage is how many posts the timeline shows at once
+	webPostsPerPage = 30
+)
+
+// webSession is the logged in user of a web request
+type webSession struct {
+	user database.User
+	csrf string
+}
+
+func (srv *server) webRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /login", srv.webLoginPage)
+	mux.HandleFunc("POST /login", srv.webLogin)
+	mux.HandleFunc("POST /logout", srv.webAuthenticated(srv.webLogout))
+	mux.HandleFunc("GET /{$}", srv.webAuthenticated(srv.webTimeline))
+	mux.HandleFunc("GET /feeds/{feed}", srv.webAuthenticated(srv.webFeed))
+	mux.HandleFunc("GET /posts/{post}", srv.webAuthenticated(srv.webPost))
+	mux.HandleFunc("POST /posts/{post}/state", srv.webAuthenticated(srv.webSetPostState))
+	mux.HandleFunc("POST /read", srv.webAuthenticated(srv.webMarkAllRead))
+	mux.HandleFunc("GET /subscriptions", srv.webAuthenticated(srv.webSubscriptions))
+	mux.HandleFunc("POST /subscriptions", srv.webAuthenticated(srv.webFollow))
+	mux.HandleFunc("POST /subscriptions/{feed}/title", srv.webAuthenticated(srv.webRetitle))
+	mux.HandleFunc("POST /subscriptions/{feed}/folder", srv.webAuthenticated(srv.webMove))
+	mux.HandleFunc("POST /subscriptions/{feed}/unfollow", srv.webAuthenticated(srv.webUnfollow))
+}
+
+// webAuthenticated is authenticated for the web UI: it resolves the session
+// cookie to its user, sending the browser to the login page without one,
+// and checks the CSRF token of every form post
+func (srv *server) webAuthenticated(handler func(w http.ResponseWriter, r *http.Request, sess webSession)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(webSessionCookie)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		user, err := srv.s.db.GetUserBySession(r.Context(), database.GetUserBySessionParams{
+			TokenHash: hashToken(cookie.Value),
+			Now:       time.Now().UTC(),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			webError(w, err)
+			return
+		}
+
+		sess := webSession{user: user, csrf: csrfToken(cookie.Value)}
+		if r.Method == http.MethodPost {
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(r.PostForm.Get("csrf")), []byte(sess.csrf)) != 1 {
+				http.Error(w, "invalid form token, reload the page and try again", http.StatusForbidden)
+				return
+			}
+		}
+		handler(w, r, sess)
+	}
+}
+
+func csrfToken(sessionToken string) string {
+	// derived from the session so it needs no storage of its own
+	return hashToken("csrf:" + sessionToken)
+}
+
+func (srv *server) webPage(r *http.Request, sess webSession, title string) (webui.Page, error) {
+	// fills in the parts shared by every page, including the feed list
+	page := webui.Page{
+		Title: title,
+		User:  sess.user.Name,
+		CSRF:  sess.csrf,
+		Path:  r.URL.RequestURI(),
+	}
+	follows, err := srv.s.db.GetFeedFollowsForUser(r.Context(), database.GetFeedFollowsForUserParams{UserID: sess.user.ID})
+	if err != nil {
+		return webui.Page{}, err
+	}
+	counts, err := srv.s.db.GetUnreadCountsForUser(r.Context(), sess.user.ID)
+	if err != nil {
+		return webui.Page{}, err
+	}
+	unread := make(map[uuid.UUID]int64, len(counts))
+	for _, count := range counts {
+		unread[count.FeedID] = count.Unread
+	}
+	for _, follow := range follows {
+		page.Feeds = append(page.Feeds, webui.Feed{
+			ID:      follow.FeedID.String(),
+			Title:   follow.FeedName,
+			URL:     follow.FeedUrl,
+			SiteURL: follow.FeedSiteUrl,
+			Folder:  follow.FolderName.String,
+			Unread:  unread[follow.FeedID],
+		})
+		page.Unread += unread[follow.FeedID]
+	}
+	return page, nil
+}
+
+func (srv *server) webLoginPage(w http.ResponseWriter, r *http.Request) {
+	webRender(w, http.StatusOK, "login.html", webui.Page{Title: "Log in"})
+}
+
+func (srv *server) webLogin(w http.ResponseWriter, r *http.Request) {
+	// checks the password and starts a session. Users without a password
+	// can't log in here, since anyone could then claim their name.
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := r.PostForm.Get("username")
+	user, err := srv.s.db.GetUserByName(r.Context(), name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		webError(w, err)
+		return
+	}
+	if err != nil || !user.PasswordHash.Valid ||
+		bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(r.PostForm.Get("password"))) != nil {
+		if err == nil {
+			audit(srv.s, user, "login failed", user.Name, "web")
+		}
+		webRender(w, http.StatusUnauthorized, "login.html", webui.Page{
+			Title: "Log in",
+			Error: "Wrong username or password.",
+		})
+		return
+	}
+
+	token, expires, err := newSession(srv.s, user)
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     webSessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	audit(srv.s, user, "login", user.Name, "web")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (srv *server) webLogout(w http.ResponseWriter, r *http.Request, sess webSession) {
+	if cookie, err := r.Cookie(webSessionCookie); err == nil {
+		if err := srv.s.db.DeleteSession(r.Context(), hashToken(cookie.Value)); err != nil {
+			webError(w, err)
+			return
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: webSessionCookie, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (srv *server) webTimeline(w http.ResponseWriter, r *http.Request, sess webSession) {
+	heading := "Timeline"
+	query := r.URL.Query()
+	switch {
+	case query.Has("starred"):
+		heading = "Starred"
+	case query.Get("folder") != "":
+		heading = query.Get("folder")
+	case query.Get("tag") != "":
+		heading = "Tagged " + query.Get("tag")
+	}
+	srv.webPosts(w, r, sess, heading, nil)
+}
+
+func (srv *server) webFeed(w http.ResponseWriter, r *http.Request, sess webSession) {
+	feed, err := srv.lookupFeed(r.Context(), sess.user, r.PathValue("feed"))
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	srv.webPosts(w, r, sess, feed.Name, &feed)
+}
+
+func (srv *server) webPosts(w http.ResponseWriter, r *http.Request, sess webSession, heading string, feed *database.Feed) {
+	// lists posts like browse, taking the browse flags as query parameters
+	fs := flag.NewFlagSet("timeline", flag.ContinueOnError)
+	var filters postFilters
+	filters.register(fs, webPostsPerPage)
+	if err := setFlagsFromQuery(fs, r.URL.Query()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if feed != nil {
+		filters.feed = feed.Url
+	}
+	params, err := filters.params(sess.user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := srv.s.db.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		webError(w, err)
+		return
+	}
+
+	page, err := srv.webPage(r, sess, heading)
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	data := webui.PostsPage{
+		Page:       page,
+		Heading:    heading,
+		Folder:     filters.folder,
+		UnreadOnly: filters.unread,
+		AllURL:     pageURL(r.URL, "unread", ""),
+		UnreadURL:  pageURL(r.URL, "unread", "true"),
+	}
+	if feed != nil {
+		for i := range page.Feeds {
+			if page.Feeds[i].ID == feed.ID.String() {
+				data.Feed = &page.Feeds[i]
+				data.Heading = page.Feeds[i].Title
+			}
+		}
+		if data.Feed == nil {
+			// a feed the user doesn't follow shows no posts, but still has a heading
+			data.Feed = &webui.Feed{ID: feed.ID.String(), Title: feed.Name, URL: feed.Url, SiteURL: feed.SiteUrl}
+		}
+		data.Title = data.Heading
+	}
+	for _, row := range rows {
+		data.Posts = append(data.Posts, webui.Post{
+			ID:        row.ID.String(),
+			Title:     row.Title,
+			URL:       row.Url,
+			FeedID:    row.FeedID.String(),
+			Feed:      row.Name,
+			Author:    row.Author,
+			Summary:   summarize(row.Description),
+			Published: publishedOrCreated(row.PublishedAt, row.CreatedAt),
+			Read:      row.ReadAt.Valid,
+			Starred:   row.StarredAt.Valid,
+		})
+	}
+	if filters.offset == 0 {
+		if filters.page > 1 {
+			data.PrevURL = pageURL(r.URL, "page", strconv.Itoa(filters.page-1))
+		}
+		if len(rows) == filters.limit {
+			data.NextURL = pageURL(r.URL, "page", strconv.Itoa(filters.page+1))
+		}
+	}
+	webRender(w, http.StatusOK, "posts.html", data)
+}
+
+func (srv *server) webPost(w http.ResponseWriter, r *http.Request, sess webSession) {
+	// shows a post in full, marking it read
+	post, err := lookupFollowedPost(srv.s, sess.user, r.PathValue("post"))
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	if !post.ReadAt.Valid {
+		if err := setPostState(srv.s, sess.user, post.ID, "read"); err != nil {
+			webError(w, err)
+			return
+		}
+		post.ReadAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	page, err := srv.webPage(r, sess, post.Title)
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	content := post.Content
+	if content == "" {
+		content = post.Description
+	}
+	webRender(w, http.StatusOK, "post.html", webui.PostPage{
+		Page: page,
+		Post: webui.Post{
+			ID:        post.ID.String(),
+			Title:     post.Title,
+			URL:       post.Url,
+			FeedID:    post.FeedID.String(),
+			Feed:      post.FeedName,
+			Author:    post.Author,
+			Content:   content,
+			Published: publishedOrCreated(post.PublishedAt, post.CreatedAt),
+			Read:      post.ReadAt.Valid,
+			Starred:   post.StarredAt.Valid,
+		},
+	})
+}
+
+func (srv *server) webSetPostState(w http.ResponseWriter, r *http.Request, sess webSession) {
+	post, err := lookupFollowedPost(srv.s, sess.user, r.PathValue("post"))
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	if err := setPostState(srv.s, sess.user, post.ID, r.PostForm.Get("state")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	webRedirect(w, r, r.PostForm.Get("next"))
+}
+
+func (srv *server) webMarkAllRead(w http.ResponseWriter, r *http.Request, sess webSession) {
+	// marks everything in the timeline, a folder or a feed read
+	params := database.MarkReaderStreamReadParams{
+		UserID: sess.user.ID,
+		Before: time.Now().UTC(),
+	}
+	if ref := r.PostForm.Get("feed"); ref != "" {
+		feed, err := srv.lookupFeed(r.Context(), sess.user, ref)
+		if err != nil {
+			webError(w, err)
+			return
+		}
+		params.FeedSerialID = sql.NullInt64{Int64: feed.SerialID, Valid: true}
+	}
+	if folder := r.PostForm.Get("folder"); folder != "" {
+		params.Folder = sql.NullString{String: folder, Valid: true}
+	}
+	if _, err := srv.s.db.MarkReaderStreamRead(r.Context(), params); err != nil {
+		webError(w, err)
+		return
+	}
+	webRedirect(w, r, r.PostForm.Get("next"))
+}
+
+func (srv *server) webSubscriptions(w http.ResponseWriter, r *http.Request, sess webSession) {
+	srv.renderSubscriptions(w, r, sess, http.StatusOK, "")
+}
+
+func (srv *server) renderSubscriptions(w http.ResponseWriter, r *http.Request, sess webSession, status int, msg string) {
+	page, err := srv.webPage(r, sess, "Subscriptions")
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	page.Path = "/subscriptions"
+	page.Error = msg
+	folders, err := srv.s.db.GetFoldersForUser(r.Context(), sess.user.ID)
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	data := webui.SubscriptionsPage{Page: page}
+	for _, folder := range folders {
+		data.Folders = append(data.Folders, folder.Name)
+	}
+	webRender(w, status, "subscriptions.html", data)
+}
+
+func (srv *server) webFollow(w http.ResponseWriter, r *http.Request, sess webSession) {
+	// follows a feed by url, adding it to gator if it's new
+	feedURL := strings.TrimSpace(r.PostForm.Get("url"))
+	if err := validateFeedURL(feedURL); err != nil {
+		srv.renderSubscriptions(w, r, sess, http.StatusBadRequest, err.Error())
+		return
+	}
+	feed, err := subscribe(srv.s, sess.user, feedURL, strings.TrimSpace(r.PostForm.Get("name")), "web")
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	if folder := strings.TrimSpace(r.PostForm.Get("folder")); folder != "" {
+		if err := srv.webSetFolder(sess.user, feed, folder); err != nil {
+			webError(w, err)
+			return
+		}
+	}
+	http.Redirect(w, r, "/subscriptions", http.StatusSeeOther)
+}
+
+func (srv *server) webRetitle(w http.ResponseWriter, r *http.Request, sess webSession) {
+	// an empty title goes back to the feed's own name, like retitle --reset
+	feed, err := srv.lookupFeed(r.Context(), sess.user, r.PathValue("feed"))
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	title := sql.NullString{}
+	if t := strings.TrimSpace(r.PostForm.Get("title")); t != "" && t != feed.Name {
+		title = sql.NullString{String: t, Valid: true}
+	}
+	_, err = srv.s.db.SetFeedFollowTitle(r.Context(), database.SetFeedFollowTitleParams{
+		UserID: sess.user.ID,
+		FeedID: feed.ID,
+		Title:  title,
+	})
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/subscriptions", http.StatusSeeOther)
+}
+
+func (srv *server) webMove(w http.ResponseWriter, r *http.Request, sess webSession) {
+	feed, err := srv.lookupFeed(r.Context(), sess.user, r.PathValue("feed"))
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	if err := srv.webSetFolder(sess.user, feed, strings.TrimSpace(r.PostForm.Get("folder"))); err != nil {
+		webError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/subscriptions", http.StatusSeeOther)
+}
+
+func (srv *server) webSetFolder(user database.User, feed database.Feed, folder string) error {
+	// moves a followed feed into folder, creating it if needed, or out of any folder if empty
+	folderID := uuid.NullUUID{}
+	if folder != "" {
+		var err error
+		folderID, err = ensureFolder(context.Background(), srv.s.db, user, folder)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := srv.s.db.SetFeedFollowFolder(context.Background(), database.SetFeedFollowFolderParams{
+		UserID:   user.ID,
+		FeedID:   feed.ID,
+		FolderID: folderID,
+	})
+	return err
+}
+
+func (srv *server) webUnfollow(w http.ResponseWriter, r *http.Request, sess webSession) {
+	feed, err := srv.lookupFeed(r.Context(), sess.user, r.PathValue("feed"))
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	err = srv.s.db.Unfollow(r.Context(), database.UnfollowParams{FeedID: feed.ID, UserID: sess.user.ID})
+	if err != nil {
+		webError(w, err)
+		return
+	}
+	audit(srv.s, sess.user, "unfollow", feed.Url, "web")
+	http.Redirect(w, r, "/subscriptions", http.StatusSeeOther)
+}
+
+func pageURL(u *url.URL, key, value string) string {
+	// returns the current page with one query parameter changed, or removed if value is empty
+	query := u.Query()
+	query.Del(key)
+	if value != "" {
+		query.Set(key, value)
+	}
+	if key != "page" {
+		// changing what is listed starts over at the first page
+		query.Del("page")
+	}
+	if len(query) == 0 {
+		return u.Path
+	}
+	return u.Path + "?" + query.Encode()
+}
+
+func webRedirect(w http.ResponseWriter, r *http.Request, next string) {
+	// sends the browser back to next, as long as it is a page of this site
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		next = "/"
+	}
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func webRender(w http.ResponseWriter, status int, name string, data any) {
+	// renders into a buffer first so a template error doesn't leave half a page
+	var buf bytes.Buffer
+	if err := webui.Render(&buf, name, data); err != nil {
+		webError(w, fmt.Errorf("rendering %v: %v", name, err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func webError(w http.ResponseWriter, err error) {
+	var nf notFoundError
+	if errors.As(err, &nf) {
+		http.Error(w, nf.msg, http.StatusNotFound)
+		return
+	}
+	log.Println(err)
+	http.Error(w, "something went wrong", http.StatusInternalServerError)
+}
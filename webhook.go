@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Webhooks POST new posts to a url as they are saved. Matching posts are
+// queued in webhook_deliveries by savePosts, or by notify rules for rules
+// only webhooks, and agg and serve deliver the queue in the background,
+// retrying failures with exponential backoff.
+
+const (
+	// webhookInterval is how often the queue is checked for due deliveries
+	webhookInterval = 10 * time.Second
+	// webhookAttempts is how many times a delivery is tried before it fails
+	webhookAttempts = 6
+	// webhookBackoff is the wait after the first failed attempt, doubling after each one
+	webhookBackoff = time.Minute
+	// webhookTimeout bounds a single attempt
+	webhookTimeout = 15 * time.Second
+	// webhookBatch is how many deliveries are claimed at once
+	webhookBatch = 20
+)
+
+// webhookAddrAllowed reports whether webhooks may be sent to an address.
+// Loopback, link-local and private addresses are refused, so a webhook
+// can't be used to reach the machine gator runs on or its network.
+var webhookAddrAllowed = func(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// webhookClient checks every address it connects to, which catches hosts
+// whose DNS changed since the webhook was added and redirects elsewhere
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: webhookTimeout, Control: webhookDialControl}).DialContext,
+	},
+}
+
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !webhookAddrAllowed(addrPort.Addr()) {
+		return fmt.Errorf("webhooks can't be sent to %v, it isn't a public address", addrPort.Addr())
+	}
+	return nil
+}
+
+func checkWebhookURL(ctx context.Context, webhookURL string) error {
+	// refuses a url whose host is, or resolves to, an address webhooks can't be sent to
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return err
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("couldn't resolve %v: %v", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !webhookAddrAllowed(addr) {
+			return fmt.Errorf("%v is %v, which isn't a public address", u.Hostname(), addr.Unmap())
+		}
+	}
+	return nil
+}
+
+// webhookData is what payload templates are executed with
+type webhookData struct {
+	Event   string          `json:"event"`
+	Webhook string          `json:"webhook"`
+	Feed    webhookDataFeed `json:"feed"`
+	Post    webhookDataPost `json:"post"`
+}
+
+type webhookDataFeed struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	SiteURL string `json:"site_url"`
+}
+
+type webhookDataPost struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	Author     string    `json:"author"`
+	Published  time.Time `json:"published"`
+	Categories []string  `json:"categories"`
+	// Summary is a short plain text excerpt of the description
+	Summary string `json:"summary"`
+}
+
+func newWebhookData(webhookName string, feed database.Feed, post database.Post) webhookData {
+	return webhookData{
+		Event:   "post.created",
+		Webhook: webhookName,
+		Feed: webhookDataFeed{
+			Name:    feed.Name,
+			URL:     feed.Url,
+			SiteURL: feed.SiteUrl,
+		},
+		Post: webhookDataPost{
+			ID:         post.ID.String(),
+			Title:      post.Title,
+			URL:        post.Url,
+			Author:     post.Author,
+			Published:  publishedOrCreated(post.PublishedAt, post.CreatedAt),
+			Categories: orEmpty(post.Categories),
+			Summary:    summarize(post.Description),
+		},
+	}
+}
+
+var webhookFuncs = template.FuncMap{
+	// json encodes a value, so templates can build JSON bodies safely
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+func parseWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("payload").Funcs(webhookFuncs).Option("missingkey=error").Parse(text)
+}
+
+func renderWebhookPayload(templateText string, data webhookData) (string, error) {
+	// executes the webhook's template, or encodes data as JSON without one
+	if templateText == "" {
+		payload, err := json.Marshal(data)
+		return string(payload), err
+	}
+	tmpl, err := parseWebhookTemplate(templateText)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func queueWebhooks(s *state, feed database.Feed, post database.Post) {
+	// queues a delivery for every webhook whose scope and condition match a new post
+	hooks, err := s.db.GetWebhooksForPost(context.Background(), post.ID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	target := postRuleTarget(post)
+	for _, hook := range hooks {
+		if hook.Pattern != "" {
+			ok, err := ruleMatches(hook.Field, hook.MatchType, hook.Pattern, target)
+			if err != nil {
+				log.Printf("webhook %v for %v: %v", hook.Name, hook.UserName, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+		}
+		if err := queueWebhookDelivery(s, hook.ID, hook.Name, hook.Template, feed, post); err != nil {
+			log.Printf("webhook %v for %v: %v", hook.Name, hook.UserName, err)
+		}
+	}
+}
+
+func queueWebhookDelivery(s *state, webhookID uuid.UUID, webhookName, templateText string, feed database.Feed, post database.Post) error {
+	// renders a post with the webhook's template and queues it for sending
+	payload, err := renderWebhookPayload(templateText, newWebhookData(webhookName, feed, post))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.CreateWebhookDelivery(context.Background(), database.CreateWebhookDeliveryParams{
+		ID:            uuid.New(),
+		WebhookID:     webhookID,
+		PostID:        uuid.NullUUID{UUID: post.ID, Valid: true},
+		Payload:       payload,
+		NextAttemptAt: time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// already queued for this webhook
+		return nil
+	}
+	return err
+}
+
+func webhookLoop(s *state) {
+	// delivers queued webhooks for as long as the process runs
+	ticker := time.NewTicker(webhookInterval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		if err := deliverWebhooks(s); err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+	}
+}
+
+func deliverWebhooks(s *state) error {
+	// attempts every due delivery, claiming them first so agg and serve
+	// running side by side don't both send the same one. The lease has to
+	// outlast sending the whole batch, one delivery after another
+	for {
+		now := time.Now().UTC()
+		deliveries, err := s.db.ClaimWebhookDeliveries(context.Background(), database.ClaimWebhookDeliveriesParams{
+			Now:        now,
+			LeaseUntil: now.Add(webhookBatch * 2 * webhookTimeout),
+			Limit:      webhookBatch,
+		})
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		for _, delivery := range deliveries {
+			code, err := sendWebhook(delivery.Url, delivery.Secret, delivery.ContentType, delivery.ID, delivery.Payload)
+			if err := recordWebhookAttempt(s, delivery.ID, int(delivery.Attempts)+1, code, err); err != nil {
+				return err
+			}
+			if err != nil {
+				log.Printf("webhook %v: %v", delivery.WebhookName, err)
+			}
+		}
+	}
+}
+
+func recordWebhookAttempt(s *state, deliveryID uuid.UUID, attempt, code int, sendErr error) error {
+	// logs the outcome of an attempt and schedules the next one if needed
+	params := database.RecordWebhookAttemptParams{
+		ID:            deliveryID,
+		Status:        "delivered",
+		NextAttemptAt: time.Now().UTC(),
+	}
+	if code != 0 {
+		params.ResponseCode = sql.NullInt32{Int32: int32(code), Valid: true}
+	}
+	switch {
+	case sendErr == nil:
+		params.DeliveredAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	case attempt >= webhookAttempts:
+		params.Status = "failed"
+		params.Error = sendErr.Error()
+	default:
+		params.Status = "pending"
+		params.Error = sendErr.Error()
+		params.NextAttemptAt = time.Now().UTC().Add(webhookBackoff << (attempt - 1))
+	}
+	return s.db.RecordWebhookAttempt(context.Background(), params)
+}
+
+func sendWebhook(url, secret, contentType string, deliveryID uuid.UUID, payload string) (int, error) {
+	// POSTs a payload signed with the webhook's secret, returning the status
+	// code and an error unless the receiver answered 2xx
+	req, err := http.NewRequest("POST", url, strings.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("X-Gator-Event", "post.created")
+	req.Header.Set("X-Gator-Delivery", deliveryID.String())
+	req.Header.Set("X-Gator-Signature-256", "sha256="+signPayload(secret, []byte(payload)))
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	if res.StatusCode/100 != 2 {
+		return res.StatusCode, fmt.Errorf("receiver answered %v: %v", res.Status, strings.TrimSpace(string(bytes.ToValidUTF8(body, nil))))
+	}
+	return res.StatusCode, nil
+}
+
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// webhookReceiver records the requests it's sent and answers them with status
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   string
+}
+
+func newWebhookReceiver(t *testing.T, status int) (*webhookReceiver, *httptest.Server) {
+	// the receiver listens on loopback, so webhooks are let through to it
+	t.Helper()
+	allowed := webhookAddrAllowed
+	webhookAddrAllowed = func(netip.Addr) bool { return true }
+	t.Cleanup(func() { webhookAddrAllowed = allowed })
+	rec := &webhookReceiver{status: status}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.requests = append(rec.requests, receivedWebhook{header: r.Header.Clone(), body: string(body)})
+		w.WriteHeader(rec.status)
+		io.WriteString(w, http.StatusText(rec.status))
+	}))
+	t.Cleanup(srv.Close)
+	return rec, srv
+}
+
+func (rec *webhookReceiver) received() []receivedWebhook {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]receivedWebhook(nil), rec.requests...)
+}
+
+func signedBy(secret string, req receivedWebhook) bool {
+	// checks X-Gator-Signature-256 the way a receiver would
+	header := req.header.Get("X-Gator-Signature-256")
+	return strings.HasPrefix(header, "sha256=") && validSignature(header, []byte(req.body), secret)
+}
+
+func TestSendWebhookSignsPayload(t *testing.T) {
+	rec, srv := newWebhookReceiver(t, http.StatusNoContent)
+	id := uuid.New()
+	payload := `{"event":"post.created"}`
+
+	code, err := sendWebhook(srv.URL, "s3cret", "application/json", id, payload)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("sendWebhook = %v, %v, want 204 and no error", code, err)
+	}
+	reqs := rec.received()
+	if len(reqs) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(reqs))
+	}
+	req := reqs[0]
+	if req.body != payload {
+		t.Errorf("body = %q, want %q", req.body, payload)
+	}
+	if !signedBy("s3cret", req) {
+		t.Errorf("X-Gator-Signature-256 = %q doesn't match the payload", req.header.Get("X-Gator-Signature-256"))
+	}
+	if signedBy("other", req) {
+		t.Errorf("signature matches a different secret")
+	}
+	for header, want := range map[string]string{
+		"Content-Type":     "application/json",
+		"X-Gator-Event":    "post.created",
+		"X-Gator-Delivery": id.String(),
+	} {
+		if got := req.header.Get(header); got != want {
+			t.Errorf("%v = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestSendWebhookRejectsNon2xx(t *testing.T) {
+	_, srv := newWebhookReceiver(t, http.StatusServiceUnavailable)
+
+	code, err := sendWebhook(srv.URL, "", "application/json", uuid.New(), "{}")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("code = %v, want 503", code)
+	}
+	if err == nil || !strings.Contains(err.Error(), "Service Unavailable") {
+		t.Errorf("err = %v, want the receiver's answer", err)
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://93.184.216.34/hook", true},
+		{"https://[2606:2800:220:1:248:1893:25c8:1946]/hook", true},
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://10.0.0.1/hook", false},
+		{"http://172.16.5.4/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://224.0.0.1/hook", false},
+	}
+	for _, tt := range tests {
+		err := checkWebhookURL(context.Background(), tt.url)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkWebhookURL(%v) = %v, want ok %v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+func TestSendWebhookRefusesPrivateAddress(t *testing.T) {
+	// the address is checked again when connecting, in case the host's DNS
+	// changed since the webhook was added
+	var hit bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hit = true }))
+	t.Cleanup(srv.Close)
+
+	_, err := sendWebhook(srv.URL, "secret", "application/json", uuid.New(), "{}")
+	if err == nil || !strings.Contains(err.Error(), "isn't a public address") {
+		t.Fatalf("sendWebhook = %v, want a public address error", err)
+	}
+	if hit {
+		t.Fatal("the request reached the server")
+	}
+}
+
+func TestDeliverWebhooksRetriesThenFails(t *testing.T) {
+	s := testState(t)
+	ctx := context.Background()
+	rec, srv := newWebhookReceiver(t, http.StatusInternalServerError)
+
+	user := testUser(t, s, "alice")
+	feed := testFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+	_, err := s.db.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		UserID:      user.ID,
+		Name:        "chat",
+		Url:         srv.URL,
+		Secret:      "s3cret",
+		Field:       "title",
+		MatchType:   "substring",
+		ContentType: "application/json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := testPost(t, s, feed, "Go 1.23 is released", "https://go.dev/blog/go1.23")
+	queueWebhooks(s, feed, post)
+
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		before := time.Now().UTC()
+		if err := deliverWebhooks(s); err != nil {
+			t.Fatal(err)
+		}
+		d := onlyDelivery(t, s, user)
+		if int(d.Attempts) != attempt {
+			t.Fatalf("attempt %d: attempts = %d", attempt, d.Attempts)
+		}
+		if !d.ResponseCode.Valid || d.ResponseCode.Int32 != http.StatusInternalServerError {
+			t.Errorf("attempt %d: response code = %v, want 500", attempt, d.ResponseCode)
+		}
+		if attempt == webhookAttempts {
+			if d.Status != "failed" {
+				t.Fatalf("after %d attempts status = %q, want failed", attempt, d.Status)
+			}
+			break
+		}
+
+		// each retry waits twice as long as the one before
+		wait := webhookBackoff << (attempt - 1)
+		if d.Status != "pending" {
+			t.Fatalf("attempt %d: status = %q, want pending", attempt, d.Status)
+		}
+		if d.NextAttemptAt.Before(before.Add(wait-time.Second)) || d.NextAttemptAt.After(time.Now().UTC().Add(wait+time.Second)) {
+			t.Fatalf("attempt %d: next attempt at %v, want about %v from %v", attempt, d.NextAttemptAt, wait, before)
+		}
+		// nothing is due until the backoff has passed
+		if err := deliverWebhooks(s); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(rec.received()); n != attempt {
+			t.Fatalf("attempt %d: receiver got %d requests before the backoff passed", attempt, n)
+		}
+		if _, err := s.conn.Exec("UPDATE webhook_deliveries SET next_attempt_at = $1", time.Now().UTC()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// failed deliveries aren't tried again
+	if err := deliverWebhooks(s); err != nil {
+		t.Fatal(err)
+	}
+	reqs := rec.received()
+	if len(reqs) != webhookAttempts {
+		t.Fatalf("receiver got %d requests, want %d", len(reqs), webhookAttempts)
+	}
+	for _, req := range reqs {
+		if !signedBy("s3cret", req) || !strings.Contains(req.body, post.Title) {
+			t.Fatalf("unexpected request %v %q", req.header, req.body)
+		}
+	}
+}
+
+func TestDeliverWebhooksMarksDelivered(t *testing.T) {
+	s := testState(t)
+	ctx := context.Background()
+	rec, srv := newWebhookReceiver(t, http.StatusOK)
+
+	user := testUser(t, s, "alice")
+	feed := testFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+	_, err := s.db.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		UserID:      user.ID,
+		Name:        "chat",
+		Url:         srv.URL,
+		Field:       "title",
+		MatchType:   "substring",
+		Pattern:     "released",
+		ContentType: "application/json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the post matching the webhook's condition is queued
+	queueWebhooks(s, feed, testPost(t, s, feed, "Go 1.23 is released", "https://go.dev/blog/go1.23"))
+	queueWebhooks(s, feed, testPost(t, s, feed, "Range over functions", "https://go.dev/blog/range-functions"))
+
+	if err := deliverWebhooks(s); err != nil {
+		t.Fatal(err)
+	}
+	d := onlyDelivery(t, s, user)
+	if d.Status != "delivered" || d.Attempts != 1 || !d.DeliveredAt.Valid {
+		t.Fatalf("delivery = %+v, want delivered on the first attempt", d)
+	}
+	if n := len(rec.received()); n != 1 {
+		t.Fatalf("receiver got %d requests, want 1", n)
+	}
+}
+
+func TestNotifyRuleDoesNotDuplicateDelivery(t *testing.T) {
+	// a notify rule picking a post its webhook already gets queues it once
+	s := testState(t)
+	ctx := context.Background()
+	user := testUser(t, s, "alice")
+	feed := testFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+	_, err := s.db.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		UserID:      user.ID,
+		Name:        "chat",
+		Url:         "https://chat.example.com/hooks/1",
+		Field:       "title",
+		MatchType:   "substring",
+		ContentType: "application/json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.db.CreateRule(ctx, database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      "releases",
+		Field:     "title",
+		MatchType: "substring",
+		Pattern:   "released",
+		Action:    "notify",
+		ActionArg: "chat",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savePosts(s, feed, []RSSItem{{Title: "Go 1.23 is released", Link: "https://go.dev/blog/go1.23"}})
+	onlyDelivery(t, s, user)
+}
+
+func TestWebhookRemoveKeepsNotifyTargets(t *testing.T) {
+	// a webhook can't be removed while a notify rule sends posts to it
+	s := testState(t)
+	ctx := context.Background()
+	user := testUser(t, s, "alice")
+	_, err := s.db.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		UserID:      user.ID,
+		Name:        "pager",
+		Url:         "https://pager.example.com/hooks/1",
+		Field:       "title",
+		MatchType:   "substring",
+		ContentType: "application/json",
+		RulesOnly:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.db.CreateRule(ctx, database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      "outage",
+		Field:     "title",
+		MatchType: "substring",
+		Pattern:   "outage",
+		Action:    "notify",
+		ActionArg: "pager",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rm := command{Name: "webhook rm", Args: []string{"pager"}}
+	err = webhookRemove(s, rm, user)
+	if err == nil || !strings.Contains(err.Error(), "used by notify rules outage") {
+		t.Fatalf("webhook rm = %v, want it refused for rule outage", err)
+	}
+	if err := rulesRemove(s, command{Name: "rules rm", Args: []string{"outage"}}, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := webhookRemove(s, rm, user); err != nil {
+		t.Fatalf("webhook rm after removing the rule = %v", err)
+	}
+}
+
+func onlyDelivery(t *testing.T, s *state, user database.User) database.GetWebhookDeliveriesRow {
+	t.Helper()
+	deliveries, err := s.db.GetWebhookDeliveries(context.Background(), database.GetWebhookDeliveriesParams{
+		UserID: user.ID,
+		Limit:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// WebSub (https://www.w3.org/TR/websub/) lets a feed's hub push new items
+// to gator instead of waiting for the next poll. agg records the hub a feed
+// advertises; while serve runs with a public url it subscribes to those
+// hubs and renews the subscriptions before their leases run out.
+
+const (
+	// websubInterval is how often serve looks for hubs to subscribe to
+	websubInterval = 5 * time.Minute
+	// websubLease is the lease requested from hubs, which may pick another
+	websubLease = 7 * 24 * time.Hour
+	// websubRenewBefore is how long before a lease expires it is renewed
+	websubRenewBefore = 24 * time.Hour
+	// websubRetryAfter is how long to wait for a hub to verify a request before asking again
+	websubRetryAfter = time.Hour
+	// websubMaxPush is the largest pushed document accepted
+	websubMaxPush = 10 << 20
+)
+
+func (srv *server) websubLoop(ctx context.Context) {
+	// subscribes to hubs until ctx is done
+	ticker := time.NewTicker(websubInterval)
+	defer ticker.Stop()
+	for {
+		if err := srv.websubSubscribeAll(ctx); err != nil {
+			log.Printf("websub: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (srv *server) websubCallbackPrefix() string {
+	return strings.TrimSuffix(srv.publicURL, "/") + "/websub/"
+}
+
+func (srv *server) websubSubscribeAll(ctx context.Context) error {
+	// sends a subscription request for every feed that is new, changed hubs,
+	// has a lease about to expire, or whose last request was never verified
+	now := time.Now().UTC()
+	feeds, err := srv.s.db.GetFeedsToSubscribe(ctx, database.GetFeedsToSubscribeParams{
+		CallbackPrefix: srv.websubCallbackPrefix(),
+		RenewBefore:    now.Add(websubRenewBefore),
+		RetryBefore:    now.Add(-websubRetryAfter),
+	})
+	if err != nil {
+		return err
+	}
+	for _, feed := range feeds {
+		if err := srv.websubSubscribe(ctx, feed); err != nil {
+			log.Printf("websub: subscribing to %v at %v: %v", feed.TopicUrl, feed.HubUrl, err)
+		}
+	}
+	return nil
+}
+
+func (srv *server) websubSubscribe(ctx context.Context, feed database.GetFeedsToSubscribeRow) error {
+	// the secret is only used for new subscriptions; renewals keep theirs
+	secret, err := newToken("")
+	if err != nil {
+		return err
+	}
+	sub, err := srv.s.db.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+		FeedID:      feed.ID,
+		HubUrl:      feed.HubUrl,
+		TopicUrl:    feed.TopicUrl,
+		CallbackUrl: srv.websubCallbackPrefix() + feed.ID.String(),
+		Secret:      secret,
+	})
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {sub.TopicUrl},
+		"hub.callback":      {sub.CallbackUrl},
+		"hub.secret":        {sub.Secret},
+		"hub.lease_seconds": {strconv.Itoa(int(websubLease.Seconds()))},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.HubUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "gator")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	// hubs answer 202 Accepted and verify intent on the callback later
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("hub answered %v: %v", res.Status, strings.TrimSpace(string(body)))
+	}
+	log.Printf("websub: requested subscription to %v at %v", sub.TopicUrl, sub.HubUrl)
+	return nil
+}
+
+func (srv *server) websubVerify(w http.ResponseWriter, r *http.Request) {
+	// answers a hub's intent verification, or records that it denied us
+	sub, ok := srv.websubLookup(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("hub.topic") != sub.TopicUrl {
+		http.Error(w, "unknown topic", http.StatusNotFound)
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		// only a request gator sent and is waiting on can be verified, so a
+		// hub can't revive a denied subscription or stretch an active one
+		if sub.State != "pending" {
+			http.Error(w, "no pending subscription", http.StatusNotFound)
+			return
+		}
+		// hubs may pick a shorter lease, but not one longer than requested
+		lease, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 || lease > int(websubLease.Seconds()) {
+			lease = int(websubLease.Seconds())
+		}
+		expires := time.Now().UTC().Add(time.Duration(lease) * time.Second)
+		err = srv.s.db.ActivateWebSubSubscription(r.Context(), database.ActivateWebSubSubscriptionParams{
+			FeedID:         sub.FeedID,
+			LeaseExpiresAt: sql.NullTime{Time: expires, Valid: true},
+		})
+		if err != nil {
+			respondWithErr(w, err)
+			return
+		}
+		log.Printf("websub: subscribed to %v until %v", sub.TopicUrl, expires.Format(time.RFC3339))
+		respondWithText(w, http.StatusOK, query.Get("hub.challenge"))
+
+	case "denied":
+		err := srv.s.db.SetWebSubSubscriptionState(r.Context(), database.SetWebSubSubscriptionStateParams{
+			FeedID: sub.FeedID,
+			State:  "denied",
+		})
+		if err != nil {
+			respondWithErr(w, err)
+			return
+		}
+		log.Printf("websub: hub denied subscription to %v: %v", sub.TopicUrl, query.Get("hub.reason"))
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		// gator never unsubscribes, so an unsubscribe request isn't ours
+		http.Error(w, "unexpected mode", http.StatusNotFound)
+	}
+}
+
+func (srv *server) websubPush(w http.ResponseWriter, r *http.Request) {
+	// ingests new items pushed by the hub, after checking they are signed
+	// with the subscription's secret
+	sub, ok := srv.websubLookup(w, r)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, websubMaxPush))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// the spec asks for a 2xx even when the signature is wrong, so the
+	// hub can't be used to probe for the secret
+	if !validSignature(r.Header.Get("X-Hub-Signature"), body, sub.Secret) {
+		log.Printf("websub: ignoring push for %v with a bad signature", sub.TopicUrl)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	rssFeed, err := parseFeed(body)
+	if err != nil {
+		log.Printf("websub: ignoring push for %v: %v", sub.TopicUrl, err)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	feed, err := srv.s.db.GetFeedByID(r.Context(), sub.FeedID)
+	if err != nil {
+		respondWithErr(w, err)
+		return
+	}
+	log.Printf("websub: %v pushed %d items", feed.Name, len(rssFeed.Channel.Item))
+	savePosts(srv.s, feed, rssFeed.Channel.Item)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (srv *server) websubLookup(w http.ResponseWriter, r *http.Request) (database.WebsubSubscription, bool) {
+	// finds the subscription a callback is for. 410 Gone tells hubs to stop
+	// sending for subscriptions gator no longer has.
+	feedID, err := uuid.Parse(r.PathValue("feed"))
+	if err != nil {
+		http.Error(w, "unknown subscription", http.StatusGone)
+		return database.WebsubSubscription{}, false
+	}
+	sub, err := srv.s.db.GetWebSubSubscription(r.Context(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "unknown subscription", http.StatusGone)
+		return database.WebsubSubscription{}, false
+	}
+	if err != nil {
+		respondWithErr(w, err)
+		return database.WebsubSubscription{}, false
+	}
+	return sub, true
+}
+
+func validSignature(header string, body []byte, secret string) bool {
+	// checks an X-Hub-Signature of the form method=hexdigest
+	method, signature, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+	var newHash func() hash.Hash
+	switch method {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
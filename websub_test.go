@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cryptidcodes/gator/internal/database"
+)
+
+func TestWebSubVerify(t *testing.T) {
+	// a hub can only verify a pending subscription, and the lease it grants
+	// is capped at the one gator asked for
+	s := testState(t)
+	ctx := context.Background()
+	srv := httptest.NewServer((&server{s: s, publicURL: "https://gator.example.com"}).routes())
+	t.Cleanup(srv.Close)
+	user := testUser(t, s, "alice")
+	feed := testFeed(t, s, user, "Go Blog", "https://go.dev/blog/feed.atom")
+	_, err := s.db.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+		FeedID:      feed.ID,
+		HubUrl:      "https://hub.example.com",
+		TopicUrl:    feed.Url,
+		CallbackUrl: "https://gator.example.com/websub/" + feed.ID.String(),
+		Secret:      "s3cret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verify := func(mode, lease string) (int, string) {
+		t.Helper()
+		query := url.Values{
+			"hub.mode":          {mode},
+			"hub.topic":         {feed.Url},
+			"hub.challenge":     {"challenge"},
+			"hub.lease_seconds": {lease},
+		}
+		res, err := srv.Client().Get(srv.URL + "/websub/" + feed.ID.String() + "?" + query.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, string(body)
+	}
+
+	before := time.Now().UTC()
+	if code, body := verify("subscribe", "315360000"); code != http.StatusOK || body != "challenge" {
+		t.Fatalf("verifying the pending subscription = %v %q, want the challenge echoed", code, body)
+	}
+	sub, err := s.db.GetWebSubSubscription(ctx, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.State != "active" || sub.LeaseExpiresAt.Time.After(before.Add(websubLease+time.Minute)) {
+		t.Fatalf("subscription %v until %v, want active for at most %v", sub.State, sub.LeaseExpiresAt.Time, websubLease)
+	}
+
+	// verifying again once it's active is refused and leaves the lease alone
+	if code, _ := verify("subscribe", "3600"); code != http.StatusNotFound {
+		t.Fatalf("verifying an active subscription = %v, want 404", code)
+	}
+	again, err := s.db.GetWebSubSubscription(ctx, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.LeaseExpiresAt.Time.Equal(sub.LeaseExpiresAt.Time) {
+		t.Fatalf("lease moved from %v to %v", sub.LeaseExpiresAt.Time, again.LeaseExpiresAt.Time)
+	}
+}